@@ -0,0 +1,75 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path"
+	"strings"
+
+	_ "github.com/lib/pq"
+
+	"github.com/doug-martin/goqu/v8"
+	_ "github.com/doug-martin/goqu/v8/dialect/postgres"
+
+	source "github.com/HencoSmith/graphql-example-go/source"
+)
+
+const usage = `Usage:
+  export [-format csv|jsonl|parquet] [-genre GENRE] [-tag TAG] [-o FILE] movies|reviews|users
+        Write the dataset to the file or standard output. Movies and reviews are
+        filtered by genre and tag like the movie list, the format is determined
+        from the file name by default and is csv otherwise
+`
+
+func main() {
+	format := flag.String("format", "", "'csv', 'jsonl' or 'parquet'")
+	genre := flag.String("genre", "", "Only export movies of the genre and their reviews")
+	tag := flag.String("tag", "", "Only export movies labelled with the tag and their reviews")
+	output := flag.String("o", "", "File to write, standard output by default")
+	flag.Usage = func() {
+		fmt.Fprint(os.Stderr, usage)
+	}
+	flag.Parse()
+
+	if flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	if len(*format) == 0 {
+		*format = source.ExportCSV
+		if extension := strings.TrimPrefix(path.Ext(*output), "."); len(extension) > 0 {
+			*format = extension
+		}
+	}
+	if _, ok := source.ExportContentTypes[*format]; !ok {
+		log.Fatal(source.ErrUnknownExportFormat)
+	}
+
+	// Read configuration file
+	config := source.GetConfig(".")
+
+	// Connect to the database
+	db, errConnect := source.ConnectToDB(config)
+	if errConnect != nil {
+		log.Fatal(errConnect)
+	}
+	defer db.Close()
+
+	var out io.Writer = os.Stdout
+	if len(*output) > 0 {
+		file, createErr := os.Create(*output)
+		if createErr != nil {
+			log.Fatal(createErr)
+		}
+		defer file.Close()
+		out = file
+	}
+
+	errExport := source.ExportData(goqu.Dialect("postgres"), db, flag.Arg(0), *format, *genre, *tag, out)
+	if errExport != nil {
+		log.Fatal(errExport)
+	}
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+
+	_ "github.com/lib/pq"
+
+	"github.com/doug-martin/goqu/v8"
+	_ "github.com/doug-martin/goqu/v8/dialect/postgres"
+
+	source "github.com/HencoSmith/graphql-example-go/source"
+)
+
+const usage = `Usage:
+  import -owner EMAIL [-format csv|jsonl] FILE
+        Create movies owned by the user from a CSV or JSON Lines file, - reads
+        from standard input. Rows matching an existing movie by name and release
+        year are skipped. Exits with status 1 if any row was refused
+`
+
+func main() {
+	owner := flag.String("owner", "", "Email of the user the movies belong to")
+	format := flag.String("format", "", "'csv' or 'jsonl', determined from the file name by default")
+	flag.Usage = func() {
+		fmt.Fprint(os.Stderr, usage)
+	}
+	flag.Parse()
+
+	if flag.NArg() != 1 || len(*owner) == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	var data io.Reader = os.Stdin
+	if flag.Arg(0) != "-" {
+		file, openErr := os.Open(flag.Arg(0))
+		if openErr != nil {
+			log.Fatal(openErr)
+		}
+		defer file.Close()
+		data = file
+		if len(*format) == 0 {
+			*format = source.ImportFormat(flag.Arg(0))
+		}
+	}
+
+	// Read configuration file
+	config := source.GetConfig(".")
+
+	// Connect to the database
+	db, errConnect := source.ConnectToDB(config)
+	if errConnect != nil {
+		log.Fatal(errConnect)
+	}
+	defer db.Close()
+
+	// Lookup the query builder dialect
+	dialect := goqu.Dialect("postgres")
+
+	user, errUser := source.GetUser(dialect, db, "", *owner)
+	if errUser != nil {
+		log.Fatal(errUser)
+	}
+
+	result, errImport := source.ImportMovies(dialect, db, user.ID, *format, data)
+	for _, rowErr := range result.Errors {
+		fmt.Fprintf(os.Stderr, "row %d: %s\n", rowErr.Row, rowErr.Message)
+	}
+	fmt.Printf("imported %d, skipped %d, refused %d\n", result.Imported, result.Skipped, len(result.Errors))
+	if errImport != nil {
+		log.Fatal(errImport)
+	}
+	if len(result.Errors) > 0 {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"flag"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+
+	"github.com/HencoSmith/graphql-example-go/source"
+	"github.com/HencoSmith/graphql-example-go/test/oidcmock"
+)
+
+// Serve a mock OpenID Connect provider at the configured issuer for local development
+func main() {
+	subject := flag.String("subject", "mock-subject", "Subject of the issued ID tokens")
+	email := flag.String("email", "mock@mail.com", "Email of the issued ID tokens")
+	verified := flag.Bool("verified", true, "Whether the email is marked as verified")
+	flag.Parse()
+
+	// Read configuration file
+	config := source.GetConfig(".")
+
+	issuer, err := url.Parse(config.OIDC.Issuer)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// The server reads the client secret from the environment as well
+	secret := config.OIDC.ClientSecret
+	if envSecret := os.Getenv("OIDC_CLIENT_SECRET"); len(envSecret) != 0 {
+		secret = envSecret
+	}
+
+	provider, err := oidcmock.New(config.OIDC.Issuer, config.OIDC.ClientID, secret)
+	if err != nil {
+		log.Fatal(err)
+	}
+	provider.Subject = *subject
+	provider.Email = *email
+	provider.EmailVerified = *verified
+
+	log.Println("mock OpenID Connect provider listening at", config.OIDC.Issuer)
+	log.Fatal(http.ListenAndServe(issuer.Host, provider))
+}
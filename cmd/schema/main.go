@@ -0,0 +1,84 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+
+	"github.com/doug-martin/goqu/v8"
+	_ "github.com/doug-martin/goqu/v8/dialect/postgres"
+
+	gqlschema "github.com/HencoSmith/graphql-example-go/graphql/schema"
+)
+
+const usage = `Usage:
+  schema print
+        Print the SDL of the schema assembled from the Go code
+  schema diff OLD.graphql [NEW.graphql]
+        Classify the changes between two SDL files as breaking, dangerous or safe,
+        NEW defaults to the schema assembled from the Go code. Exits with status 1
+        if any change is breaking
+`
+
+// currentSDL - Assemble the schema without a database connection and print it as SDL
+func currentSDL() string {
+	schema, err := gqlschema.New(goqu.Dialect("postgres"), nil)
+	if err != nil {
+		log.Fatal(err)
+	}
+	return gqlschema.PrintSDL(schema)
+}
+
+// readFile - Read the contents of the SDL file
+func readFile(path string) string {
+	contents, err := ioutil.ReadFile(path)
+	if err != nil {
+		log.Fatal(err)
+	}
+	return string(contents)
+}
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprint(os.Stderr, usage)
+	}
+	flag.Parse()
+
+	switch flag.Arg(0) {
+	case "print":
+		fmt.Print(currentSDL())
+
+	case "diff":
+		if flag.NArg() < 2 {
+			flag.Usage()
+			os.Exit(2)
+		}
+		oldSDL := readFile(flag.Arg(1))
+		newSDL := ""
+		if flag.NArg() > 2 {
+			newSDL = readFile(flag.Arg(2))
+		} else {
+			newSDL = currentSDL()
+		}
+
+		changes, err := gqlschema.Diff(oldSDL, newSDL)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if len(changes) == 0 {
+			fmt.Println("No changes")
+		}
+		for _, change := range changes {
+			fmt.Printf("%-9s %s\n", change.Criticality, change.Message)
+		}
+		if gqlschema.HasBreaking(changes) {
+			os.Exit(1)
+		}
+
+	default:
+		flag.Usage()
+		os.Exit(2)
+	}
+}
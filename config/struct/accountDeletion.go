@@ -0,0 +1,7 @@
+package config
+
+// AccountDeletionConfiguration relates to the content of deleted accounts, either "keep" or "delete"
+type AccountDeletionConfiguration struct {
+	Movies  string
+	Reviews string
+}
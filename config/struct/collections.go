@@ -0,0 +1,6 @@
+package config
+
+// CollectionsConfiguration relates to movie collections curated by users
+type CollectionsConfiguration struct {
+	ShareURL string
+}
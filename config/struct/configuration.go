@@ -2,7 +2,22 @@ package config
 
 // Configuration Links all sub configurations"
 type Configuration struct {
-	Server   ServerConfiguration
-	Database DatabaseConfiguration
-	JWT      JWTConfiguration
+	Server          ServerConfiguration
+	Database        DatabaseConfiguration
+	AccountDeletion AccountDeletionConfiguration
+	Collections     CollectionsConfiguration
+	Encryption      EncryptionConfiguration
+	Import          ImportConfiguration
+	JWT             JWTConfiguration
+	Login           LoginConfiguration
+	Mail            MailConfiguration
+	OIDC            OIDCConfiguration
+	PasswordHash    PasswordHashConfiguration
+	PasswordReset   PasswordResetConfiguration
+	Purge           PurgeConfiguration
+	RateLimit       RateLimitConfiguration
+	Storage         StorageConfiguration
+	TOTP            TOTPConfiguration
+	Upload          UploadConfiguration
+	Verification    VerificationConfiguration
 }
@@ -0,0 +1,6 @@
+package config
+
+// EncryptionConfiguration relates to the encryption of secrets stored in the database
+type EncryptionConfiguration struct {
+	Key string
+}
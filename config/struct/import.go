@@ -0,0 +1,7 @@
+package config
+
+// ImportConfiguration relates to bulk imports of movies
+type ImportConfiguration struct {
+	BatchSize int
+	MaxRows   int
+}
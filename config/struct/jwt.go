@@ -8,4 +8,6 @@ import (
 type JWTConfiguration struct {
 	Key        string
 	Expiration time.Duration
+	Algorithm  string
+	Rotation   time.Duration
 }
@@ -0,0 +1,15 @@
+package config
+
+import (
+	"time"
+)
+
+// LoginConfiguration relates to brute-force protection variables
+type LoginConfiguration struct {
+	MaxAttempts   int
+	IPMaxAttempts int
+	Window        time.Duration
+	Lockout       time.Duration
+	Delay         time.Duration
+	MaxDelay      time.Duration
+}
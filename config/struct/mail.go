@@ -0,0 +1,12 @@
+package config
+
+// MailConfiguration relates to email delivery variables
+type MailConfiguration struct {
+	Driver   string
+	From     string
+	Host     string
+	Port     string
+	Username string
+	Password string
+	Path     string
+}
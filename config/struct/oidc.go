@@ -0,0 +1,18 @@
+package config
+
+import (
+	"time"
+)
+
+// OIDCConfiguration relates to login through an external OpenID Connect provider
+type OIDCConfiguration struct {
+	Enabled      bool
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+	Scopes       []string
+	SuccessURL   string
+	LinkByEmail  bool
+	Expiration   time.Duration
+}
@@ -0,0 +1,16 @@
+package config
+
+// PasswordHashConfiguration relates to how passwords are hashed
+type PasswordHashConfiguration struct {
+	Algorithm string
+	Cost      int
+	Argon2    Argon2Configuration
+}
+
+// Argon2Configuration relates to the argon2id parameters
+type Argon2Configuration struct {
+	Time      uint32
+	Memory    uint32
+	Threads   uint8
+	KeyLength uint32
+}
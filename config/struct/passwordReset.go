@@ -0,0 +1,11 @@
+package config
+
+import (
+	"time"
+)
+
+// PasswordResetConfiguration relates to password reset variables
+type PasswordResetConfiguration struct {
+	Expiration time.Duration
+	URL        string
+}
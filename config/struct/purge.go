@@ -0,0 +1,7 @@
+package config
+
+// PurgeConfiguration relates to permanently removing soft-deleted movies
+type PurgeConfiguration struct {
+	RetentionDays   int
+	IntervalMinutes int
+}
@@ -0,0 +1,15 @@
+package config
+
+// RateLimitConfiguration relates to request rate limiting variables
+type RateLimitConfiguration struct {
+	TrustProxy    bool
+	Anonymous     BucketConfiguration
+	Authenticated BucketConfiguration
+	Operations    map[string]BucketConfiguration
+}
+
+// BucketConfiguration relates to the size and refill rate of a token bucket
+type BucketConfiguration struct {
+	Rate  float64
+	Burst int
+}
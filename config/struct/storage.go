@@ -0,0 +1,8 @@
+package config
+
+// StorageConfiguration relates to where uploaded files are stored
+type StorageConfiguration struct {
+	Driver string
+	Path   string
+	URL    string
+}
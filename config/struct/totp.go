@@ -0,0 +1,6 @@
+package config
+
+// TOTPConfiguration relates to two-factor authentication variables
+type TOTPConfiguration struct {
+	Issuer string
+}
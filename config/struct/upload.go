@@ -0,0 +1,10 @@
+package config
+
+// UploadConfiguration relates to validating and processing uploaded images
+type UploadConfiguration struct {
+	MaxSize        int64
+	Types          []string
+	MaxDimension   int
+	MaxPixels      int64
+	ThumbnailWidth int
+}
@@ -0,0 +1,12 @@
+package config
+
+import (
+	"time"
+)
+
+// VerificationConfiguration relates to email verification variables
+type VerificationConfiguration struct {
+	Expiration time.Duration
+	URL        string
+	Exempt     []string
+}
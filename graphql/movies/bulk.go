@@ -0,0 +1,112 @@
+package movies
+
+import (
+	"database/sql"
+	"errors"
+	"strconv"
+
+	"github.com/HencoSmith/graphql-example-go/models"
+)
+
+// maxBulkMovies - movies a single bulk mutation may change
+const maxBulkMovies = 500
+
+// codeRolledBack - error code of items which succeeded but were rolled back along with a failed
+// item of an all-or-nothing change
+const codeRolledBack = "ROLLED_BACK"
+
+// errMovieNotFound - returned for items addressing a movie which does not exist, is deleted or
+// belongs to another user
+var errMovieNotFound = errors.New("Movie not found")
+
+// bulkItem - Apply the change to the item at the index of the input, returns the movie changed
+type bulkItem func(tx *sql.Tx, index int) (*models.Movie, error)
+
+// errorCode - Machine readable code of the error, empty when it has none
+func errorCode(err error) string {
+	if extended, ok := err.(interface {
+		Extensions() map[string]interface{}
+	}); ok {
+		code, _ := extended.Extensions()["code"].(string)
+		return code
+	}
+	return ""
+}
+
+// bulkInputs - Input objects of the movies argument
+func bulkInputs(args map[string]interface{}) []map[string]interface{} {
+	inputs := []map[string]interface{}{}
+	if values, ok := args["movies"].([]interface{}); ok {
+		for _, value := range values {
+			if input, ok := value.(map[string]interface{}); ok {
+				inputs = append(inputs, input)
+			}
+		}
+	}
+	return inputs
+}
+
+// bulkMovies - Apply the change to every item in a single transaction. Each item runs in a
+// savepoint so a failed item leaves the others applied, unless allOrNothing is set in which case
+// any failed item rolls back every item
+// db - SQL DB connection to use
+// count - Amount of items
+// allOrNothing - Roll back every item when one fails
+// apply - Change of a single item
+func bulkMovies(db *sql.DB, count int, allOrNothing bool, apply bulkItem) (models.BulkMovies, error) {
+	result := models.BulkMovies{Results: []models.BulkMovieResult{}}
+	if count > maxBulkMovies {
+		return result, errors.New("At most " + strconv.Itoa(maxBulkMovies) + " movies can be changed at once")
+	}
+
+	tx, txErr := db.Begin()
+	if txErr != nil {
+		return result, txErr
+	}
+	defer tx.Rollback()
+
+	for index := 0; index < count; index++ {
+		if _, savepointErr := tx.Exec("SAVEPOINT bulk_item"); savepointErr != nil {
+			return result, savepointErr
+		}
+
+		movie, itemErr := apply(tx, index)
+		if itemErr != nil {
+			// A failed statement aborts the transaction until rolled back to the savepoint
+			if _, rollbackErr := tx.Exec("ROLLBACK TO SAVEPOINT bulk_item"); rollbackErr != nil {
+				return result, rollbackErr
+			}
+			result.Results = append(result.Results, models.BulkMovieResult{
+				Index: index,
+				Error: itemErr.Error(),
+				Code:  errorCode(itemErr),
+			})
+			result.Failed++
+			continue
+		}
+
+		if _, releaseErr := tx.Exec("RELEASE SAVEPOINT bulk_item"); releaseErr != nil {
+			return result, releaseErr
+		}
+		result.Results = append(result.Results, models.BulkMovieResult{
+			Index: index,
+			Movie: movie,
+		})
+		result.Succeeded++
+	}
+
+	if allOrNothing && result.Failed > 0 {
+		for i := range result.Results {
+			if len(result.Results[i].Error) == 0 {
+				result.Results[i].Movie = nil
+				result.Results[i].Error = "Rolled back as another movie failed"
+				result.Results[i].Code = codeRolledBack
+			}
+		}
+		result.Failed = count
+		result.Succeeded = 0
+		return result, nil
+	}
+
+	return result, tx.Commit()
+}
@@ -0,0 +1,458 @@
+package movies
+
+import (
+	"database/sql"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/doug-martin/goqu/v8"
+	"github.com/doug-martin/goqu/v8/exp"
+	"github.com/lib/pq"
+
+	"github.com/HencoSmith/graphql-example-go/graphql/node"
+	"github.com/HencoSmith/graphql-example-go/models"
+	source "github.com/HencoSmith/graphql-example-go/source"
+)
+
+// maxCollectionMovies - most movies a single collection may hold
+const maxCollectionMovies = 500
+
+// errCollectionNotFound - returned for collections which do not exist or which the user may not
+// see, private collections are not revealed to others
+var errCollectionNotFound = errors.New("Collection not found")
+
+// collectionColumns - columns of the collections table scanned by findCollections
+var collectionColumns = []interface{}{
+	"id",
+	"created_at",
+	"updated_at",
+	"users_id",
+	"name",
+	"description",
+	"public",
+	"share_token",
+}
+
+// validCollection - Check the fields of a collection
+func validCollection(name string, description string) error {
+	if len(strings.TrimSpace(name)) == 0 || utf8.RuneCountInString(name) > 128 {
+		return errors.New("Collection name must be between 1 and 128 characters")
+	}
+	if utf8.RuneCountInString(description) > 10000 {
+		return errors.New("Collection description must not exceed 10000 characters")
+	}
+	return nil
+}
+
+// findCollections - lookup the collections matching the specified expression
+// dialect - Query builder dialect object used
+// db - SQL DB connection to use
+// expression - Expression collections looking up should adhere to
+// order - Order of the collections
+func findCollections(dialect goqu.DialectWrapper, db queryer, expression exp.Expression, order ...exp.OrderedExpression) ([]models.Collection, error) {
+	dialectString := dialect.From("collections").Select(collectionColumns...).Where(expression).Order(order...)
+	query, _, dialectErr := dialectString.ToSQL()
+	if dialectErr != nil {
+		return nil, dialectErr
+	}
+
+	rows, queryErr := db.Query(query)
+	if queryErr != nil {
+		return nil, queryErr
+	}
+	defer rows.Close()
+
+	collectionsArr := []models.Collection{}
+	for rows.Next() {
+		var collectionRow = models.Collection{}
+		scanErr := rows.Scan(
+			&collectionRow.ID,
+			&collectionRow.CreatedAt,
+			&collectionRow.UpdatedAt,
+			&collectionRow.UsersID,
+			&collectionRow.Name,
+			&collectionRow.Description,
+			&collectionRow.Public,
+			&collectionRow.ShareToken,
+		)
+		if scanErr != nil {
+			return nil, scanErr
+		}
+		collectionsArr = append(collectionsArr, collectionRow)
+	}
+	if errRows := rows.Err(); errRows != nil {
+		return nil, errRows
+	}
+
+	return collectionsArr, nil
+}
+
+// findCollection - lookup a collection matching the specified expression
+// dialect - Query builder dialect object used
+// db - SQL DB connection to use
+// expression - Expression collection looking up should adhere to
+func findCollection(dialect goqu.DialectWrapper, db queryer, expression goqu.Ex) (*models.Collection, error) {
+	collectionsArr, findErr := findCollections(dialect, db, expression)
+	if findErr != nil {
+		return nil, findErr
+	}
+
+	if len(collectionsArr) < 1 {
+		return nil, nil
+	}
+
+	return &collectionsArr[0], nil
+}
+
+// visibleCollection - Determine whether the user may see the collection without its share link,
+// being public or the user being its owner or an admin
+func visibleCollection(user models.User, collection models.Collection) bool {
+	return collection.Public || source.RequireOwnerOrAdmin(user, collection.UsersID) == nil
+}
+
+// changeableCollection - lookup a collection which the user may change, being its owner or an
+// admin. The collection is locked until the transaction ends so changes to the order of its
+// movies do not interleave
+// dialect - Query builder dialect object used
+// tx - Transaction to use
+// user - User changing the collection
+// id - UUID of the collection
+func changeableCollection(dialect goqu.DialectWrapper, tx *sql.Tx, user models.User, id string) (*models.Collection, error) {
+	lockDialect := dialect.From("collections").Select("id").Where(goqu.Ex{
+		"id": id,
+	}).ForUpdate(exp.Wait)
+	lockQuery, _, lockToSQLErr := lockDialect.ToSQL()
+	if lockToSQLErr != nil {
+		return nil, lockToSQLErr
+	}
+	if _, lockErr := tx.Exec(lockQuery); lockErr != nil {
+		return nil, lockErr
+	}
+
+	collection, findErr := findCollection(dialect, tx, goqu.Ex{
+		"id": id,
+	})
+	if findErr != nil {
+		return nil, findErr
+	}
+	if collection == nil || !visibleCollection(user, *collection) {
+		return nil, errCollectionNotFound
+	}
+	if ownerErr := source.RequireOwnerOrAdmin(user, collection.UsersID); ownerErr != nil {
+		return nil, ownerErr
+	}
+
+	return collection, nil
+}
+
+// updateCollection - Change the columns of the collection and return it as changed
+// dialect - Query builder dialect object used
+// tx - Transaction to use
+// id - UUID of the collection
+// record - Columns to change, updated_at is always set
+func updateCollection(dialect goqu.DialectWrapper, tx *sql.Tx, id string, record goqu.Record) (*models.Collection, error) {
+	record["updated_at"] = time.Now().Format(time.RFC3339)
+	updateDialect := dialect.Update("collections").Set(record).Where(goqu.Ex{
+		"id": id,
+	})
+	updateQuery, _, toSQLErr := updateDialect.ToSQL()
+	if toSQLErr != nil {
+		return nil, toSQLErr
+	}
+	if _, updateErr := tx.Exec(updateQuery); updateErr != nil {
+		return nil, updateErr
+	}
+
+	return findCollection(dialect, tx, goqu.Ex{
+		"id": id,
+	})
+}
+
+// changeCollection - Apply the change to a collection the user may change within a transaction,
+// the columns returned by the change are updated along with updated_at
+// dialect - Query builder dialect object used
+// db - SQL DB connection to use
+// user - User changing the collection
+// id - UUID of the collection
+// change - Change applied to the locked collection
+func changeCollection(dialect goqu.DialectWrapper, db *sql.DB, user models.User, id string, change func(tx *sql.Tx, collection *models.Collection) (goqu.Record, error)) (*models.Collection, error) {
+	tx, txErr := db.Begin()
+	if txErr != nil {
+		return nil, txErr
+	}
+	defer tx.Rollback()
+
+	collection, collectionErr := changeableCollection(dialect, tx, user, id)
+	if collectionErr != nil {
+		return nil, collectionErr
+	}
+
+	record, changeErr := change(tx, collection)
+	if changeErr != nil {
+		return nil, changeErr
+	}
+
+	changed, updateErr := updateCollection(dialect, tx, collection.ID, record)
+	if updateErr != nil {
+		return nil, updateErr
+	}
+
+	return changed, tx.Commit()
+}
+
+// collectionMovieIDs - UUIDs of the movies of the collection in their order, including deleted
+// movies which keep their place should they be restored
+// dialect - Query builder dialect object used
+// db - SQL DB connection or transaction to use
+// collectionsID - UUID of the collection
+func collectionMovieIDs(dialect goqu.DialectWrapper, db queryer, collectionsID string) ([]string, error) {
+	selectDialect := dialect.From("collections_movies").Select("movies_id").Where(goqu.Ex{
+		"collections_id": collectionsID,
+	}).Order(goqu.C("position").Asc(), goqu.C("movies_id").Asc())
+	selectQuery, _, toSQLErr := selectDialect.ToSQL()
+	if toSQLErr != nil {
+		return nil, toSQLErr
+	}
+
+	rows, queryErr := db.Query(selectQuery)
+	if queryErr != nil {
+		return nil, queryErr
+	}
+	defer rows.Close()
+
+	ids := []string{}
+	for rows.Next() {
+		var moviesID string
+		if scanErr := rows.Scan(&moviesID); scanErr != nil {
+			return nil, scanErr
+		}
+		ids = append(ids, moviesID)
+	}
+
+	return ids, rows.Err()
+}
+
+// setCollectionOrder - Number the movies of the collection from 0 in the order of the UUIDs
+// tx - Transaction to use
+// collectionsID - UUID of the collection
+// ids - UUIDs of every movie of the collection
+func setCollectionOrder(tx *sql.Tx, collectionsID string, ids []string) error {
+	_, updateErr := tx.Exec(`
+	UPDATE collections_movies SET
+		position = array_position($2::uuid[], movies_id) - 1
+	WHERE collections_id = $1`, collectionsID, pq.Array(ids))
+	return updateErr
+}
+
+// addCollectionMovie - Add the movie to the collection at the position, appending it when the
+// position is negative or past the end. A movie already in the collection is moved instead
+// dialect - Query builder dialect object used
+// tx - Transaction to use
+// collectionsID - UUID of the collection, locked by changeableCollection
+// moviesID - UUID of the movie
+// position - Index the movie is placed at
+func addCollectionMovie(dialect goqu.DialectWrapper, tx *sql.Tx, collectionsID string, moviesID string, position int) error {
+	movie, findErr := findMovie(dialect, tx, goqu.Ex{
+		"id":         moviesID,
+		"deleted_at": nil,
+	})
+	if findErr != nil {
+		return findErr
+	}
+	if movie == nil {
+		return errMovieNotFound
+	}
+
+	ids, idsErr := collectionMovieIDs(dialect, tx, collectionsID)
+	if idsErr != nil {
+		return idsErr
+	}
+
+	others := []string{}
+	for _, id := range ids {
+		if id != movie.ID {
+			others = append(others, id)
+		}
+	}
+	if len(others) == len(ids) {
+		if len(ids) >= maxCollectionMovies {
+			return errors.New("Collections can hold at most " + strconv.Itoa(maxCollectionMovies) + " movies")
+		}
+
+		insertDialect := dialect.Insert("collections_movies").Rows(goqu.Record{
+			"collections_id": collectionsID,
+			"movies_id":      movie.ID,
+		})
+		insertQuery, _, toSQLErr := insertDialect.ToSQL()
+		if toSQLErr != nil {
+			return toSQLErr
+		}
+		if _, insertErr := tx.Exec(insertQuery); insertErr != nil {
+			return insertErr
+		}
+	}
+
+	if position < 0 || position > len(others) {
+		position = len(others)
+	}
+	order := append([]string{}, others[:position]...)
+	order = append(order, movie.ID)
+	order = append(order, others[position:]...)
+	return setCollectionOrder(tx, collectionsID, order)
+}
+
+// removeCollectionMovie - Remove the movie from the collection, nothing happens if it is not in it
+// dialect - Query builder dialect object used
+// tx - Transaction to use
+// collectionsID - UUID of the collection, locked by changeableCollection
+// moviesID - UUID of the movie
+func removeCollectionMovie(dialect goqu.DialectWrapper, tx *sql.Tx, collectionsID string, moviesID string) error {
+	deleteDialect := dialect.Delete("collections_movies").Where(goqu.Ex{
+		"collections_id": collectionsID,
+		"movies_id":      moviesID,
+	})
+	deleteQuery, _, toSQLErr := deleteDialect.ToSQL()
+	if toSQLErr != nil {
+		return toSQLErr
+	}
+	if _, deleteErr := tx.Exec(deleteQuery); deleteErr != nil {
+		return deleteErr
+	}
+
+	ids, idsErr := collectionMovieIDs(dialect, tx, collectionsID)
+	if idsErr != nil {
+		return idsErr
+	}
+	return setCollectionOrder(tx, collectionsID, ids)
+}
+
+// reorderCollectionMovies - Place the listed movies first in the given order, movies which are not
+// listed keep their order after them
+// dialect - Query builder dialect object used
+// tx - Transaction to use
+// collectionsID - UUID of the collection, locked by changeableCollection
+// moviesIDs - UUIDs of movies of the collection
+func reorderCollectionMovies(dialect goqu.DialectWrapper, tx *sql.Tx, collectionsID string, moviesIDs []string) error {
+	ids, idsErr := collectionMovieIDs(dialect, tx, collectionsID)
+	if idsErr != nil {
+		return idsErr
+	}
+
+	listed := map[string]bool{}
+	for _, id := range ids {
+		listed[id] = false
+	}
+	for _, id := range moviesIDs {
+		seen, ok := listed[id]
+		if !ok {
+			return errors.New("Movie " + id + " is not in the collection")
+		}
+		if seen {
+			return errors.New("Movie " + id + " is listed more than once")
+		}
+		listed[id] = true
+	}
+
+	order := append([]string{}, moviesIDs...)
+	for _, id := range ids {
+		if !listed[id] {
+			order = append(order, id)
+		}
+	}
+	return setCollectionOrder(tx, collectionsID, order)
+}
+
+// collectionMovies - A page of the movies of the collection which have not been deleted, in the
+// order of the collection
+// dialect - Query builder dialect object used
+// db - SQL DB connection to use
+// collectionsID - UUID of the collection
+// first - Number of movies to return
+// after - Position of the movie to continue after, empty for the first page
+func collectionMovies(dialect goqu.DialectWrapper, db *sql.DB, collectionsID string, first int, after string) (*models.CollectionMovieConnection, error) {
+	listed := goqu.Ex{
+		"collections_movies.collections_id": collectionsID,
+		"collections_movies.movies_id": goqu.Op{"in": dialect.From("movies").Select("id").Where(goqu.Ex{
+			"deleted_at": nil,
+		})},
+	}
+
+	totalCount, countErr := countRows(dialect, db, "collections_movies", listed)
+	if countErr != nil {
+		return nil, countErr
+	}
+
+	expression := goqu.And(listed)
+	if len(after) > 0 {
+		position, positionErr := strconv.Atoi(after)
+		if positionErr != nil {
+			return nil, node.ErrInvalidCursor
+		}
+		expression = goqu.And(listed, goqu.I("collections_movies.position").Gt(position))
+	}
+
+	// One more than requested tells whether there is a next page
+	selectDialect := dialect.From("collections_movies").Select(
+		"collections_movies.movies_id",
+		"collections_movies.position",
+		"collections_movies.created_at",
+	).Where(expression).Order(
+		goqu.I("collections_movies.position").Asc(),
+	).Limit(uint(first + 1))
+	selectQuery, _, toSQLErr := selectDialect.ToSQL()
+	if toSQLErr != nil {
+		return nil, toSQLErr
+	}
+
+	rows, queryErr := db.Query(selectQuery)
+	if queryErr != nil {
+		return nil, queryErr
+	}
+	defer rows.Close()
+
+	edges := []models.CollectionMovieEdge{}
+	for rows.Next() {
+		var moviesID string
+		var position int64
+		var addedAt time.Time
+		if scanErr := rows.Scan(&moviesID, &position, &addedAt); scanErr != nil {
+			return nil, scanErr
+		}
+		edges = append(edges, models.CollectionMovieEdge{
+			Cursor:   node.ToCursor(strconv.FormatInt(position, 10)),
+			Position: position,
+			AddedAt:  &addedAt,
+			Node:     models.Movie{ID: moviesID},
+		})
+	}
+	if errRows := rows.Err(); errRows != nil {
+		return nil, errRows
+	}
+
+	connection := &models.CollectionMovieConnection{
+		Edges:      edges,
+		TotalCount: totalCount,
+	}
+	if len(edges) > first {
+		connection.Edges = edges[:first]
+		connection.PageInfo.HasNextPage = true
+	}
+	if len(connection.Edges) == 0 {
+		return connection, nil
+	}
+
+	nodes := []*models.Movie{}
+	for i := range connection.Edges {
+		nodes = append(nodes, &connection.Edges[i].Node)
+	}
+	if hydrateErr := hydrateMovies(dialect, db, nodes); hydrateErr != nil {
+		return nil, hydrateErr
+	}
+
+	connection.PageInfo.StartCursor = &connection.Edges[0].Cursor
+	connection.PageInfo.EndCursor = &connection.Edges[len(connection.Edges)-1].Cursor
+	return connection, nil
+}
@@ -0,0 +1,338 @@
+package movies
+
+import (
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/doug-martin/goqu/v8"
+	"github.com/graphql-go/graphql"
+	uuid "github.com/satori/go.uuid"
+
+	"github.com/HencoSmith/graphql-example-go/models"
+	source "github.com/HencoSmith/graphql-example-go/source"
+)
+
+// maxTagLength - characters a tag may consist of
+const maxTagLength = 32
+
+// maxGenreLength - characters the name of a genre may consist of
+const maxGenreLength = 64
+
+// maxMovieTags - tags a single movie may be labelled with
+const maxMovieTags = 20
+
+// findGenres - lookup the genres matching the specified expression along with the amount of
+// movies in each, ordered by name
+// dialect - Query builder dialect object used
+// db - SQL DB connection to use
+// expression - Expression genres looking up should adhere to
+func findGenres(dialect goqu.DialectWrapper, db queryer, expression goqu.Ex) ([]models.Genre, error) {
+	dialectString := dialect.From("genres").Select(
+		"genres.id",
+		"genres.created_at",
+		"genres.name",
+		goqu.COUNT("movies.id"),
+	).LeftJoin(
+		goqu.T("movies_genres"),
+		goqu.On(goqu.Ex{"movies_genres.genres_id": goqu.I("genres.id")}),
+	).LeftJoin(
+		goqu.T("movies"),
+		goqu.On(goqu.Ex{
+			"movies.id":         goqu.I("movies_genres.movies_id"),
+			"movies.deleted_at": nil,
+		}),
+	).Where(expression).GroupBy("genres.id").Order(goqu.I("genres.name").Asc())
+	query, _, dialectErr := dialectString.ToSQL()
+	if dialectErr != nil {
+		return nil, dialectErr
+	}
+
+	rows, queryErr := db.Query(query)
+	if queryErr != nil {
+		return nil, queryErr
+	}
+	defer rows.Close()
+
+	genresArr := []models.Genre{}
+	for rows.Next() {
+		var genreRow = models.Genre{}
+		scanErr := rows.Scan(
+			&genreRow.ID,
+			&genreRow.CreatedAt,
+			&genreRow.Name,
+			&genreRow.MovieCount,
+		)
+		if scanErr != nil {
+			return nil, scanErr
+		}
+		genresArr = append(genresArr, genreRow)
+	}
+	if errRows := rows.Err(); errRows != nil {
+		return nil, errRows
+	}
+
+	return genresArr, nil
+}
+
+// findTags - lookup the tags of the movie, ordered by name
+// dialect - Query builder dialect object used
+// db - SQL DB connection to use
+// moviesID - UUID of the movie
+func findTags(dialect goqu.DialectWrapper, db queryer, moviesID string) ([]string, error) {
+	dialectString := dialect.From("tags").Select("tags.name").Join(
+		goqu.T("movies_tags"),
+		goqu.On(goqu.Ex{"movies_tags.tags_id": goqu.I("tags.id")}),
+	).Where(goqu.Ex{
+		"movies_tags.movies_id": moviesID,
+	}).Order(goqu.I("tags.name").Asc())
+	query, _, dialectErr := dialectString.ToSQL()
+	if dialectErr != nil {
+		return nil, dialectErr
+	}
+
+	rows, queryErr := db.Query(query)
+	if queryErr != nil {
+		return nil, queryErr
+	}
+	defer rows.Close()
+
+	tags := []string{}
+	for rows.Next() {
+		var tag string
+		if scanErr := rows.Scan(&tag); scanErr != nil {
+			return nil, scanErr
+		}
+		tags = append(tags, tag)
+	}
+	if errRows := rows.Err(); errRows != nil {
+		return nil, errRows
+	}
+
+	return tags, nil
+}
+
+// replaceMovieLinks - Replace the rows of the join table linking the movie to other entities
+// dialect - Query builder dialect object used
+// tx - Transaction to use
+// table - Join table e.g. movies_genres
+// column - Column of the join table referencing the other entity e.g. genres_id
+// moviesID - UUID of the movie
+// ids - UUIDs of the entities linked from now on
+func replaceMovieLinks(dialect goqu.DialectWrapper, tx *sql.Tx, table string, column string, moviesID string, ids []string) error {
+	deleteDialect := dialect.Delete(table).Where(goqu.Ex{
+		"movies_id": moviesID,
+	})
+	deleteQuery, _, deleteToSQLErr := deleteDialect.ToSQL()
+	if deleteToSQLErr != nil {
+		return deleteToSQLErr
+	}
+	if _, deleteErr := tx.Exec(deleteQuery); deleteErr != nil {
+		return deleteErr
+	}
+
+	if len(ids) == 0 {
+		return nil
+	}
+
+	rows := []interface{}{}
+	for _, id := range ids {
+		rows = append(rows, goqu.Record{
+			"movies_id": moviesID,
+			column:      id,
+		})
+	}
+	insertDialect := dialect.Insert(table).Rows(rows...)
+	insertQuery, _, insertToSQLErr := insertDialect.ToSQL()
+	if insertToSQLErr != nil {
+		return insertToSQLErr
+	}
+
+	_, insertErr := tx.Exec(insertQuery)
+	return insertErr
+}
+
+// setMovieGenres - Replace the genres of the movie, every genre has to exist
+// dialect - Query builder dialect object used
+// tx - Transaction to use
+// moviesID - UUID of the movie
+// names - Names of the genres, matched case-insensitively
+func setMovieGenres(dialect goqu.DialectWrapper, tx *sql.Tx, moviesID string, names []string) error {
+	lowerNames := []string{}
+	for _, name := range names {
+		lowerNames = append(lowerNames, strings.ToLower(strings.TrimSpace(name)))
+	}
+
+	ids := []string{}
+	if len(lowerNames) > 0 {
+		genres, findErr := findGenres(dialect, tx, goqu.Ex{
+			"genres.id": goqu.Op{"in": dialect.From("genres").Select("id").Where(
+				goqu.Func("lower", goqu.I("name")).In(lowerNames),
+			)},
+		})
+		if findErr != nil {
+			return findErr
+		}
+
+		found := map[string]bool{}
+		for _, genre := range genres {
+			found[strings.ToLower(genre.Name)] = true
+			ids = append(ids, genre.ID)
+		}
+		for i, name := range lowerNames {
+			if !found[name] {
+				return errors.New("Unknown genre: " + names[i])
+			}
+		}
+	}
+
+	return replaceMovieLinks(dialect, tx, "movies_genres", "genres_id", moviesID, ids)
+}
+
+// setMovieTags - Replace the tags of the movie, tags which are not in use yet are created
+// dialect - Query builder dialect object used
+// tx - Transaction to use
+// moviesID - UUID of the movie
+// names - Free-form tags
+func setMovieTags(dialect goqu.DialectWrapper, tx *sql.Tx, moviesID string, names []string) error {
+	unique := map[string]bool{}
+	tags := []string{}
+	for _, name := range names {
+		tag := source.NormalizeTag(name)
+		if length := utf8.RuneCountInString(tag); length == 0 || length > maxTagLength {
+			return errors.New("Tags must be between 1 and 32 characters")
+		}
+		if !unique[tag] {
+			unique[tag] = true
+			tags = append(tags, tag)
+		}
+	}
+	if len(tags) > maxMovieTags {
+		return errors.New("Movies can have at most 20 tags")
+	}
+
+	ids := []string{}
+	if len(tags) > 0 {
+		newTags := []interface{}{}
+		for _, tag := range tags {
+			newTags = append(newTags, goqu.Record{
+				"id":   uuid.NewV4().String(),
+				"name": tag,
+			})
+		}
+		insertDialect := dialect.Insert("tags").Rows(newTags...).OnConflict(goqu.DoNothing())
+		insertQuery, _, insertToSQLErr := insertDialect.ToSQL()
+		if insertToSQLErr != nil {
+			return insertToSQLErr
+		}
+		if _, insertErr := tx.Exec(insertQuery); insertErr != nil {
+			return insertErr
+		}
+
+		selectDialect := dialect.From("tags").Select("id").Where(goqu.Ex{
+			"name": tags,
+		})
+		selectQuery, _, selectToSQLErr := selectDialect.ToSQL()
+		if selectToSQLErr != nil {
+			return selectToSQLErr
+		}
+		rows, selectErr := tx.Query(selectQuery)
+		if selectErr != nil {
+			return selectErr
+		}
+		for rows.Next() {
+			var id string
+			if scanErr := rows.Scan(&id); scanErr != nil {
+				rows.Close()
+				return scanErr
+			}
+			ids = append(ids, id)
+		}
+		rows.Close()
+		if errRows := rows.Err(); errRows != nil {
+			return errRows
+		}
+	}
+
+	return replaceMovieLinks(dialect, tx, "movies_tags", "tags_id", moviesID, ids)
+}
+
+// replaceMovieLabels - Resolve a mutation replacing the labels of a movie, such as its genres or
+// tags, only the owner or an admin may change them. The change bumps the version of the movie
+// and is recorded in its revisions
+// params - Parameters of the resolved field, holding the movie id and the labels
+// dialect - Query builder dialect object used
+// db - SQL DB connection to use
+// argument - Name of the argument listing the labels
+// replace - Replaces the labels of the movie within the transaction
+func replaceMovieLabels(
+	params graphql.ResolveParams,
+	dialect goqu.DialectWrapper,
+	db *sql.DB,
+	argument string,
+	replace func(dialect goqu.DialectWrapper, tx *sql.Tx, moviesID string, names []string) error,
+) (interface{}, error) {
+	user, customError := source.GetUserFromToken(params.Context, dialect, db)
+	if customError != nil {
+		return nil, customError
+	}
+	if scopeErr := source.RequireScope(user, source.ScopeWrite); scopeErr != nil {
+		return nil, scopeErr
+	}
+
+	id, _ := params.Args["id"].(string)
+	names := []string{}
+	if values, ok := params.Args[argument].([]interface{}); ok {
+		for _, value := range values {
+			if name, ok := value.(string); ok {
+				names = append(names, name)
+			}
+		}
+	}
+
+	tx, txErr := db.Begin()
+	if txErr != nil {
+		return nil, txErr
+	}
+	defer tx.Rollback()
+
+	previous, movieErr := changeableMovie(dialect, tx, user, id)
+	if movieErr != nil {
+		return nil, movieErr
+	}
+
+	if replaceErr := replace(dialect, tx, previous.ID, names); replaceErr != nil {
+		return nil, replaceErr
+	}
+
+	updateDialect := dialect.Update("movies").Set(
+		goqu.Record{
+			"updated_at": time.Now().Format(time.RFC3339),
+			"version":    goqu.L("version + 1"),
+		},
+	).Where(goqu.Ex{
+		"id": previous.ID,
+	})
+	updateQuery, _, toSQLErr := updateDialect.ToSQL()
+	if toSQLErr != nil {
+		return nil, toSQLErr
+	}
+	if _, updateErr := tx.Exec(updateQuery); updateErr != nil {
+		return nil, updateErr
+	}
+
+	current, currentErr := findMovie(dialect, tx, goqu.Ex{
+		"id": previous.ID,
+	})
+	if currentErr != nil {
+		return nil, currentErr
+	}
+
+	if revisionErr := source.RecordMovieRevision(dialect, tx, user.ID, source.RevisionUpdate, previous, current); revisionErr != nil {
+		return nil, revisionErr
+	}
+
+	return current, tx.Commit()
+}
@@ -2,25 +2,47 @@ package movies
 
 import (
 	"database/sql"
+	"errors"
 	"math"
+	"strings"
 	"time"
+	"unicode/utf8"
 
 	"github.com/graphql-go/graphql"
 
 	"github.com/doug-martin/goqu/v8"
+	"github.com/doug-martin/goqu/v8/exp"
 	uuid "github.com/satori/go.uuid"
 
+	"github.com/HencoSmith/graphql-example-go/graphql/scalars"
 	"github.com/HencoSmith/graphql-example-go/models"
 	source "github.com/HencoSmith/graphql-example-go/source"
 )
 
-// findMovie - lookup a movie matching the specified expression
+// movieColumns - columns of the movies table scanned by findMovies
+var movieColumns = []interface{}{
+	"id",
+	"created_at",
+	"updated_at",
+	"deleted_at",
+	"users_id",
+	"name",
+	"release_year",
+	"description",
+	"rating",
+	"review_count",
+	"version",
+	"poster_key",
+	"thumbnail_key",
+}
+
+// findMovies - lookup the movies matching the specified expression
 // dialect - Query builder dialect object used
 // db - SQL DB connection to use
-// expression - Expression movie looking up should adhere to
-func findMovie(dialect goqu.DialectWrapper, db *sql.DB, expression goqu.Ex) (*models.Movie, error) {
-	// Find movie inserted (ID is generated by DB hence lookup is required)
-	dialectString := dialect.From("movies").Where(expression)
+// expression - Expression movies looking up should adhere to
+// order - Order of the movies
+func findMovies(dialect goqu.DialectWrapper, db queryer, expression exp.Expression, order ...exp.OrderedExpression) ([]models.Movie, error) {
+	dialectString := dialect.From("movies").Select(movieColumns...).Where(expression).Order(order...)
 	query, _, dialectErr := dialectString.ToSQL()
 	if dialectErr != nil {
 		return nil, dialectErr
@@ -32,7 +54,7 @@ func findMovie(dialect goqu.DialectWrapper, db *sql.DB, expression goqu.Ex) (*mo
 	}
 	defer rows.Close()
 
-	var moviesArr []models.Movie
+	moviesArr := []models.Movie{}
 	for rows.Next() {
 		var movieRow = models.Movie{}
 		scanErr := rows.Scan(
@@ -46,6 +68,9 @@ func findMovie(dialect goqu.DialectWrapper, db *sql.DB, expression goqu.Ex) (*mo
 			&movieRow.Description,
 			&movieRow.Rating,
 			&movieRow.ReviewCount,
+			&movieRow.Version,
+			&movieRow.PosterKey,
+			&movieRow.ThumbnailKey,
 		)
 		if scanErr != nil {
 			return nil, scanErr
@@ -56,6 +81,19 @@ func findMovie(dialect goqu.DialectWrapper, db *sql.DB, expression goqu.Ex) (*mo
 		return nil, errRows
 	}
 
+	return moviesArr, nil
+}
+
+// findMovie - lookup a movie matching the specified expression
+// dialect - Query builder dialect object used
+// db - SQL DB connection to use
+// expression - Expression movie looking up should adhere to
+func findMovie(dialect goqu.DialectWrapper, db queryer, expression goqu.Ex) (*models.Movie, error) {
+	moviesArr, findErr := findMovies(dialect, db, expression)
+	if findErr != nil {
+		return nil, findErr
+	}
+
 	if len(moviesArr) < 1 {
 		return nil, nil
 	}
@@ -63,6 +101,364 @@ func findMovie(dialect goqu.DialectWrapper, db *sql.DB, expression goqu.Ex) (*mo
 	return &moviesArr[0], nil
 }
 
+// hydrateMovies - Replace movies only holding their UUID, such as the nodes of a page of
+// edges, with the movies looked up in a single query
+// dialect - Query builder dialect object used
+// db - SQL DB connection to use
+// movies - Movies to fill in, keeping their order
+func hydrateMovies(dialect goqu.DialectWrapper, db queryer, movies []*models.Movie) error {
+	if len(movies) == 0 {
+		return nil
+	}
+
+	ids := []string{}
+	for _, movie := range movies {
+		ids = append(ids, movie.ID)
+	}
+	moviesArr, findErr := findMovies(dialect, db, goqu.Ex{
+		"id": ids,
+	})
+	if findErr != nil {
+		return findErr
+	}
+
+	moviesByID := map[string]models.Movie{}
+	for _, movie := range moviesArr {
+		moviesByID[movie.ID] = movie
+	}
+	for _, movie := range movies {
+		*movie = moviesByID[movie.ID]
+	}
+	return nil
+}
+
+// versionConflict - Determine whether a change conditioned on the expected version was refused
+// because the movie moved on to another version, rather than it no longer existing
+// dialect - Query builder dialect object used
+// db - SQL DB connection or transaction to use
+// id - UUID of the movie changed
+// expectedVersion - Version the change was conditioned on
+func versionConflict(dialect goqu.DialectWrapper, db queryer, id string, expectedVersion int) error {
+	current, findErr := findMovie(dialect, db, goqu.Ex{
+		"id":         id,
+		"deleted_at": nil,
+	})
+	if findErr != nil {
+		return findErr
+	}
+	if current != nil && current.Version != int64(expectedVersion) {
+		return source.VersionConflictError{CurrentVersion: current.Version}
+	}
+
+	return nil
+}
+
+// changeableMovie - lookup a movie which is not deleted and which the user may change, being
+// its owner or an admin
+// dialect - Query builder dialect object used
+// tx - Transaction to use
+// user - User changing the movie
+// id - UUID of the movie
+func changeableMovie(dialect goqu.DialectWrapper, tx *sql.Tx, user models.User, id string) (*models.Movie, error) {
+	movie, findErr := findMovie(dialect, tx, goqu.Ex{
+		"id":         id,
+		"deleted_at": nil,
+	})
+	if findErr != nil {
+		return nil, findErr
+	}
+	if movie == nil {
+		return nil, errors.New("Movie not found")
+	}
+	if ownerErr := source.RequireOwnerOrAdmin(user, movie.UsersID); ownerErr != nil {
+		return nil, ownerErr
+	}
+
+	return movie, nil
+}
+
+// replaceMoviePoster - Reference the stored poster and thumbnail from the movie, the files of
+// the previous poster are removed once the change is committed
+// dialect - Query builder dialect object used
+// db - SQL DB connection to use
+// user - User uploading the poster
+// id - UUID of the movie
+// posterKey - Key of the stored poster
+// thumbnailKey - Key of the stored thumbnail
+func replaceMoviePoster(dialect goqu.DialectWrapper, db *sql.DB, user models.User, id string, posterKey string, thumbnailKey string) (*models.Movie, error) {
+	tx, txErr := db.Begin()
+	if txErr != nil {
+		return nil, txErr
+	}
+	defer tx.Rollback()
+
+	// The movie may have been deleted while the image was processed
+	previous, movieErr := changeableMovie(dialect, tx, user, id)
+	if movieErr != nil {
+		return nil, movieErr
+	}
+
+	updateDialect := dialect.Update("movies").Set(
+		goqu.Record{
+			"poster_key":    posterKey,
+			"thumbnail_key": thumbnailKey,
+			"updated_at":    time.Now().Format(time.RFC3339),
+			"version":       goqu.L("version + 1"),
+		},
+	).Where(goqu.Ex{
+		"id": previous.ID,
+	})
+	updateQuery, _, toSQLErr := updateDialect.ToSQL()
+	if toSQLErr != nil {
+		return nil, toSQLErr
+	}
+	if _, updateErr := tx.Exec(updateQuery); updateErr != nil {
+		return nil, updateErr
+	}
+
+	current, currentErr := findMovie(dialect, tx, goqu.Ex{
+		"id": previous.ID,
+	})
+	if currentErr != nil {
+		return nil, currentErr
+	}
+
+	if revisionErr := source.RecordMovieRevision(dialect, tx, user.ID, source.RevisionUpdate, previous, current); revisionErr != nil {
+		return nil, revisionErr
+	}
+
+	if commitErr := tx.Commit(); commitErr != nil {
+		return nil, commitErr
+	}
+
+	source.DeleteBlobs(previous.PosterKey, previous.ThumbnailKey)
+	return current, nil
+}
+
+// movieInput - Fields of a created or updated movie, updates leave empty fields unchanged
+type movieInput struct {
+	Name            string
+	Description     string
+	ReleaseYear     int
+	ExpectedVersion int
+	Versioned       bool
+}
+
+// movieInputArgs - Read the fields of a movie from the arguments or an input object
+func movieInputArgs(args map[string]interface{}) movieInput {
+	input := movieInput{}
+	input.Name, _ = args["name"].(string)
+	input.Description, _ = args["description"].(string)
+	input.ReleaseYear, _ = args["releaseYear"].(int)
+	input.ExpectedVersion, input.Versioned = args["expectedVersion"].(int)
+	return input
+}
+
+// createMovie - Insert a movie owned by the user
+// dialect - Query builder dialect object used
+// tx - Transaction to use
+// user - User creating the movie
+// input - Fields of the movie
+func createMovie(dialect goqu.DialectWrapper, tx *sql.Tx, user models.User, input movieInput) (*models.Movie, error) {
+	if validErr := source.ValidateMovie(input.Name, input.Description, int64(input.ReleaseYear)); validErr != nil {
+		return nil, validErr
+	}
+
+	id := uuid.NewV4().String()
+	insertDialect := dialect.Insert("movies").Rows(
+		goqu.Record{
+			"id":           id,
+			"name":         input.Name,
+			"description":  input.Description,
+			"release_year": input.ReleaseYear,
+			"users_id":     user.ID,
+		},
+	)
+	insertQuery, _, toSQLErr := insertDialect.ToSQL()
+	if toSQLErr != nil {
+		return nil, toSQLErr
+	}
+
+	if _, insertErr := tx.Exec(insertQuery); insertErr != nil {
+		return nil, insertErr
+	}
+
+	movie, findErr := findMovie(dialect, tx, goqu.Ex{
+		"id": id,
+	})
+	if findErr != nil {
+		return nil, findErr
+	}
+
+	if revisionErr := source.RecordMovieRevision(dialect, tx, user.ID, source.RevisionCreate, nil, movie); revisionErr != nil {
+		return nil, revisionErr
+	}
+
+	return movie, nil
+}
+
+// updateMovie - Change the fields of a movie owned by the user, or any movie for admins, returns the
+// movie and whether it changed. Movies which do not exist or are deleted are returned unchanged,
+// movies of other users are refused with ErrForbidden
+// dialect - Query builder dialect object used
+// tx - Transaction to use
+// user - User changing the movie
+// id - UUID of the movie
+// input - Fields to change and the expected version
+func updateMovie(dialect goqu.DialectWrapper, tx *sql.Tx, user models.User, id string, input movieInput) (*models.Movie, bool, error) {
+	updateFields := goqu.Record{}
+	if len(input.Name) > 0 {
+		updateFields["name"] = input.Name
+	}
+	if len(input.Description) > 0 {
+		updateFields["description"] = input.Description
+	}
+	if input.ReleaseYear > 1900 {
+		updateFields["release_year"] = input.ReleaseYear
+	}
+	updateFields["updated_at"] = time.Now().Format(time.RFC3339)
+	updateFields["version"] = goqu.L("version + 1")
+
+	before, beforeErr := findMovie(dialect, tx, goqu.Ex{
+		"id": id,
+	})
+	if beforeErr != nil {
+		return nil, false, beforeErr
+	}
+	if before == nil || before.DeletedAt != nil {
+		return before, false, nil
+	}
+	if ownerErr := source.RequireOwnerOrAdmin(user, before.UsersID); ownerErr != nil {
+		return nil, false, ownerErr
+	}
+
+	// The movie has to remain valid with the changed fields
+	changed := *before
+	if name, ok := updateFields["name"].(string); ok {
+		changed.Name = name
+	}
+	if description, ok := updateFields["description"].(string); ok {
+		changed.Description = description
+	}
+	if releaseYear, ok := updateFields["release_year"].(int); ok {
+		changed.ReleaseYear = int64(releaseYear)
+	}
+	if validErr := source.ValidateMovie(changed.Name, changed.Description, changed.ReleaseYear); validErr != nil {
+		return nil, false, validErr
+	}
+
+	// Update the existing movie
+	expression := goqu.Ex{
+		"id":         id,
+		"deleted_at": nil,
+	}
+	if input.Versioned {
+		expression["version"] = input.ExpectedVersion
+	}
+	updateDialect := dialect.Update("movies").Set(
+		updateFields,
+	).Where(expression)
+	updateQuery, _, toSQLErr := updateDialect.ToSQL()
+	if toSQLErr != nil {
+		return nil, false, toSQLErr
+	}
+
+	updateRes, updateErr := tx.Exec(updateQuery)
+	if updateErr != nil {
+		return nil, false, updateErr
+	}
+	if updated, _ := updateRes.RowsAffected(); updated < 1 {
+		if input.Versioned {
+			if conflictErr := versionConflict(dialect, tx, id, input.ExpectedVersion); conflictErr != nil {
+				return nil, false, conflictErr
+			}
+		}
+		return before, false, nil
+	}
+
+	movie, findErr := findMovie(dialect, tx, goqu.Ex{
+		"id": id,
+	})
+	if findErr != nil {
+		return nil, false, findErr
+	}
+
+	if revisionErr := source.RecordMovieRevision(dialect, tx, user.ID, source.RevisionUpdate, before, movie); revisionErr != nil {
+		return nil, false, revisionErr
+	}
+
+	return movie, true, nil
+}
+
+// deleteMovie - Soft-delete a movie owned by the user, or any movie for admins, returns the movie
+// as it was before and whether it was deleted. Movies which do not exist or are deleted are left
+// as they are, movies of other users are refused with ErrForbidden
+// dialect - Query builder dialect object used
+// tx - Transaction to use
+// user - User deleting the movie
+// id - UUID of the movie
+// input - The expected version
+func deleteMovie(dialect goqu.DialectWrapper, tx *sql.Tx, user models.User, id string, input movieInput) (*models.Movie, bool, error) {
+	// Lookup existing movie
+	movie, findErr := findMovie(dialect, tx, goqu.Ex{
+		"id": id,
+	})
+	if findErr != nil {
+		return nil, false, findErr
+	}
+	if movie == nil || movie.DeletedAt != nil {
+		return movie, false, nil
+	}
+	if ownerErr := source.RequireOwnerOrAdmin(user, movie.UsersID); ownerErr != nil {
+		return nil, false, ownerErr
+	}
+
+	// Remove the existing movie
+	expression := goqu.Ex{
+		"id":         id,
+		"deleted_at": nil,
+	}
+	if input.Versioned {
+		expression["version"] = input.ExpectedVersion
+	}
+	deleteDialect := dialect.Update("movies").Set(
+		goqu.Record{
+			"deleted_at": time.Now().Format(time.RFC3339),
+			"version":    goqu.L("version + 1"),
+		},
+	).Where(expression)
+	deleteQuery, _, toSQLErr := deleteDialect.ToSQL()
+	if toSQLErr != nil {
+		return nil, false, toSQLErr
+	}
+
+	deleteRes, deleteErr := tx.Exec(deleteQuery)
+	if deleteErr != nil {
+		return nil, false, deleteErr
+	}
+	if deleted, _ := deleteRes.RowsAffected(); deleted < 1 {
+		if input.Versioned {
+			if conflictErr := versionConflict(dialect, tx, id, input.ExpectedVersion); conflictErr != nil {
+				return nil, false, conflictErr
+			}
+		}
+		return movie, false, nil
+	}
+
+	after, afterErr := findMovie(dialect, tx, goqu.Ex{
+		"id": id,
+	})
+	if afterErr != nil {
+		return nil, false, afterErr
+	}
+
+	if revisionErr := source.RecordMovieRevision(dialect, tx, user.ID, source.RevisionDelete, movie, after); revisionErr != nil {
+		return nil, false, revisionErr
+	}
+
+	return movie, true, nil
+}
+
 // calculateMovieRating - Determine the overall sum of ratings for a movie
 // dialect - Query builder dialect object used
 // db - SQL DB connection to use
@@ -105,6 +501,36 @@ func calculateMovieRating(dialect goqu.DialectWrapper, db *sql.DB, id string) (f
 	return ratingsArr[0].Total, nil
 }
 
+// savedListMutation - Mutation adding a movie to or removing it from a list of the current user
+// list - models.Watchlist or models.Favorites
+// save - true to add the movie, false to remove it
+func savedListMutation(dialect goqu.DialectWrapper, db *sql.DB, list string, save bool, description string) *graphql.Field {
+	return &graphql.Field{
+		Type:        MovieType,
+		Description: description,
+		Args: graphql.FieldConfigArgument{
+			"movieId": &graphql.ArgumentConfig{
+				Type: graphql.NewNonNull(scalars.UUID),
+			},
+		},
+		Resolve: func(params graphql.ResolveParams) (interface{}, error) {
+			user, customError := source.GetUserFromToken(params.Context, dialect, db)
+			if customError != nil {
+				return nil, customError
+			}
+			if scopeErr := source.RequireScope(user, source.ScopeWrite); scopeErr != nil {
+				return nil, scopeErr
+			}
+
+			id, _ := params.Args["movieId"].(string)
+			if save {
+				return saveMovie(dialect, db, user.ID, list, id)
+			}
+			return unsaveMovie(dialect, db, user.ID, list, id)
+		},
+	}
+}
+
 // Mutations - all GraphQL mutations related to movies
 func Mutations(dialect goqu.DialectWrapper, db *sql.DB) graphql.Fields {
 	return graphql.Fields{
@@ -127,47 +553,31 @@ func Mutations(dialect goqu.DialectWrapper, db *sql.DB) graphql.Fields {
 				if customError != nil {
 					return nil, customError
 				}
-
-				// Insert the new movie
-				name, _ := params.Args["name"].(string)
-				description, _ := params.Args["description"].(string)
-				releaseYear, _ := params.Args["releaseYear"].(int)
-				insertDialect := goqu.Insert("movies").Rows(
-					goqu.Record{
-						"id":           uuid.NewV4(),
-						"name":         name,
-						"description":  description,
-						"release_year": releaseYear,
-						"users_id":     user.ID,
-					},
-				)
-				insertQuery, _, toSQLErr := insertDialect.ToSQL()
-				if toSQLErr != nil {
-					return nil, toSQLErr
+				if scopeErr := source.RequireScope(user, source.ScopeWrite); scopeErr != nil {
+					return nil, scopeErr
 				}
 
-				insertRes, insertErr := db.Query(insertQuery)
-				if insertErr != nil {
-					return nil, insertErr
+				tx, txErr := db.Begin()
+				if txErr != nil {
+					return nil, txErr
 				}
+				defer tx.Rollback()
 
-				defer insertRes.Close()
-
-				movie, findErr := findMovie(dialect, db, goqu.Ex{
-					// Name has a unique constraint
-					"name": params.Args["name"].(string),
-				})
+				movie, createErr := createMovie(dialect, tx, user, movieInputArgs(params.Args))
+				if createErr != nil {
+					return nil, createErr
+				}
 
-				return movie, findErr
+				return movie, tx.Commit()
 			},
 		},
 
 		"update": &graphql.Field{
 			Type:        MovieType,
-			Description: "Update movie by ID",
+			Description: "Update movie by ID, only the owner or an admin may update it",
 			Args: graphql.FieldConfigArgument{
 				"id": &graphql.ArgumentConfig{
-					Type: graphql.NewNonNull(graphql.String),
+					Type: graphql.NewNonNull(scalars.UUID),
 				},
 				"name": &graphql.ArgumentConfig{
 					Type: graphql.String,
@@ -178,65 +588,47 @@ func Mutations(dialect goqu.DialectWrapper, db *sql.DB) graphql.Fields {
 				"releaseYear": &graphql.ArgumentConfig{
 					Type: graphql.Int,
 				},
+				"expectedVersion": &graphql.ArgumentConfig{
+					Type:        graphql.Int,
+					Description: "Fail with a VERSION_CONFLICT error unless the movie is still at this version",
+				},
 			},
 			Resolve: func(params graphql.ResolveParams) (interface{}, error) {
 				user, customError := source.GetUserFromToken(params.Context, dialect, db)
 				if customError != nil {
 					return nil, customError
 				}
+				if scopeErr := source.RequireScope(user, source.ScopeWrite); scopeErr != nil {
+					return nil, scopeErr
+				}
 
 				id, _ := params.Args["id"].(string)
-				name, _ := params.Args["name"].(string)
-				description, _ := params.Args["description"].(string)
-				releaseYear, _ := params.Args["releaseYear"].(int)
-				updatedAt := time.Now().Format(time.RFC3339)
 
-				updateFields := goqu.Record{}
-				if len(name) > 0 {
-					updateFields["name"] = name
-				}
-				if len(description) > 0 {
-					updateFields["description"] = description
-				}
-				if releaseYear > 1900 {
-					updateFields["release_year"] = releaseYear
-				}
-				updateFields["updated_at"] = updatedAt
-
-				// Update the existing movie
-				updateDialect := goqu.Update("movies").Set(
-					updateFields,
-				).Where(goqu.Ex{
-					"id":         id,
-					"deleted_at": nil,
-					"users_id":   user.ID,
-				})
-				updateQuery, _, toSQLErr := updateDialect.ToSQL()
-				if toSQLErr != nil {
-					return nil, toSQLErr
+				tx, txErr := db.Begin()
+				if txErr != nil {
+					return nil, txErr
 				}
+				defer tx.Rollback()
 
-				updateRes, updateErr := db.Query(updateQuery)
-				if updateErr != nil {
-					return nil, updateErr
+				movie, updated, updateErr := updateMovie(dialect, tx, user, id, movieInputArgs(params.Args))
+				if updateErr != nil || !updated {
+					return movie, updateErr
 				}
 
-				defer updateRes.Close()
-
-				movie, findErr := findMovie(dialect, db, goqu.Ex{
-					"id": id,
-				})
-
-				return movie, findErr
+				return movie, tx.Commit()
 			},
 		},
 
 		"delete": &graphql.Field{
 			Type:        MovieType,
-			Description: "Delete movie by ID",
+			Description: "Delete movie by ID, only the owner or an admin may delete it",
 			Args: graphql.FieldConfigArgument{
 				"id": &graphql.ArgumentConfig{
-					Type: graphql.NewNonNull(graphql.String),
+					Type: graphql.NewNonNull(scalars.UUID),
+				},
+				"expectedVersion": &graphql.ArgumentConfig{
+					Type:        graphql.Int,
+					Description: "Fail with a VERSION_CONFLICT error unless the movie is still at this version",
 				},
 			},
 			Resolve: func(params graphql.ResolveParams) (interface{}, error) {
@@ -244,52 +636,38 @@ func Mutations(dialect goqu.DialectWrapper, db *sql.DB) graphql.Fields {
 				if customError != nil {
 					return nil, customError
 				}
+				if scopeErr := source.RequireScope(user, source.ScopeWrite); scopeErr != nil {
+					return nil, scopeErr
+				}
 
 				id, _ := params.Args["id"].(string)
 
-				// Lookup existing movie
-				movie, findErr := findMovie(dialect, db, goqu.Ex{
-					"id": id,
-				})
-				if findErr != nil {
-					return nil, findErr
-				}
-
-				// Remove the existing movie
-				deleteDialect := goqu.Update("movies").Set(
-					goqu.Record{
-						"deleted_at": time.Now().Format(time.RFC3339),
-					},
-				).Where(goqu.Ex{
-					"id":       id,
-					"users_id": user.ID,
-				})
-				deleteQuery, _, toSQLErr := deleteDialect.ToSQL()
-				if toSQLErr != nil {
-					return nil, toSQLErr
+				tx, txErr := db.Begin()
+				if txErr != nil {
+					return nil, txErr
 				}
+				defer tx.Rollback()
 
-				deleteRes, deleteErr := db.Query(deleteQuery)
-				if deleteErr != nil {
-					return nil, deleteErr
+				movie, deleted, deleteErr := deleteMovie(dialect, tx, user, id, movieInputArgs(params.Args))
+				if deleteErr != nil || !deleted {
+					return movie, deleteErr
 				}
 
-				defer deleteRes.Close()
-
-				return movie, nil
+				return movie, tx.Commit()
 			},
 		},
 
-		"rate": &graphql.Field{
-			Type:        graphql.String,
-			Description: "Rate a movie by ID. Returns 'success' / 'failure'",
+		"createMovies": &graphql.Field{
+			Type:        BulkMoviesType,
+			Description: "Create many movies in a single transaction, returning the outcome of every movie",
 			Args: graphql.FieldConfigArgument{
-				"id": &graphql.ArgumentConfig{
-					Type: graphql.NewNonNull(graphql.String),
+				"movies": &graphql.ArgumentConfig{
+					Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(MovieCreateInputType))),
 				},
-				"rating": &graphql.ArgumentConfig{
-					Type:        graphql.NewNonNull(graphql.Int),
-					Description: "0 - 10 (0 - worst; 10 - best)",
+				"allOrNothing": &graphql.ArgumentConfig{
+					Type:         graphql.Boolean,
+					DefaultValue: false,
+					Description:  "Roll back every movie when one fails",
 				},
 			},
 			Resolve: func(params graphql.ResolveParams) (interface{}, error) {
@@ -297,43 +675,896 @@ func Mutations(dialect goqu.DialectWrapper, db *sql.DB) graphql.Fields {
 				if customError != nil {
 					return nil, customError
 				}
+				if scopeErr := source.RequireScope(user, source.ScopeWrite); scopeErr != nil {
+					return nil, scopeErr
+				}
 
-				id, _ := params.Args["id"].(string)
-				rating, _ := params.Args["rating"].(int)
-
-				// Limit rating 0 - 10
-				formattedRating := math.Max(float64(rating), float64(0))
-				formattedRating = math.Min(float64(formattedRating), float64(10))
-
-				// Find the current rating
-				movie, findErr := findMovie(dialect, db, goqu.Ex{
-					"id": id,
+				inputs := bulkInputs(params.Args)
+				allOrNothing, _ := params.Args["allOrNothing"].(bool)
+				return bulkMovies(db, len(inputs), allOrNothing, func(tx *sql.Tx, index int) (*models.Movie, error) {
+					return createMovie(dialect, tx, user, movieInputArgs(inputs[index]))
 				})
+			},
+		},
 
-				if findErr != nil {
-					return "failure", findErr
+		"updateMovies": &graphql.Field{
+			Type:        BulkMoviesType,
+			Description: "Update many movies in a single transaction like update, returning the outcome of every movie",
+			Args: graphql.FieldConfigArgument{
+				"movies": &graphql.ArgumentConfig{
+					Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(MovieUpdateInputType))),
+				},
+				"allOrNothing": &graphql.ArgumentConfig{
+					Type:         graphql.Boolean,
+					DefaultValue: false,
+					Description:  "Roll back every movie when one fails",
+				},
+			},
+			Resolve: func(params graphql.ResolveParams) (interface{}, error) {
+				user, customError := source.GetUserFromToken(params.Context, dialect, db)
+				if customError != nil {
+					return nil, customError
 				}
-
-				// Add the rating as a running total
-				insertDialect := goqu.Insert("movies_reviews").Rows(
-					goqu.Record{
-						"id":        uuid.NewV4(),
-						"rating":    formattedRating,
-						"movies_id": movie.ID,
-						"users_id":  user.ID,
-					},
-				)
-				insertQuery, _, insertToSQLErr := insertDialect.ToSQL()
-				if insertToSQLErr != nil {
-					return "failure", insertToSQLErr
+				if scopeErr := source.RequireScope(user, source.ScopeWrite); scopeErr != nil {
+					return nil, scopeErr
 				}
 
-				insertRes, insertErr := db.Query(insertQuery)
-				if insertErr != nil {
-					return "failure", insertErr
-				}
+				inputs := bulkInputs(params.Args)
+				allOrNothing, _ := params.Args["allOrNothing"].(bool)
+				return bulkMovies(db, len(inputs), allOrNothing, func(tx *sql.Tx, index int) (*models.Movie, error) {
+					id, _ := inputs[index]["id"].(string)
+					movie, updated, updateErr := updateMovie(dialect, tx, user, id, movieInputArgs(inputs[index]))
+					if updateErr == nil && !updated {
+						return nil, errMovieNotFound
+					}
+					return movie, updateErr
+				})
+			},
+		},
 
-				defer insertRes.Close()
+		"deleteMovies": &graphql.Field{
+			Type:        BulkMoviesType,
+			Description: "Delete many movies in a single transaction like delete, returning the outcome of every movie",
+			Args: graphql.FieldConfigArgument{
+				"movies": &graphql.ArgumentConfig{
+					Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(MovieDeleteInputType))),
+				},
+				"allOrNothing": &graphql.ArgumentConfig{
+					Type:         graphql.Boolean,
+					DefaultValue: false,
+					Description:  "Roll back every movie when one fails",
+				},
+			},
+			Resolve: func(params graphql.ResolveParams) (interface{}, error) {
+				user, customError := source.GetUserFromToken(params.Context, dialect, db)
+				if customError != nil {
+					return nil, customError
+				}
+				if scopeErr := source.RequireScope(user, source.ScopeWrite); scopeErr != nil {
+					return nil, scopeErr
+				}
+
+				inputs := bulkInputs(params.Args)
+				allOrNothing, _ := params.Args["allOrNothing"].(bool)
+				return bulkMovies(db, len(inputs), allOrNothing, func(tx *sql.Tx, index int) (*models.Movie, error) {
+					id, _ := inputs[index]["id"].(string)
+					movie, deleted, deleteErr := deleteMovie(dialect, tx, user, id, movieInputArgs(inputs[index]))
+					if deleteErr == nil && !deleted {
+						return nil, errMovieNotFound
+					}
+					return movie, deleteErr
+				})
+			},
+		},
+
+		"restoreMovie": &graphql.Field{
+			Type:        MovieType,
+			Description: "Restore a deleted movie by ID, only the owner or an admin may restore it",
+			Args: graphql.FieldConfigArgument{
+				"id": &graphql.ArgumentConfig{
+					Type: graphql.NewNonNull(scalars.UUID),
+				},
+			},
+			Resolve: func(params graphql.ResolveParams) (interface{}, error) {
+				user, customError := source.GetUserFromToken(params.Context, dialect, db)
+				if customError != nil {
+					return nil, customError
+				}
+				if scopeErr := source.RequireScope(user, source.ScopeWrite); scopeErr != nil {
+					return nil, scopeErr
+				}
+
+				id, _ := params.Args["id"].(string)
+
+				tx, txErr := db.Begin()
+				if txErr != nil {
+					return nil, txErr
+				}
+				defer tx.Rollback()
+
+				// Lookup the deleted movie
+				movie, findErr := findMovie(dialect, tx, goqu.Ex{
+					"id":         id,
+					"deleted_at": goqu.Op{"neq": nil},
+				})
+				if findErr != nil {
+					return nil, findErr
+				}
+				if movie == nil {
+					return nil, errors.New("Deleted movie not found")
+				}
+				if ownerErr := source.RequireOwnerOrAdmin(user, movie.UsersID); ownerErr != nil {
+					return nil, ownerErr
+				}
+
+				restoreDialect := dialect.Update("movies").Set(
+					goqu.Record{
+						"deleted_at": nil,
+						"updated_at": time.Now().Format(time.RFC3339),
+						"version":    goqu.L("version + 1"),
+					},
+				).Where(goqu.Ex{
+					"id": id,
+				})
+				restoreQuery, _, toSQLErr := restoreDialect.ToSQL()
+				if toSQLErr != nil {
+					return nil, toSQLErr
+				}
+
+				if _, restoreErr := tx.Exec(restoreQuery); restoreErr != nil {
+					return nil, restoreErr
+				}
+
+				restored, restoredErr := findMovie(dialect, tx, goqu.Ex{
+					"id": id,
+				})
+				if restoredErr != nil {
+					return nil, restoredErr
+				}
+
+				if revisionErr := source.RecordMovieRevision(dialect, tx, user.ID, source.RevisionRestore, movie, restored); revisionErr != nil {
+					return nil, revisionErr
+				}
+
+				return restored, tx.Commit()
+			},
+		},
+
+		"revertMovie": &graphql.Field{
+			Type:        MovieType,
+			Description: "Reset the name, description and release year of a movie to those after the revision, only the owner or an admin may revert it",
+			Args: graphql.FieldConfigArgument{
+				"revisionId": &graphql.ArgumentConfig{
+					Type: graphql.NewNonNull(scalars.UUID),
+				},
+			},
+			Resolve: func(params graphql.ResolveParams) (interface{}, error) {
+				user, customError := source.GetUserFromToken(params.Context, dialect, db)
+				if customError != nil {
+					return nil, customError
+				}
+				if scopeErr := source.RequireScope(user, source.ScopeWrite); scopeErr != nil {
+					return nil, scopeErr
+				}
+
+				revisionID, _ := params.Args["revisionId"].(string)
+
+				tx, txErr := db.Begin()
+				if txErr != nil {
+					return nil, txErr
+				}
+				defer tx.Rollback()
+
+				revisions, revisionsErr := findRevisions(dialect, tx, goqu.Ex{
+					"id": revisionID,
+				})
+				if revisionsErr != nil {
+					return nil, revisionsErr
+				}
+				if len(revisions) < 1 || revisions[0].After == nil {
+					return nil, errors.New("Movie revision not found")
+				}
+				target := revisions[0].After
+
+				// Deleted movies have to be restored before they can be changed
+				movie, findErr := findMovie(dialect, tx, goqu.Ex{
+					"id":         revisions[0].MoviesID,
+					"deleted_at": nil,
+				})
+				if findErr != nil {
+					return nil, findErr
+				}
+				if movie == nil {
+					return nil, errors.New("Movie not found")
+				}
+				if ownerErr := source.RequireOwnerOrAdmin(user, movie.UsersID); ownerErr != nil {
+					return nil, ownerErr
+				}
+
+				revertDialect := dialect.Update("movies").Set(
+					goqu.Record{
+						"name":         target.Name,
+						"description":  target.Description,
+						"release_year": target.ReleaseYear,
+						"updated_at":   time.Now().Format(time.RFC3339),
+						"version":      goqu.L("version + 1"),
+					},
+				).Where(goqu.Ex{
+					"id": movie.ID,
+				})
+				revertQuery, _, toSQLErr := revertDialect.ToSQL()
+				if toSQLErr != nil {
+					return nil, toSQLErr
+				}
+
+				if _, revertErr := tx.Exec(revertQuery); revertErr != nil {
+					return nil, revertErr
+				}
+
+				reverted, revertedErr := findMovie(dialect, tx, goqu.Ex{
+					"id": movie.ID,
+				})
+				if revertedErr != nil {
+					return nil, revertedErr
+				}
+
+				if revisionErr := source.RecordMovieRevision(dialect, tx, user.ID, source.RevisionRevert, movie, reverted); revisionErr != nil {
+					return nil, revisionErr
+				}
+
+				return reverted, tx.Commit()
+			},
+		},
+
+		"setMovieGenres": &graphql.Field{
+			Type:        MovieType,
+			Description: "Replace the genres of a movie, only the owner or an admin may change them",
+			Args: graphql.FieldConfigArgument{
+				"id": &graphql.ArgumentConfig{
+					Type: graphql.NewNonNull(scalars.UUID),
+				},
+				"genres": &graphql.ArgumentConfig{
+					Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(graphql.String))),
+				},
+			},
+			Resolve: func(params graphql.ResolveParams) (interface{}, error) {
+				return replaceMovieLabels(params, dialect, db, "genres", setMovieGenres)
+			},
+		},
+
+		"setMovieTags": &graphql.Field{
+			Type:        MovieType,
+			Description: "Replace the tags of a movie, new tags are created as needed, only the owner or an admin may change them",
+			Args: graphql.FieldConfigArgument{
+				"id": &graphql.ArgumentConfig{
+					Type: graphql.NewNonNull(scalars.UUID),
+				},
+				"tags": &graphql.ArgumentConfig{
+					Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(graphql.String))),
+				},
+			},
+			Resolve: func(params graphql.ResolveParams) (interface{}, error) {
+				return replaceMovieLabels(params, dialect, db, "tags", setMovieTags)
+			},
+		},
+
+		"uploadPoster": &graphql.Field{
+			Type:        MovieType,
+			Description: "Upload the poster of a movie as a multipart request, a thumbnail is generated from it. Only the owner or an admin may upload it",
+			Args: graphql.FieldConfigArgument{
+				"movieId": &graphql.ArgumentConfig{
+					Type: graphql.NewNonNull(scalars.UUID),
+				},
+				"file": &graphql.ArgumentConfig{
+					Type: graphql.NewNonNull(scalars.Upload),
+				},
+			},
+			Resolve: func(params graphql.ResolveParams) (interface{}, error) {
+				user, customError := source.GetUserFromToken(params.Context, dialect, db)
+				if customError != nil {
+					return nil, customError
+				}
+				if scopeErr := source.RequireScope(user, source.ScopeWrite); scopeErr != nil {
+					return nil, scopeErr
+				}
+
+				id, _ := params.Args["movieId"].(string)
+				upload, ok := params.Args["file"].(*models.Upload)
+				if !ok {
+					return nil, errors.New("No file uploaded")
+				}
+
+				// Check access before storing anything
+				movie, findErr := findMovie(dialect, db, goqu.Ex{
+					"id":         id,
+					"deleted_at": nil,
+				})
+				if findErr != nil {
+					return nil, findErr
+				}
+				if movie == nil {
+					return nil, errors.New("Movie not found")
+				}
+				if ownerErr := source.RequireOwnerOrAdmin(user, movie.UsersID); ownerErr != nil {
+					return nil, ownerErr
+				}
+
+				posterKey, thumbnailKey, storeErr := source.StoreImage(*upload, "posters/"+movie.ID)
+				if storeErr != nil {
+					return nil, storeErr
+				}
+
+				updated, updateErr := replaceMoviePoster(dialect, db, user, movie.ID, posterKey, thumbnailKey)
+				if updateErr != nil {
+					source.DeleteBlobs(posterKey, thumbnailKey)
+					return nil, updateErr
+				}
+
+				return updated, nil
+			},
+		},
+
+		"importMovies": &graphql.Field{
+			Type:        MovieImportType,
+			Description: "Create movies from a CSV or JSON Lines file uploaded as a multipart request, rows matching an existing movie by name and release year are skipped",
+			Args: graphql.FieldConfigArgument{
+				"file": &graphql.ArgumentConfig{
+					Type: graphql.NewNonNull(scalars.Upload),
+				},
+				"format": &graphql.ArgumentConfig{
+					Type:        graphql.String,
+					Description: "'csv' or 'jsonl', determined from the file name by default",
+				},
+			},
+			Resolve: func(params graphql.ResolveParams) (interface{}, error) {
+				user, customError := source.GetUserFromToken(params.Context, dialect, db)
+				if customError != nil {
+					return nil, customError
+				}
+				if scopeErr := source.RequireScope(user, source.ScopeWrite); scopeErr != nil {
+					return nil, scopeErr
+				}
+
+				upload, ok := params.Args["file"].(*models.Upload)
+				if !ok || upload.File == nil {
+					return nil, errors.New("No file uploaded")
+				}
+				format, _ := params.Args["format"].(string)
+				if len(format) == 0 {
+					format = source.ImportFormat(upload.Filename)
+				}
+
+				result, importErr := source.ImportMovies(dialect, db, user.ID, strings.ToLower(format), upload.File)
+				if importErr != nil {
+					// Failing before any row was processed is an error of the request itself
+					if result.Imported == 0 && result.Skipped == 0 && len(result.Errors) == 0 {
+						return nil, importErr
+					}
+					// Returning the error would discard the result, report it along with the
+					// rows processed so far
+					result.Error = importErr.Error()
+				}
+
+				return result, nil
+			},
+		},
+
+		"createGenre": &graphql.Field{
+			Type:        GenreType,
+			Description: "Add a genre to the taxonomy, only admins may add genres",
+			Args: graphql.FieldConfigArgument{
+				"name": &graphql.ArgumentConfig{
+					Type: graphql.NewNonNull(graphql.String),
+				},
+			},
+			Resolve: func(params graphql.ResolveParams) (interface{}, error) {
+				user, customError := source.GetUserFromToken(params.Context, dialect, db)
+				if customError != nil {
+					return nil, customError
+				}
+				if scopeErr := source.RequireScope(user, source.ScopeWrite); scopeErr != nil {
+					return nil, scopeErr
+				}
+				if !source.IsAdmin(user) {
+					return nil, source.ErrForbidden
+				}
+
+				name, _ := params.Args["name"].(string)
+				name = strings.TrimSpace(name)
+				if length := utf8.RuneCountInString(name); length == 0 || length > maxGenreLength {
+					return nil, errors.New("Genre name must be between 1 and 64 characters")
+				}
+
+				id := uuid.NewV4().String()
+				insertDialect := dialect.Insert("genres").Rows(
+					goqu.Record{
+						"id":   id,
+						"name": name,
+					},
+				)
+				insertQuery, _, toSQLErr := insertDialect.ToSQL()
+				if toSQLErr != nil {
+					return nil, toSQLErr
+				}
+				if _, insertErr := db.Exec(insertQuery); insertErr != nil {
+					if strings.Contains(insertErr.Error(), "genres_name_idx") {
+						return nil, errors.New("Genre already exists")
+					}
+					return nil, insertErr
+				}
+
+				genres, findErr := findGenres(dialect, db, goqu.Ex{
+					"genres.id": id,
+				})
+				if findErr != nil {
+					return nil, findErr
+				}
+				if len(genres) < 1 {
+					return nil, errors.New("Genre not found")
+				}
+				return genres[0], nil
+			},
+		},
+
+		"createPerson": &graphql.Field{
+			Type:        PersonType,
+			Description: "Add a person who can be credited in movies",
+			Args: graphql.FieldConfigArgument{
+				"name": &graphql.ArgumentConfig{
+					Type: graphql.NewNonNull(graphql.String),
+				},
+				"biography": &graphql.ArgumentConfig{
+					Type: graphql.String,
+				},
+			},
+			Resolve: func(params graphql.ResolveParams) (interface{}, error) {
+				user, customError := source.GetUserFromToken(params.Context, dialect, db)
+				if customError != nil {
+					return nil, customError
+				}
+				if scopeErr := source.RequireScope(user, source.ScopeWrite); scopeErr != nil {
+					return nil, scopeErr
+				}
+
+				name, _ := params.Args["name"].(string)
+				biography, _ := params.Args["biography"].(string)
+				name = strings.TrimSpace(name)
+				if validErr := validPerson(name, biography); validErr != nil {
+					return nil, validErr
+				}
+
+				id := uuid.NewV4().String()
+				insertDialect := dialect.Insert("people").Rows(
+					goqu.Record{
+						"id":        id,
+						"users_id":  user.ID,
+						"name":      name,
+						"biography": biography,
+					},
+				)
+				insertQuery, _, toSQLErr := insertDialect.ToSQL()
+				if toSQLErr != nil {
+					return nil, toSQLErr
+				}
+				if _, insertErr := db.Exec(insertQuery); insertErr != nil {
+					return nil, insertErr
+				}
+
+				return findPerson(dialect, db, goqu.Ex{
+					"id": id,
+				})
+			},
+		},
+
+		"updatePerson": &graphql.Field{
+			Type:        PersonType,
+			Description: "Update person by ID, only the user who added the person or an admin may update it",
+			Args: graphql.FieldConfigArgument{
+				"id": &graphql.ArgumentConfig{
+					Type: graphql.NewNonNull(scalars.UUID),
+				},
+				"name": &graphql.ArgumentConfig{
+					Type: graphql.String,
+				},
+				"biography": &graphql.ArgumentConfig{
+					Type: graphql.String,
+				},
+			},
+			Resolve: func(params graphql.ResolveParams) (interface{}, error) {
+				user, customError := source.GetUserFromToken(params.Context, dialect, db)
+				if customError != nil {
+					return nil, customError
+				}
+				if scopeErr := source.RequireScope(user, source.ScopeWrite); scopeErr != nil {
+					return nil, scopeErr
+				}
+
+				id, _ := params.Args["id"].(string)
+				person, findErr := findPerson(dialect, db, goqu.Ex{
+					"id":         id,
+					"deleted_at": nil,
+				})
+				if findErr != nil {
+					return nil, findErr
+				}
+				if person == nil {
+					return nil, errors.New("Person not found")
+				}
+				if ownerErr := source.RequireOwnerOrAdmin(user, person.UsersID); ownerErr != nil {
+					return nil, ownerErr
+				}
+
+				updateFields := goqu.Record{
+					"updated_at": time.Now().Format(time.RFC3339),
+				}
+				name := person.Name
+				if value, ok := params.Args["name"].(string); ok {
+					name = strings.TrimSpace(value)
+					updateFields["name"] = name
+				}
+				biography := person.Biography
+				if value, ok := params.Args["biography"].(string); ok {
+					biography = value
+					updateFields["biography"] = biography
+				}
+				if validErr := validPerson(name, biography); validErr != nil {
+					return nil, validErr
+				}
+
+				updateDialect := dialect.Update("people").Set(updateFields).Where(goqu.Ex{
+					"id": id,
+				})
+				updateQuery, _, toSQLErr := updateDialect.ToSQL()
+				if toSQLErr != nil {
+					return nil, toSQLErr
+				}
+				if _, updateErr := db.Exec(updateQuery); updateErr != nil {
+					return nil, updateErr
+				}
+
+				return findPerson(dialect, db, goqu.Ex{
+					"id": id,
+				})
+			},
+		},
+
+		"deletePerson": &graphql.Field{
+			Type:        PersonType,
+			Description: "Delete person by ID, their credits are hidden from movies. Only the user who added the person or an admin may delete it",
+			Args: graphql.FieldConfigArgument{
+				"id": &graphql.ArgumentConfig{
+					Type: graphql.NewNonNull(scalars.UUID),
+				},
+			},
+			Resolve: func(params graphql.ResolveParams) (interface{}, error) {
+				user, customError := source.GetUserFromToken(params.Context, dialect, db)
+				if customError != nil {
+					return nil, customError
+				}
+				if scopeErr := source.RequireScope(user, source.ScopeWrite); scopeErr != nil {
+					return nil, scopeErr
+				}
+
+				id, _ := params.Args["id"].(string)
+				person, findErr := findPerson(dialect, db, goqu.Ex{
+					"id":         id,
+					"deleted_at": nil,
+				})
+				if findErr != nil {
+					return nil, findErr
+				}
+				if person == nil {
+					return nil, errors.New("Person not found")
+				}
+				if ownerErr := source.RequireOwnerOrAdmin(user, person.UsersID); ownerErr != nil {
+					return nil, ownerErr
+				}
+
+				// Credits of deleted people are hidden from movies and filmographies
+				deleteDialect := dialect.Update("people").Set(
+					goqu.Record{
+						"deleted_at": time.Now().Format(time.RFC3339),
+					},
+				).Where(goqu.Ex{
+					"id": id,
+				})
+				deleteQuery, _, toSQLErr := deleteDialect.ToSQL()
+				if toSQLErr != nil {
+					return nil, toSQLErr
+				}
+				if _, deleteErr := db.Exec(deleteQuery); deleteErr != nil {
+					return nil, deleteErr
+				}
+
+				return person, nil
+			},
+		},
+
+		"addCredit": &graphql.Field{
+			Type:        CreditType,
+			Description: "Credit a person with a role in a movie, only the owner of the movie or an admin may change its credits",
+			Args: graphql.FieldConfigArgument{
+				"movieId": &graphql.ArgumentConfig{
+					Type: graphql.NewNonNull(scalars.UUID),
+				},
+				"personId": &graphql.ArgumentConfig{
+					Type: graphql.NewNonNull(scalars.UUID),
+				},
+				"role": &graphql.ArgumentConfig{
+					Type:        graphql.NewNonNull(graphql.String),
+					Description: "'actor', 'director', 'writer', 'producer', 'composer', 'cinematographer' or 'editor'",
+				},
+				"characterName": &graphql.ArgumentConfig{
+					Type: graphql.String,
+				},
+				"position": &graphql.ArgumentConfig{
+					Type:        graphql.Int,
+					Description: "Order of the credit among those with the same role",
+				},
+			},
+			Resolve: func(params graphql.ResolveParams) (interface{}, error) {
+				user, customError := source.GetUserFromToken(params.Context, dialect, db)
+				if customError != nil {
+					return nil, customError
+				}
+				if scopeErr := source.RequireScope(user, source.ScopeWrite); scopeErr != nil {
+					return nil, scopeErr
+				}
+
+				movieID, _ := params.Args["movieId"].(string)
+				personID, _ := params.Args["personId"].(string)
+				role, _ := params.Args["role"].(string)
+				characterName, _ := params.Args["characterName"].(string)
+				position, _ := params.Args["position"].(int)
+				if roleErr := validCreditRole(role); roleErr != nil {
+					return nil, roleErr
+				}
+				if utf8.RuneCountInString(characterName) > 128 {
+					return nil, errors.New("Character name must not exceed 128 characters")
+				}
+
+				tx, txErr := db.Begin()
+				if txErr != nil {
+					return nil, txErr
+				}
+				defer tx.Rollback()
+
+				movie, movieErr := changeableMovie(dialect, tx, user, movieID)
+				if movieErr != nil {
+					return nil, movieErr
+				}
+				person, personErr := findPerson(dialect, tx, goqu.Ex{
+					"id":         personID,
+					"deleted_at": nil,
+				})
+				if personErr != nil {
+					return nil, personErr
+				}
+				if person == nil {
+					return nil, errors.New("Person not found")
+				}
+
+				id := uuid.NewV4().String()
+				insertDialect := dialect.Insert("movies_credits").Rows(
+					goqu.Record{
+						"id":             id,
+						"movies_id":      movie.ID,
+						"people_id":      person.ID,
+						"role":           role,
+						"character_name": strings.TrimSpace(characterName),
+						"position":       position,
+					},
+				)
+				insertQuery, _, toSQLErr := insertDialect.ToSQL()
+				if toSQLErr != nil {
+					return nil, toSQLErr
+				}
+				if _, insertErr := tx.Exec(insertQuery); insertErr != nil {
+					return nil, insertErr
+				}
+
+				credit, findErr := findCredit(dialect, tx, id)
+				if findErr != nil {
+					return nil, findErr
+				}
+
+				return credit, tx.Commit()
+			},
+		},
+
+		"updateCredit": &graphql.Field{
+			Type:        CreditType,
+			Description: "Update credit by ID, only the owner of the movie or an admin may change its credits",
+			Args: graphql.FieldConfigArgument{
+				"id": &graphql.ArgumentConfig{
+					Type: graphql.NewNonNull(scalars.UUID),
+				},
+				"role": &graphql.ArgumentConfig{
+					Type: graphql.String,
+				},
+				"characterName": &graphql.ArgumentConfig{
+					Type: graphql.String,
+				},
+				"position": &graphql.ArgumentConfig{
+					Type: graphql.Int,
+				},
+			},
+			Resolve: func(params graphql.ResolveParams) (interface{}, error) {
+				user, customError := source.GetUserFromToken(params.Context, dialect, db)
+				if customError != nil {
+					return nil, customError
+				}
+				if scopeErr := source.RequireScope(user, source.ScopeWrite); scopeErr != nil {
+					return nil, scopeErr
+				}
+
+				id, _ := params.Args["id"].(string)
+
+				tx, txErr := db.Begin()
+				if txErr != nil {
+					return nil, txErr
+				}
+				defer tx.Rollback()
+
+				credit, findErr := findCredit(dialect, tx, id)
+				if findErr != nil {
+					return nil, findErr
+				}
+				if credit == nil {
+					return nil, errors.New("Credit not found")
+				}
+				if _, movieErr := changeableMovie(dialect, tx, user, credit.MoviesID); movieErr != nil {
+					return nil, movieErr
+				}
+
+				updateFields := goqu.Record{}
+				if role, ok := params.Args["role"].(string); ok {
+					if roleErr := validCreditRole(role); roleErr != nil {
+						return nil, roleErr
+					}
+					updateFields["role"] = role
+				}
+				if characterName, ok := params.Args["characterName"].(string); ok {
+					if utf8.RuneCountInString(characterName) > 128 {
+						return nil, errors.New("Character name must not exceed 128 characters")
+					}
+					updateFields["character_name"] = strings.TrimSpace(characterName)
+				}
+				if position, ok := params.Args["position"].(int); ok {
+					updateFields["position"] = position
+				}
+				if len(updateFields) == 0 {
+					return credit, nil
+				}
+
+				updateDialect := dialect.Update("movies_credits").Set(updateFields).Where(goqu.Ex{
+					"id": id,
+				})
+				updateQuery, _, toSQLErr := updateDialect.ToSQL()
+				if toSQLErr != nil {
+					return nil, toSQLErr
+				}
+				if _, updateErr := tx.Exec(updateQuery); updateErr != nil {
+					return nil, updateErr
+				}
+
+				updated, updatedErr := findCredit(dialect, tx, id)
+				if updatedErr != nil {
+					return nil, updatedErr
+				}
+
+				return updated, tx.Commit()
+			},
+		},
+
+		"removeCredit": &graphql.Field{
+			Type:        CreditType,
+			Description: "Remove credit by ID, only the owner of the movie or an admin may change its credits",
+			Args: graphql.FieldConfigArgument{
+				"id": &graphql.ArgumentConfig{
+					Type: graphql.NewNonNull(scalars.UUID),
+				},
+			},
+			Resolve: func(params graphql.ResolveParams) (interface{}, error) {
+				user, customError := source.GetUserFromToken(params.Context, dialect, db)
+				if customError != nil {
+					return nil, customError
+				}
+				if scopeErr := source.RequireScope(user, source.ScopeWrite); scopeErr != nil {
+					return nil, scopeErr
+				}
+
+				id, _ := params.Args["id"].(string)
+
+				tx, txErr := db.Begin()
+				if txErr != nil {
+					return nil, txErr
+				}
+				defer tx.Rollback()
+
+				credit, findErr := findCredit(dialect, tx, id)
+				if findErr != nil {
+					return nil, findErr
+				}
+				if credit == nil {
+					return nil, errors.New("Credit not found")
+				}
+				if _, movieErr := changeableMovie(dialect, tx, user, credit.MoviesID); movieErr != nil {
+					return nil, movieErr
+				}
+
+				deleteDialect := dialect.Delete("movies_credits").Where(goqu.Ex{
+					"id": id,
+				})
+				deleteQuery, _, toSQLErr := deleteDialect.ToSQL()
+				if toSQLErr != nil {
+					return nil, toSQLErr
+				}
+				if _, deleteErr := tx.Exec(deleteQuery); deleteErr != nil {
+					return nil, deleteErr
+				}
+
+				return credit, tx.Commit()
+			},
+		},
+
+		"rate": &graphql.Field{
+			Type:        graphql.String,
+			Description: "Rate a movie by ID. Returns 'success' / 'failure'",
+			Args: graphql.FieldConfigArgument{
+				"id": &graphql.ArgumentConfig{
+					Type: graphql.NewNonNull(scalars.UUID),
+				},
+				"rating": &graphql.ArgumentConfig{
+					Type:        graphql.NewNonNull(graphql.Int),
+					Description: "0 - 10 (0 - worst; 10 - best)",
+				},
+			},
+			Resolve: func(params graphql.ResolveParams) (interface{}, error) {
+				user, customError := source.GetUserFromToken(params.Context, dialect, db)
+				if customError != nil {
+					return nil, customError
+				}
+				if scopeErr := source.RequireScope(user, source.ScopeWrite); scopeErr != nil {
+					return nil, scopeErr
+				}
+
+				id, _ := params.Args["id"].(string)
+				rating, _ := params.Args["rating"].(int)
+
+				// Limit rating 0 - 10
+				formattedRating := math.Max(float64(rating), float64(0))
+				formattedRating = math.Min(float64(formattedRating), float64(10))
+
+				// Find the current rating
+				movie, findErr := findMovie(dialect, db, goqu.Ex{
+					"id": id,
+				})
+
+				if findErr != nil {
+					return "failure", findErr
+				}
+
+				// Add the rating as a running total
+				insertDialect := goqu.Insert("movies_reviews").Rows(
+					goqu.Record{
+						"id":        uuid.NewV4(),
+						"rating":    formattedRating,
+						"movies_id": movie.ID,
+						"users_id":  user.ID,
+					},
+				)
+				insertQuery, _, insertToSQLErr := insertDialect.ToSQL()
+				if insertToSQLErr != nil {
+					return "failure", insertToSQLErr
+				}
+
+				insertRes, insertErr := db.Query(insertQuery)
+				if insertErr != nil {
+					return "failure", insertErr
+				}
+
+				defer insertRes.Close()
 
 				// Calculate review total (aggregate functions not allowed on update)
 				totalRating, ratingErr := calculateMovieRating(dialect, db, id)
@@ -369,5 +1600,302 @@ func Mutations(dialect goqu.DialectWrapper, db *sql.DB) graphql.Fields {
 				return "success", nil
 			},
 		},
+
+		"addToWatchlist":      savedListMutation(dialect, db, models.Watchlist, true, "Add a movie to the watchlist of the current user"),
+		"removeFromWatchlist": savedListMutation(dialect, db, models.Watchlist, false, "Remove a movie from the watchlist of the current user"),
+		"addToFavorites":      savedListMutation(dialect, db, models.Favorites, true, "Add a movie to the favorites of the current user"),
+		"removeFromFavorites": savedListMutation(dialect, db, models.Favorites, false, "Remove a movie from the favorites of the current user"),
+
+		"createCollection": &graphql.Field{
+			Type:        CollectionType,
+			Description: "Create a collection of movies owned by the current user, private unless public is set",
+			Args: graphql.FieldConfigArgument{
+				"name": &graphql.ArgumentConfig{
+					Type: graphql.NewNonNull(graphql.String),
+				},
+				"description": &graphql.ArgumentConfig{
+					Type: graphql.String,
+				},
+				"public": &graphql.ArgumentConfig{
+					Type:         graphql.Boolean,
+					DefaultValue: false,
+				},
+			},
+			Resolve: func(params graphql.ResolveParams) (interface{}, error) {
+				user, customError := source.GetUserFromToken(params.Context, dialect, db)
+				if customError != nil {
+					return nil, customError
+				}
+				if scopeErr := source.RequireScope(user, source.ScopeWrite); scopeErr != nil {
+					return nil, scopeErr
+				}
+
+				name, _ := params.Args["name"].(string)
+				description, _ := params.Args["description"].(string)
+				public, _ := params.Args["public"].(bool)
+				if validErr := validCollection(name, description); validErr != nil {
+					return nil, validErr
+				}
+
+				id := uuid.NewV4().String()
+				insertDialect := dialect.Insert("collections").Rows(goqu.Record{
+					"id":          id,
+					"users_id":    user.ID,
+					"name":        strings.TrimSpace(name),
+					"description": description,
+					"public":      public,
+				})
+				insertQuery, _, toSQLErr := insertDialect.ToSQL()
+				if toSQLErr != nil {
+					return nil, toSQLErr
+				}
+				if _, insertErr := db.Exec(insertQuery); insertErr != nil {
+					return nil, insertErr
+				}
+
+				return findCollection(dialect, db, goqu.Ex{
+					"id": id,
+				})
+			},
+		},
+
+		"updateCollection": &graphql.Field{
+			Type:        CollectionType,
+			Description: "Change the name, description or visibility of a collection, only the owner or an admin may change it",
+			Args: graphql.FieldConfigArgument{
+				"id": &graphql.ArgumentConfig{
+					Type: graphql.NewNonNull(scalars.UUID),
+				},
+				"name": &graphql.ArgumentConfig{
+					Type: graphql.String,
+				},
+				"description": &graphql.ArgumentConfig{
+					Type: graphql.String,
+				},
+				"public": &graphql.ArgumentConfig{
+					Type: graphql.Boolean,
+				},
+			},
+			Resolve: func(params graphql.ResolveParams) (interface{}, error) {
+				user, customError := source.GetUserFromToken(params.Context, dialect, db)
+				if customError != nil {
+					return nil, customError
+				}
+				if scopeErr := source.RequireScope(user, source.ScopeWrite); scopeErr != nil {
+					return nil, scopeErr
+				}
+
+				id, _ := params.Args["id"].(string)
+				return changeCollection(dialect, db, user, id, func(tx *sql.Tx, collection *models.Collection) (goqu.Record, error) {
+					record := goqu.Record{}
+					name, description := collection.Name, collection.Description
+					if value, ok := params.Args["name"].(string); ok {
+						name = strings.TrimSpace(value)
+						record["name"] = name
+					}
+					if value, ok := params.Args["description"].(string); ok {
+						description = value
+						record["description"] = description
+					}
+					if value, ok := params.Args["public"].(bool); ok {
+						record["public"] = value
+					}
+					return record, validCollection(name, description)
+				})
+			},
+		},
+
+		"deleteCollection": &graphql.Field{
+			Type:        CollectionType,
+			Description: "Delete a collection, the movies in it are kept. Only the owner or an admin may delete it",
+			Args: graphql.FieldConfigArgument{
+				"id": &graphql.ArgumentConfig{
+					Type: graphql.NewNonNull(scalars.UUID),
+				},
+			},
+			Resolve: func(params graphql.ResolveParams) (interface{}, error) {
+				user, customError := source.GetUserFromToken(params.Context, dialect, db)
+				if customError != nil {
+					return nil, customError
+				}
+				if scopeErr := source.RequireScope(user, source.ScopeWrite); scopeErr != nil {
+					return nil, scopeErr
+				}
+
+				id, _ := params.Args["id"].(string)
+
+				tx, txErr := db.Begin()
+				if txErr != nil {
+					return nil, txErr
+				}
+				defer tx.Rollback()
+
+				collection, collectionErr := changeableCollection(dialect, tx, user, id)
+				if collectionErr != nil {
+					return nil, collectionErr
+				}
+
+				deleteDialect := dialect.Delete("collections").Where(goqu.Ex{
+					"id": collection.ID,
+				})
+				deleteQuery, _, toSQLErr := deleteDialect.ToSQL()
+				if toSQLErr != nil {
+					return nil, toSQLErr
+				}
+				if _, deleteErr := tx.Exec(deleteQuery); deleteErr != nil {
+					return nil, deleteErr
+				}
+
+				return collection, tx.Commit()
+			},
+		},
+
+		"addToCollection": &graphql.Field{
+			Type:        CollectionType,
+			Description: "Add a movie to a collection, or move it when it is already in it. Only the owner or an admin may change it",
+			Args: graphql.FieldConfigArgument{
+				"collectionId": &graphql.ArgumentConfig{
+					Type: graphql.NewNonNull(scalars.UUID),
+				},
+				"movieId": &graphql.ArgumentConfig{
+					Type: graphql.NewNonNull(scalars.UUID),
+				},
+				"position": &graphql.ArgumentConfig{
+					Type:        graphql.Int,
+					Description: "Place of the movie starting from 0, the end of the collection by default",
+				},
+			},
+			Resolve: func(params graphql.ResolveParams) (interface{}, error) {
+				user, customError := source.GetUserFromToken(params.Context, dialect, db)
+				if customError != nil {
+					return nil, customError
+				}
+				if scopeErr := source.RequireScope(user, source.ScopeWrite); scopeErr != nil {
+					return nil, scopeErr
+				}
+
+				id, _ := params.Args["collectionId"].(string)
+				moviesID, _ := params.Args["movieId"].(string)
+				position, ok := params.Args["position"].(int)
+				if !ok {
+					position = -1
+				}
+				return changeCollection(dialect, db, user, id, func(tx *sql.Tx, collection *models.Collection) (goqu.Record, error) {
+					return goqu.Record{}, addCollectionMovie(dialect, tx, collection.ID, moviesID, position)
+				})
+			},
+		},
+
+		"removeFromCollection": &graphql.Field{
+			Type:        CollectionType,
+			Description: "Remove a movie from a collection, only the owner or an admin may change it",
+			Args: graphql.FieldConfigArgument{
+				"collectionId": &graphql.ArgumentConfig{
+					Type: graphql.NewNonNull(scalars.UUID),
+				},
+				"movieId": &graphql.ArgumentConfig{
+					Type: graphql.NewNonNull(scalars.UUID),
+				},
+			},
+			Resolve: func(params graphql.ResolveParams) (interface{}, error) {
+				user, customError := source.GetUserFromToken(params.Context, dialect, db)
+				if customError != nil {
+					return nil, customError
+				}
+				if scopeErr := source.RequireScope(user, source.ScopeWrite); scopeErr != nil {
+					return nil, scopeErr
+				}
+
+				id, _ := params.Args["collectionId"].(string)
+				moviesID, _ := params.Args["movieId"].(string)
+				return changeCollection(dialect, db, user, id, func(tx *sql.Tx, collection *models.Collection) (goqu.Record, error) {
+					return goqu.Record{}, removeCollectionMovie(dialect, tx, collection.ID, moviesID)
+				})
+			},
+		},
+
+		"reorderCollection": &graphql.Field{
+			Type:        CollectionType,
+			Description: "Place the listed movies of a collection first in the given order, the others keep their order after them. Only the owner or an admin may change it",
+			Args: graphql.FieldConfigArgument{
+				"collectionId": &graphql.ArgumentConfig{
+					Type: graphql.NewNonNull(scalars.UUID),
+				},
+				"movieIds": &graphql.ArgumentConfig{
+					Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(scalars.UUID))),
+				},
+			},
+			Resolve: func(params graphql.ResolveParams) (interface{}, error) {
+				user, customError := source.GetUserFromToken(params.Context, dialect, db)
+				if customError != nil {
+					return nil, customError
+				}
+				if scopeErr := source.RequireScope(user, source.ScopeWrite); scopeErr != nil {
+					return nil, scopeErr
+				}
+
+				id, _ := params.Args["collectionId"].(string)
+				moviesIDs := []string{}
+				if values, ok := params.Args["movieIds"].([]interface{}); ok {
+					for _, value := range values {
+						if moviesID, ok := value.(string); ok {
+							moviesIDs = append(moviesIDs, moviesID)
+						}
+					}
+				}
+				return changeCollection(dialect, db, user, id, func(tx *sql.Tx, collection *models.Collection) (goqu.Record, error) {
+					return goqu.Record{}, reorderCollectionMovies(dialect, tx, collection.ID, moviesIDs)
+				})
+			},
+		},
+
+		"shareCollection": &graphql.Field{
+			Type:        CollectionType,
+			Description: "Create a share link revealing the collection to anyone holding it, replacing the previous link. Only the owner or an admin may share it",
+			Args: graphql.FieldConfigArgument{
+				"id": &graphql.ArgumentConfig{
+					Type: graphql.NewNonNull(scalars.UUID),
+				},
+			},
+			Resolve: func(params graphql.ResolveParams) (interface{}, error) {
+				user, customError := source.GetUserFromToken(params.Context, dialect, db)
+				if customError != nil {
+					return nil, customError
+				}
+				if scopeErr := source.RequireScope(user, source.ScopeWrite); scopeErr != nil {
+					return nil, scopeErr
+				}
+
+				id, _ := params.Args["id"].(string)
+				return changeCollection(dialect, db, user, id, func(tx *sql.Tx, collection *models.Collection) (goqu.Record, error) {
+					shareToken, tokenErr := source.RandomToken()
+					return goqu.Record{"share_token": shareToken}, tokenErr
+				})
+			},
+		},
+
+		"unshareCollection": &graphql.Field{
+			Type:        CollectionType,
+			Description: "Revoke the share link of a collection, only the owner or an admin may change it",
+			Args: graphql.FieldConfigArgument{
+				"id": &graphql.ArgumentConfig{
+					Type: graphql.NewNonNull(scalars.UUID),
+				},
+			},
+			Resolve: func(params graphql.ResolveParams) (interface{}, error) {
+				user, customError := source.GetUserFromToken(params.Context, dialect, db)
+				if customError != nil {
+					return nil, customError
+				}
+				if scopeErr := source.RequireScope(user, source.ScopeWrite); scopeErr != nil {
+					return nil, scopeErr
+				}
+
+				id, _ := params.Args["id"].(string)
+				return changeCollection(dialect, db, user, id, func(tx *sql.Tx, collection *models.Collection) (goqu.Record, error) {
+					return goqu.Record{"share_token": ""}, nil
+				})
+			},
+		},
 	}
 }
@@ -0,0 +1,60 @@
+package movies
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/doug-martin/goqu/v8"
+
+	"github.com/HencoSmith/graphql-example-go/graphql/node"
+	source "github.com/HencoSmith/graphql-example-go/source"
+)
+
+// Nodes - lookup functions for the movie related types addressable by global ID
+func Nodes(dialect goqu.DialectWrapper, db *sql.DB) map[string]node.Fetcher {
+	return map[string]node.Fetcher{
+		"Collection": func(ctx context.Context, id string) (interface{}, error) {
+			user, userErr := source.Viewer(ctx, dialect, db)
+			if userErr != nil {
+				return nil, userErr
+			}
+			collection, err := findCollection(dialect, db, goqu.Ex{
+				"id": id,
+			})
+			if err != nil || collection == nil || !visibleCollection(user, *collection) {
+				return nil, err
+			}
+			return collection, nil
+		},
+		"Movie": func(ctx context.Context, id string) (interface{}, error) {
+			movie, err := findMovie(dialect, db, goqu.Ex{
+				"id":         id,
+				"deleted_at": nil,
+			})
+			if err != nil || movie == nil {
+				return nil, err
+			}
+			return movie, nil
+		},
+		"Person": func(ctx context.Context, id string) (interface{}, error) {
+			person, err := findPerson(dialect, db, goqu.Ex{
+				"id":         id,
+				"deleted_at": nil,
+			})
+			if err != nil || person == nil {
+				return nil, err
+			}
+			return person, nil
+		},
+		"Review": func(ctx context.Context, id string) (interface{}, error) {
+			reviews, err := findReviews(dialect, db, goqu.Ex{
+				"id":         id,
+				"deleted_at": nil,
+			})
+			if err != nil || len(reviews) < 1 {
+				return nil, err
+			}
+			return &reviews[0], nil
+		},
+	}
+}
@@ -0,0 +1,195 @@
+package movies
+
+import (
+	"errors"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/doug-martin/goqu/v8"
+	"github.com/doug-martin/goqu/v8/exp"
+
+	"github.com/HencoSmith/graphql-example-go/models"
+)
+
+// roleActor - credit role of the cast, every other role belongs to the crew
+const roleActor = "actor"
+
+// creditRoles - roles a person may be credited with
+var creditRoles = []string{
+	roleActor,
+	"director",
+	"writer",
+	"producer",
+	"composer",
+	"cinematographer",
+	"editor",
+}
+
+// validCreditRole - Check the role is one of creditRoles
+func validCreditRole(role string) error {
+	for _, allowed := range creditRoles {
+		if role == allowed {
+			return nil
+		}
+	}
+	return errors.New("Invalid credit role, expected one of: " + strings.Join(creditRoles, ", "))
+}
+
+// validPerson - Check the fields of a person
+func validPerson(name string, biography string) error {
+	if len(strings.TrimSpace(name)) == 0 || utf8.RuneCountInString(name) > 128 {
+		return errors.New("Person name must be between 1 and 128 characters")
+	}
+	if utf8.RuneCountInString(biography) > 10000 {
+		return errors.New("Biography must not exceed 10000 characters")
+	}
+	return nil
+}
+
+// findPeople - lookup the people matching the specified expression
+// dialect - Query builder dialect object used
+// db - SQL DB connection to use
+// expression - Expression people looking up should adhere to
+func findPeople(dialect goqu.DialectWrapper, db queryer, expression goqu.Ex) ([]models.Person, error) {
+	dialectString := dialect.From("people").Select(
+		"id",
+		"created_at",
+		"updated_at",
+		"deleted_at",
+		"users_id",
+		"name",
+		"biography",
+	).Where(expression).Order(goqu.C("name").Asc())
+	query, _, dialectErr := dialectString.ToSQL()
+	if dialectErr != nil {
+		return nil, dialectErr
+	}
+
+	rows, queryErr := db.Query(query)
+	if queryErr != nil {
+		return nil, queryErr
+	}
+	defer rows.Close()
+
+	peopleArr := []models.Person{}
+	for rows.Next() {
+		var personRow = models.Person{}
+		scanErr := rows.Scan(
+			&personRow.ID,
+			&personRow.CreatedAt,
+			&personRow.UpdatedAt,
+			&personRow.DeletedAt,
+			&personRow.UsersID,
+			&personRow.Name,
+			&personRow.Biography,
+		)
+		if scanErr != nil {
+			return nil, scanErr
+		}
+		peopleArr = append(peopleArr, personRow)
+	}
+	if errRows := rows.Err(); errRows != nil {
+		return nil, errRows
+	}
+
+	return peopleArr, nil
+}
+
+// findPerson - lookup a person matching the specified expression
+// dialect - Query builder dialect object used
+// db - SQL DB connection to use
+// expression - Expression person looking up should adhere to
+func findPerson(dialect goqu.DialectWrapper, db queryer, expression goqu.Ex) (*models.Person, error) {
+	peopleArr, findErr := findPeople(dialect, db, expression)
+	if findErr != nil {
+		return nil, findErr
+	}
+
+	if len(peopleArr) < 1 {
+		return nil, nil
+	}
+
+	return &peopleArr[0], nil
+}
+
+// findCredits - lookup the credits matching the specified expression, credits of deleted movies
+// and people are left out
+// dialect - Query builder dialect object used
+// db - SQL DB connection to use
+// expression - Expression credits looking up should adhere to
+// order - Order of the credits
+func findCredits(dialect goqu.DialectWrapper, db queryer, expression exp.Expression, order ...exp.OrderedExpression) ([]models.Credit, error) {
+	dialectString := dialect.From("movies_credits").Select(
+		"movies_credits.id",
+		"movies_credits.created_at",
+		"movies_credits.movies_id",
+		"movies_credits.people_id",
+		"movies_credits.role",
+		"movies_credits.character_name",
+		"movies_credits.position",
+	).Join(
+		goqu.T("movies"),
+		goqu.On(goqu.Ex{
+			"movies.id":         goqu.I("movies_credits.movies_id"),
+			"movies.deleted_at": nil,
+		}),
+	).Join(
+		goqu.T("people"),
+		goqu.On(goqu.Ex{
+			"people.id":         goqu.I("movies_credits.people_id"),
+			"people.deleted_at": nil,
+		}),
+	).Where(expression).Order(order...)
+	query, _, dialectErr := dialectString.ToSQL()
+	if dialectErr != nil {
+		return nil, dialectErr
+	}
+
+	rows, queryErr := db.Query(query)
+	if queryErr != nil {
+		return nil, queryErr
+	}
+	defer rows.Close()
+
+	creditsArr := []models.Credit{}
+	for rows.Next() {
+		var creditRow = models.Credit{}
+		scanErr := rows.Scan(
+			&creditRow.ID,
+			&creditRow.CreatedAt,
+			&creditRow.MoviesID,
+			&creditRow.PeopleID,
+			&creditRow.Role,
+			&creditRow.CharacterName,
+			&creditRow.Position,
+		)
+		if scanErr != nil {
+			return nil, scanErr
+		}
+		creditsArr = append(creditsArr, creditRow)
+	}
+	if errRows := rows.Err(); errRows != nil {
+		return nil, errRows
+	}
+
+	return creditsArr, nil
+}
+
+// findCredit - lookup a credit by ID
+// dialect - Query builder dialect object used
+// db - SQL DB connection to use
+// id - UUID of the credit
+func findCredit(dialect goqu.DialectWrapper, db queryer, id string) (*models.Credit, error) {
+	creditsArr, findErr := findCredits(dialect, db, goqu.Ex{
+		"movies_credits.id": id,
+	})
+	if findErr != nil {
+		return nil, findErr
+	}
+
+	if len(creditsArr) < 1 {
+		return nil, nil
+	}
+
+	return &creditsArr[0], nil
+}
@@ -4,12 +4,49 @@ import (
 	"database/sql"
 
 	"github.com/doug-martin/goqu/v8"
+	"github.com/doug-martin/goqu/v8/exp"
 	"github.com/graphql-go/graphql"
 
-	"github.com/HencoSmith/graphql-example-go/models"
+	"github.com/HencoSmith/graphql-example-go/graphql/scalars"
 	source "github.com/HencoSmith/graphql-example-go/source"
 )
 
+// listFilter - Expression selecting the movies listed according to the filter arguments
+// dialect - Query builder dialect object used
+// args - Arguments of the list query
+func listFilter(dialect goqu.DialectWrapper, args map[string]interface{}) exp.Expression {
+	genre, _ := args["genre"].(string)
+	tag, _ := args["tag"].(string)
+	return source.MovieListFilter(dialect, genre, tag)
+}
+
+// countRows - Count the rows of the table matching the specified expression
+// dialect - Query builder dialect object used
+// db - SQL DB connection to use
+// table - Table counted
+// expression - Expression rows counted should adhere to
+func countRows(dialect goqu.DialectWrapper, db queryer, table string, expression exp.Expression) (int, error) {
+	countDialect := dialect.From(table).Select(goqu.COUNT("*")).Where(expression)
+	countQuery, _, toSQLErr := countDialect.ToSQL()
+	if toSQLErr != nil {
+		return 0, toSQLErr
+	}
+
+	rows, queryErr := db.Query(countQuery)
+	if queryErr != nil {
+		return 0, queryErr
+	}
+	defer rows.Close()
+
+	count := 0
+	if rows.Next() {
+		if scanErr := rows.Scan(&count); scanErr != nil {
+			return 0, scanErr
+		}
+	}
+	return count, rows.Err()
+}
+
 // Queries - all GraphQL queries related to movies
 func Queries(dialect goqu.DialectWrapper, db *sql.DB) graphql.Fields {
 	return graphql.Fields{
@@ -18,62 +55,24 @@ func Queries(dialect goqu.DialectWrapper, db *sql.DB) graphql.Fields {
 			Description: "Get movie by id",
 			Args: graphql.FieldConfigArgument{
 				"id": &graphql.ArgumentConfig{
-					Type: graphql.String,
+					Type: scalars.UUID,
 				},
 			},
 			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
-				_, customError := source.GetUserFromToken(p.Context, dialect, db)
+				user, customError := source.GetUserFromToken(p.Context, dialect, db)
 				if customError != nil {
 					return nil, customError
 				}
+				if scopeErr := source.RequireScope(user, source.ScopeRead); scopeErr != nil {
+					return nil, scopeErr
+				}
 
 				id, ok := p.Args["id"].(string)
 				if ok {
-					// Find movie
-					dialectString := dialect.From("movies").Where(goqu.Ex{
+					return findMovie(dialect, db, goqu.Ex{
 						"id":         id,
 						"deleted_at": nil,
 					})
-					query, _, dialectErr := dialectString.ToSQL()
-					if dialectErr != nil {
-						return nil, dialectErr
-					}
-
-					rows, queryErr := db.Query(query)
-					if queryErr != nil {
-						return nil, queryErr
-					}
-					defer rows.Close()
-
-					var moviesArr []models.Movie
-					for rows.Next() {
-						var movieRow = models.Movie{}
-						scanErr := rows.Scan(
-							&movieRow.ID,
-							&movieRow.CreatedAt,
-							&movieRow.UpdatedAt,
-							&movieRow.DeletedAt,
-							&movieRow.UsersID,
-							&movieRow.Name,
-							&movieRow.ReleaseYear,
-							&movieRow.Description,
-							&movieRow.Rating,
-							&movieRow.ReviewCount,
-						)
-						if scanErr != nil {
-							return nil, scanErr
-						}
-						moviesArr = append(moviesArr, movieRow)
-					}
-					if errRows := rows.Err(); errRows != nil {
-						return nil, errRows
-					}
-
-					if len(moviesArr) < 1 {
-						return nil, nil
-					}
-
-					return &moviesArr[0], nil
 				}
 				return nil, nil
 			},
@@ -82,51 +81,162 @@ func Queries(dialect goqu.DialectWrapper, db *sql.DB) graphql.Fields {
 		"list": &graphql.Field{
 			Type:        graphql.NewList(MovieType),
 			Description: "Get movie list",
+			Args: graphql.FieldConfigArgument{
+				"genre": &graphql.ArgumentConfig{
+					Type:        graphql.String,
+					Description: "Only list movies of the genre",
+				},
+				"tag": &graphql.ArgumentConfig{
+					Type:        graphql.String,
+					Description: "Only list movies labelled with the tag",
+				},
+			},
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				user, customError := source.GetUserFromToken(p.Context, dialect, db)
+				if customError != nil {
+					return nil, customError
+				}
+				if scopeErr := source.RequireScope(user, source.ScopeRead); scopeErr != nil {
+					return nil, scopeErr
+				}
+
+				return findMovies(dialect, db, listFilter(dialect, p.Args), goqu.C("id").Asc())
+			},
+		},
+
+		"person": &graphql.Field{
+			Type:        PersonType,
+			Description: "Get person by id",
+			Args: graphql.FieldConfigArgument{
+				"id": &graphql.ArgumentConfig{
+					Type: graphql.NewNonNull(scalars.UUID),
+				},
+			},
 			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
-				_, customError := source.GetUserFromToken(p.Context, dialect, db)
+				user, customError := source.GetUserFromToken(p.Context, dialect, db)
 				if customError != nil {
 					return nil, customError
 				}
+				if scopeErr := source.RequireScope(user, source.ScopeRead); scopeErr != nil {
+					return nil, scopeErr
+				}
 
-				dialectString := dialect.From("movies").Where(goqu.Ex{
+				id, _ := p.Args["id"].(string)
+				return findPerson(dialect, db, goqu.Ex{
+					"id":         id,
 					"deleted_at": nil,
-				}).Order(goqu.C("id").Asc())
-				query, _, dialectErr := dialectString.ToSQL()
-				if dialectErr != nil {
-					return nil, dialectErr
-				}
-
-				rows, queryErr := db.Query(query)
-				if queryErr != nil {
-					return nil, queryErr
-				}
-				defer rows.Close()
-
-				var moviesArr []models.Movie
-				for rows.Next() {
-					var movieRow = models.Movie{}
-					scanErr := rows.Scan(
-						&movieRow.ID,
-						&movieRow.CreatedAt,
-						&movieRow.UpdatedAt,
-						&movieRow.DeletedAt,
-						&movieRow.UsersID,
-						&movieRow.Name,
-						&movieRow.ReleaseYear,
-						&movieRow.Description,
-						&movieRow.Rating,
-						&movieRow.ReviewCount,
-					)
-					if scanErr != nil {
-						return nil, scanErr
-					}
-					moviesArr = append(moviesArr, movieRow)
-				}
-				if errRows := rows.Err(); errRows != nil {
-					return nil, errRows
-				}
-
-				return &moviesArr, nil
+				})
+			},
+		},
+
+		"genres": &graphql.Field{
+			Type:        graphql.NewList(GenreType),
+			Description: "Get every genre along with the amount of movies in it",
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				user, customError := source.GetUserFromToken(p.Context, dialect, db)
+				if customError != nil {
+					return nil, customError
+				}
+				if scopeErr := source.RequireScope(user, source.ScopeRead); scopeErr != nil {
+					return nil, scopeErr
+				}
+
+				return findGenres(dialect, db, goqu.Ex{})
+			},
+		},
+
+		"deletedMovies": &graphql.Field{
+			Type:        graphql.NewList(MovieType),
+			Description: "Get deleted movies which can still be restored, admins see the movies of every user",
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				user, customError := source.GetUserFromToken(p.Context, dialect, db)
+				if customError != nil {
+					return nil, customError
+				}
+				if scopeErr := source.RequireScope(user, source.ScopeRead); scopeErr != nil {
+					return nil, scopeErr
+				}
+
+				expression := goqu.Ex{
+					"deleted_at": goqu.Op{"neq": nil},
+				}
+				if !source.IsAdmin(user) {
+					expression["users_id"] = user.ID
+				}
+
+				return findMovies(dialect, db, expression, goqu.C("deleted_at").Desc())
+			},
+		},
+
+		"collection": &graphql.Field{
+			Type:        CollectionType,
+			Description: "Get collection by id, or by the share token of its share link which also reveals private collections",
+			Args: graphql.FieldConfigArgument{
+				"id": &graphql.ArgumentConfig{
+					Type: scalars.UUID,
+				},
+				"shareToken": &graphql.ArgumentConfig{
+					Type: graphql.String,
+				},
+			},
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				user, customError := source.GetUserFromToken(p.Context, dialect, db)
+				if customError != nil {
+					return nil, customError
+				}
+				if scopeErr := source.RequireScope(user, source.ScopeRead); scopeErr != nil {
+					return nil, scopeErr
+				}
+
+				if shareToken, ok := p.Args["shareToken"].(string); ok && len(shareToken) > 0 {
+					return findCollection(dialect, db, goqu.Ex{
+						"share_token": shareToken,
+					})
+				}
+
+				id, ok := p.Args["id"].(string)
+				if !ok {
+					return nil, nil
+				}
+				collection, findErr := findCollection(dialect, db, goqu.Ex{
+					"id": id,
+				})
+				if findErr != nil || collection == nil || !visibleCollection(user, *collection) {
+					return nil, findErr
+				}
+				return collection, nil
+			},
+		},
+
+		"collections": &graphql.Field{
+			Type:        graphql.NewList(CollectionType),
+			Description: "Get the collections of a user by name, those of the current user by default. Only public collections of other users are listed",
+			Args: graphql.FieldConfigArgument{
+				"ownerId": &graphql.ArgumentConfig{
+					Type: scalars.UUID,
+				},
+			},
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				user, customError := source.GetUserFromToken(p.Context, dialect, db)
+				if customError != nil {
+					return nil, customError
+				}
+				if scopeErr := source.RequireScope(user, source.ScopeRead); scopeErr != nil {
+					return nil, scopeErr
+				}
+
+				ownerID, ok := p.Args["ownerId"].(string)
+				if !ok {
+					ownerID = user.ID
+				}
+				expression := goqu.Ex{
+					"users_id": ownerID,
+				}
+				if source.RequireOwnerOrAdmin(user, ownerID) != nil {
+					expression["public"] = true
+				}
+
+				return findCollections(dialect, db, expression, goqu.C("name").Asc(), goqu.C("id").Asc())
 			},
 		},
 	}
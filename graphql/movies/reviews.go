@@ -0,0 +1,58 @@
+package movies
+
+import (
+	"database/sql"
+
+	"github.com/doug-martin/goqu/v8"
+
+	"github.com/HencoSmith/graphql-example-go/models"
+)
+
+// findReviews - lookup all reviews matching the specified expression
+// dialect - Query builder dialect object used
+// db - SQL DB connection to use
+// expression - Expression reviews looking up should adhere to
+func findReviews(dialect goqu.DialectWrapper, db *sql.DB, expression goqu.Ex) ([]models.Review, error) {
+	dialectString := dialect.From("movies_reviews").Select(
+		"id",
+		"created_at",
+		"updated_at",
+		"deleted_at",
+		"movies_id",
+		"users_id",
+		"rating",
+	).Where(expression).Order(goqu.C("created_at").Asc())
+	query, _, dialectErr := dialectString.ToSQL()
+	if dialectErr != nil {
+		return nil, dialectErr
+	}
+
+	rows, queryErr := db.Query(query)
+	if queryErr != nil {
+		return nil, queryErr
+	}
+	defer rows.Close()
+
+	reviewsArr := []models.Review{}
+	for rows.Next() {
+		var reviewRow = models.Review{}
+		scanErr := rows.Scan(
+			&reviewRow.ID,
+			&reviewRow.CreatedAt,
+			&reviewRow.UpdatedAt,
+			&reviewRow.DeletedAt,
+			&reviewRow.MoviesID,
+			&reviewRow.UsersID,
+			&reviewRow.Rating,
+		)
+		if scanErr != nil {
+			return nil, scanErr
+		}
+		reviewsArr = append(reviewsArr, reviewRow)
+	}
+	if errRows := rows.Err(); errRows != nil {
+		return nil, errRows
+	}
+
+	return reviewsArr, nil
+}
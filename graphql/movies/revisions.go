@@ -0,0 +1,86 @@
+package movies
+
+import (
+	"database/sql"
+	"encoding/json"
+
+	"github.com/doug-martin/goqu/v8"
+
+	"github.com/HencoSmith/graphql-example-go/models"
+)
+
+// queryer - queries shared by *sql.DB and *sql.Tx
+type queryer interface {
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+}
+
+// decodeSnapshot - Decode the movie stored in a revision, nil when the column is NULL
+func decodeSnapshot(snapshot []byte) (*models.Movie, error) {
+	if snapshot == nil {
+		return nil, nil
+	}
+
+	movie := models.Movie{}
+	if unmarshalErr := json.Unmarshal(snapshot, &movie); unmarshalErr != nil {
+		return nil, unmarshalErr
+	}
+	return &movie, nil
+}
+
+// findRevisions - lookup the movie revisions matching the specified expression, newest first
+// dialect - Query builder dialect object used
+// db - SQL DB connection to use
+// expression - Expression revisions looking up should adhere to
+func findRevisions(dialect goqu.DialectWrapper, db queryer, expression goqu.Ex) ([]models.MovieRevision, error) {
+	dialectString := dialect.From("movie_revisions").Select(
+		"id",
+		"created_at",
+		"movies_id",
+		"users_id",
+		"action",
+		"before",
+		"after",
+	).Where(expression).Order(goqu.C("created_at").Desc(), goqu.C("id").Asc())
+	query, _, dialectErr := dialectString.ToSQL()
+	if dialectErr != nil {
+		return nil, dialectErr
+	}
+
+	rows, queryErr := db.Query(query)
+	if queryErr != nil {
+		return nil, queryErr
+	}
+	defer rows.Close()
+
+	revisionsArr := []models.MovieRevision{}
+	for rows.Next() {
+		var revisionRow = models.MovieRevision{}
+		var before, after []byte
+		scanErr := rows.Scan(
+			&revisionRow.ID,
+			&revisionRow.CreatedAt,
+			&revisionRow.MoviesID,
+			&revisionRow.UsersID,
+			&revisionRow.Action,
+			&before,
+			&after,
+		)
+		if scanErr != nil {
+			return nil, scanErr
+		}
+
+		var decodeErr error
+		if revisionRow.Before, decodeErr = decodeSnapshot(before); decodeErr != nil {
+			return nil, decodeErr
+		}
+		if revisionRow.After, decodeErr = decodeSnapshot(after); decodeErr != nil {
+			return nil, decodeErr
+		}
+		revisionsArr = append(revisionsArr, revisionRow)
+	}
+	if errRows := rows.Err(); errRows != nil {
+		return nil, errRows
+	}
+
+	return revisionsArr, nil
+}
@@ -0,0 +1,200 @@
+package movies
+
+import (
+	"database/sql"
+	"strings"
+	"time"
+
+	"github.com/doug-martin/goqu/v8"
+	uuid "github.com/satori/go.uuid"
+
+	"github.com/HencoSmith/graphql-example-go/graphql/node"
+	"github.com/HencoSmith/graphql-example-go/models"
+)
+
+// saveMovie - Add the movie to the list of the user, saving it again keeps the original time
+// dialect - Query builder dialect object used
+// db - SQL DB connection to use
+// userID - UUID of the user saving the movie
+// list - models.Watchlist or models.Favorites
+// moviesID - UUID of the movie
+func saveMovie(dialect goqu.DialectWrapper, db *sql.DB, userID string, list string, moviesID string) (*models.Movie, error) {
+	movie, findErr := findMovie(dialect, db, goqu.Ex{
+		"id":         moviesID,
+		"deleted_at": nil,
+	})
+	if findErr != nil {
+		return nil, findErr
+	}
+	if movie == nil {
+		return nil, errMovieNotFound
+	}
+
+	insertDialect := dialect.Insert("movies_saves").Rows(goqu.Record{
+		"users_id":  userID,
+		"movies_id": movie.ID,
+		"list":      list,
+	}).OnConflict(goqu.DoNothing())
+	insertQuery, _, toSQLErr := insertDialect.ToSQL()
+	if toSQLErr != nil {
+		return nil, toSQLErr
+	}
+	if _, insertErr := db.Exec(insertQuery); insertErr != nil {
+		return nil, insertErr
+	}
+
+	return movie, nil
+}
+
+// unsaveMovie - Remove the movie from the list of the user, nothing happens if it is not on it
+// dialect - Query builder dialect object used
+// db - SQL DB connection to use
+// userID - UUID of the user
+// list - models.Watchlist or models.Favorites
+// moviesID - UUID of the movie
+func unsaveMovie(dialect goqu.DialectWrapper, db *sql.DB, userID string, list string, moviesID string) (*models.Movie, error) {
+	movie, findErr := findMovie(dialect, db, goqu.Ex{
+		"id":         moviesID,
+		"deleted_at": nil,
+	})
+	if findErr != nil {
+		return nil, findErr
+	}
+	if movie == nil {
+		return nil, errMovieNotFound
+	}
+
+	deleteDialect := dialect.Delete("movies_saves").Where(goqu.Ex{
+		"users_id":  userID,
+		"movies_id": movie.ID,
+		"list":      list,
+	})
+	deleteQuery, _, toSQLErr := deleteDialect.ToSQL()
+	if toSQLErr != nil {
+		return nil, toSQLErr
+	}
+	if _, deleteErr := db.Exec(deleteQuery); deleteErr != nil {
+		return nil, deleteErr
+	}
+
+	return movie, nil
+}
+
+// saveCursor - Position of a save within a list, ordered by the time it was saved
+func saveCursor(savedAt time.Time, moviesID string) string {
+	return node.ToCursor(savedAt.Format(time.RFC3339Nano) + "/" + moviesID)
+}
+
+// fromSaveCursor - Time and movie UUID of the save the cursor refers to
+func fromSaveCursor(position string) (time.Time, string, error) {
+	parts := strings.SplitN(position, "/", 2)
+	if len(parts) != 2 {
+		return time.Time{}, "", node.ErrInvalidCursor
+	}
+	savedAt, timeErr := time.Parse(time.RFC3339Nano, parts[0])
+	if timeErr != nil {
+		return time.Time{}, "", node.ErrInvalidCursor
+	}
+	if _, idErr := uuid.FromString(parts[1]); idErr != nil {
+		return time.Time{}, "", node.ErrInvalidCursor
+	}
+	return savedAt, parts[1], nil
+}
+
+// savedMovies - A page of the movies on the list of the user which have not been deleted, most
+// recently saved first
+// dialect - Query builder dialect object used
+// db - SQL DB connection to use
+// userID - UUID of the user
+// list - models.Watchlist or models.Favorites
+// first - Number of movies to return
+// after - Position of the save to continue after, empty for the first page
+func savedMovies(dialect goqu.DialectWrapper, db *sql.DB, userID string, list string, first int, after string) (*models.SavedMovieConnection, error) {
+	listed := goqu.Ex{
+		"movies_saves.users_id": userID,
+		"movies_saves.list":     list,
+		"movies_saves.movies_id": goqu.Op{"in": dialect.From("movies").Select("id").Where(goqu.Ex{
+			"deleted_at": nil,
+		})},
+	}
+
+	totalCount, countErr := countRows(dialect, db, "movies_saves", listed)
+	if countErr != nil {
+		return nil, countErr
+	}
+
+	expression := goqu.And(listed)
+	if len(after) > 0 {
+		savedAt, moviesID, cursorErr := fromSaveCursor(after)
+		if cursorErr != nil {
+			return nil, cursorErr
+		}
+		expression = goqu.And(listed, goqu.Or(
+			goqu.I("movies_saves.created_at").Lt(savedAt),
+			goqu.And(
+				goqu.I("movies_saves.created_at").Eq(savedAt),
+				goqu.I("movies_saves.movies_id").Lt(moviesID),
+			),
+		))
+	}
+
+	// One more than requested tells whether there is a next page
+	selectDialect := dialect.From("movies_saves").Select(
+		"movies_saves.movies_id",
+		"movies_saves.created_at",
+	).Where(expression).Order(
+		goqu.I("movies_saves.created_at").Desc(),
+		goqu.I("movies_saves.movies_id").Desc(),
+	).Limit(uint(first + 1))
+	selectQuery, _, toSQLErr := selectDialect.ToSQL()
+	if toSQLErr != nil {
+		return nil, toSQLErr
+	}
+
+	rows, queryErr := db.Query(selectQuery)
+	if queryErr != nil {
+		return nil, queryErr
+	}
+	defer rows.Close()
+
+	edges := []models.SavedMovieEdge{}
+	for rows.Next() {
+		var moviesID string
+		var savedAt time.Time
+		if scanErr := rows.Scan(&moviesID, &savedAt); scanErr != nil {
+			return nil, scanErr
+		}
+		edges = append(edges, models.SavedMovieEdge{
+			Cursor:  saveCursor(savedAt, moviesID),
+			SavedAt: &savedAt,
+			Node:    models.Movie{ID: moviesID},
+		})
+	}
+	if errRows := rows.Err(); errRows != nil {
+		return nil, errRows
+	}
+
+	connection := &models.SavedMovieConnection{
+		Edges:      edges,
+		TotalCount: totalCount,
+	}
+	if len(edges) > first {
+		connection.Edges = edges[:first]
+		connection.PageInfo.HasNextPage = true
+	}
+	if len(connection.Edges) == 0 {
+		return connection, nil
+	}
+
+	nodes := []*models.Movie{}
+	for i := range connection.Edges {
+		nodes = append(nodes, &connection.Edges[i].Node)
+	}
+	if hydrateErr := hydrateMovies(dialect, db, nodes); hydrateErr != nil {
+		return nil, hydrateErr
+	}
+
+	connection.PageInfo.StartCursor = &connection.Edges[0].Cursor
+	connection.PageInfo.EndCursor = &connection.Edges[len(connection.Edges)-1].Cursor
+	return connection, nil
+}
@@ -1,28 +1,47 @@
 package movies
 
 import (
+	"database/sql"
+
+	"github.com/doug-martin/goqu/v8"
 	"github.com/graphql-go/graphql"
+
+	"github.com/HencoSmith/graphql-example-go/graphql/node"
+	"github.com/HencoSmith/graphql-example-go/graphql/scalars"
+	"github.com/HencoSmith/graphql-example-go/graphql/users"
+	"github.com/HencoSmith/graphql-example-go/models"
+	source "github.com/HencoSmith/graphql-example-go/source"
 )
 
 // MovieType - Entries found in the movies table
 var MovieType = graphql.NewObject(
 	graphql.ObjectConfig{
-		Name: "Movie",
+		Name:       "Movie",
+		Interfaces: []*graphql.Interface{node.Interface},
+		IsTypeOf: func(p graphql.IsTypeOfParams) bool {
+			switch p.Value.(type) {
+			case models.Movie, *models.Movie:
+				return true
+			}
+			return false
+		},
 		Fields: graphql.Fields{
-			"id": &graphql.Field{
-				Type: graphql.String,
+			"id": node.GlobalIDField("Movie"),
+			"uuid": &graphql.Field{
+				Type:    scalars.UUID,
+				Resolve: node.LocalID,
 			},
 			"created_at": &graphql.Field{
-				Type: graphql.String,
+				Type: scalars.DateTime,
 			},
 			"updated_at": &graphql.Field{
-				Type: graphql.String,
+				Type: scalars.DateTime,
 			},
 			"deleted_at": &graphql.Field{
-				Type: graphql.String,
+				Type: scalars.DateTime,
 			},
 			"users_id": &graphql.Field{
-				Type: graphql.String,
+				Type: scalars.UUID,
 			},
 			"name": &graphql.Field{
 				Type: graphql.String,
@@ -39,6 +58,704 @@ var MovieType = graphql.NewObject(
 			"review_count": &graphql.Field{
 				Type: graphql.Int,
 			},
+			"version": &graphql.Field{
+				Type:        graphql.Int,
+				Description: "Incremented by every change, pass it as expectedVersion to detect concurrent changes",
+			},
+			"poster_url":    blobURLField("poster_key", "Link of the poster uploaded with uploadPoster"),
+			"thumbnail_url": blobURLField("thumbnail_key", "Link of the JPEG thumbnail generated from the poster"),
+		},
+	},
+)
+
+// blobURLField - Field resolving the link of the stored file the key property of the source
+// object refers to, null when no file was uploaded
+func blobURLField(keyField string, description string) *graphql.Field {
+	return &graphql.Field{
+		Type:        graphql.String,
+		Description: description,
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			p.Info.FieldName = keyField
+			key, err := graphql.DefaultResolveFn(p)
+			if err != nil {
+				return nil, err
+			}
+
+			blobKey, _ := key.(string)
+			return source.BlobURL(blobKey), nil
+		},
+	}
+}
+
+// ReviewType - Entries found in the movies_reviews table
+var ReviewType = graphql.NewObject(
+	graphql.ObjectConfig{
+		Name:       "Review",
+		Interfaces: []*graphql.Interface{node.Interface},
+		IsTypeOf: func(p graphql.IsTypeOfParams) bool {
+			switch p.Value.(type) {
+			case models.Review, *models.Review:
+				return true
+			}
+			return false
+		},
+		Fields: graphql.Fields{
+			"id": node.GlobalIDField("Review"),
+			"uuid": &graphql.Field{
+				Type:    scalars.UUID,
+				Resolve: node.LocalID,
+			},
+			"created_at": &graphql.Field{
+				Type: scalars.DateTime,
+			},
+			"updated_at": &graphql.Field{
+				Type: scalars.DateTime,
+			},
+			"movies_id": &graphql.Field{
+				Type: scalars.UUID,
+			},
+			"users_id": &graphql.Field{
+				Type: scalars.UUID,
+			},
+			"rating": &graphql.Field{
+				Type: graphql.Float,
+			},
+		},
+	},
+)
+
+// PersonType - Entries found in the people table
+var PersonType = graphql.NewObject(
+	graphql.ObjectConfig{
+		Name:       "Person",
+		Interfaces: []*graphql.Interface{node.Interface},
+		IsTypeOf: func(p graphql.IsTypeOfParams) bool {
+			switch p.Value.(type) {
+			case models.Person, *models.Person:
+				return true
+			}
+			return false
+		},
+		Fields: graphql.Fields{
+			"id": node.GlobalIDField("Person"),
+			"uuid": &graphql.Field{
+				Type:    scalars.UUID,
+				Resolve: node.LocalID,
+			},
+			"created_at": &graphql.Field{
+				Type: scalars.DateTime,
+			},
+			"updated_at": &graphql.Field{
+				Type: scalars.DateTime,
+			},
+			"users_id": &graphql.Field{
+				Type:        scalars.UUID,
+				Description: "User who added the person",
+			},
+			"name": &graphql.Field{
+				Type: graphql.String,
+			},
+			"biography": &graphql.Field{
+				Type: graphql.String,
+			},
+		},
+	},
+)
+
+// CreditType - Entries found in the movies_credits table
+var CreditType = graphql.NewObject(
+	graphql.ObjectConfig{
+		Name: "Credit",
+		Fields: graphql.Fields{
+			"id": &graphql.Field{
+				Type: scalars.UUID,
+			},
+			"created_at": &graphql.Field{
+				Type: scalars.DateTime,
+			},
+			"movies_id": &graphql.Field{
+				Type: scalars.UUID,
+			},
+			"people_id": &graphql.Field{
+				Type: scalars.UUID,
+			},
+			"role": &graphql.Field{
+				Type:        graphql.String,
+				Description: "'actor', 'director', 'writer', 'producer', 'composer', 'cinematographer' or 'editor'",
+			},
+			"character_name": &graphql.Field{
+				Type:        graphql.String,
+				Description: "Character played by an actor",
+			},
+			"position": &graphql.Field{
+				Type:        graphql.Int,
+				Description: "Order of the credit among those with the same role",
+			},
+		},
+	},
+)
+
+// GenreType - Entries found in the genres table
+var GenreType = graphql.NewObject(
+	graphql.ObjectConfig{
+		Name: "Genre",
+		Fields: graphql.Fields{
+			"id": &graphql.Field{
+				Type: scalars.UUID,
+			},
+			"created_at": &graphql.Field{
+				Type: scalars.DateTime,
+			},
+			"name": &graphql.Field{
+				Type: graphql.String,
+			},
+			"movie_count": &graphql.Field{
+				Type:        graphql.Int,
+				Description: "Amount of movies in the genre",
+			},
+		},
+	},
+)
+
+// MovieSnapshotType - State of a movie recorded in a revision
+var MovieSnapshotType = graphql.NewObject(
+	graphql.ObjectConfig{
+		Name: "MovieSnapshot",
+		Fields: graphql.Fields{
+			"version": &graphql.Field{
+				Type: graphql.Int,
+			},
+			"updated_at": &graphql.Field{
+				Type: scalars.DateTime,
+			},
+			"deleted_at": &graphql.Field{
+				Type: scalars.DateTime,
+			},
+			"users_id": &graphql.Field{
+				Type: scalars.UUID,
+			},
+			"name": &graphql.Field{
+				Type: graphql.String,
+			},
+			"release_year": &graphql.Field{
+				Type: graphql.Int,
+			},
+			"description": &graphql.Field{
+				Type: graphql.String,
+			},
+		},
+	},
+)
+
+// MovieRevisionType - Entries found in the movie_revisions table
+var MovieRevisionType = graphql.NewObject(
+	graphql.ObjectConfig{
+		Name: "MovieRevision",
+		Fields: graphql.Fields{
+			"id": &graphql.Field{
+				Type: scalars.UUID,
+			},
+			"created_at": &graphql.Field{
+				Type: scalars.DateTime,
+			},
+			"movies_id": &graphql.Field{
+				Type: scalars.UUID,
+			},
+			"users_id": &graphql.Field{
+				Type:        scalars.UUID,
+				Description: "User who made the change",
+			},
+			"action": &graphql.Field{
+				Type:        graphql.String,
+				Description: "'create', 'update', 'delete', 'restore' or 'revert'",
+			},
+			"before": &graphql.Field{
+				Type:        MovieSnapshotType,
+				Description: "The movie before the change, null when created",
+			},
+			"after": &graphql.Field{
+				Type:        MovieSnapshotType,
+				Description: "The movie after the change",
+			},
+		},
+	},
+)
+
+// MovieImportErrorType - A row refused by an import
+var MovieImportErrorType = graphql.NewObject(
+	graphql.ObjectConfig{
+		Name: "MovieImportError",
+		Fields: graphql.Fields{
+			"row": &graphql.Field{
+				Type:        graphql.Int,
+				Description: "Position of the row in the file starting at 1, the header of CSV files and blank lines of JSON Lines files are not counted",
+			},
+			"message": &graphql.Field{
+				Type: graphql.String,
+			},
 		},
 	},
 )
+
+// MovieImportType - Outcome of a bulk import of movies
+var MovieImportType = graphql.NewObject(
+	graphql.ObjectConfig{
+		Name: "MovieImport",
+		Fields: graphql.Fields{
+			"imported": &graphql.Field{
+				Type:        graphql.Int,
+				Description: "Amount of movies created",
+			},
+			"skipped": &graphql.Field{
+				Type:        graphql.Int,
+				Description: "Amount of rows matching an existing movie or an earlier row by name and release year",
+			},
+			"errors": &graphql.Field{
+				Type:        graphql.NewList(MovieImportErrorType),
+				Description: "Rows which were refused",
+			},
+			"error": &graphql.Field{
+				Type:        graphql.String,
+				Description: "Why the import stopped part way, the movies imported before are kept",
+			},
+		},
+	},
+)
+
+// MovieCreateInputType - Fields of a movie created by createMovies
+var MovieCreateInputType = graphql.NewInputObject(
+	graphql.InputObjectConfig{
+		Name: "MovieCreateInput",
+		Fields: graphql.InputObjectConfigFieldMap{
+			"name": &graphql.InputObjectFieldConfig{
+				Type: graphql.NewNonNull(graphql.String),
+			},
+			"description": &graphql.InputObjectFieldConfig{
+				Type: graphql.String,
+			},
+			"releaseYear": &graphql.InputObjectFieldConfig{
+				Type: graphql.NewNonNull(graphql.Int),
+			},
+		},
+	},
+)
+
+// MovieUpdateInputType - Fields of a movie changed by updateMovies, like the arguments of update
+var MovieUpdateInputType = graphql.NewInputObject(
+	graphql.InputObjectConfig{
+		Name: "MovieUpdateInput",
+		Fields: graphql.InputObjectConfigFieldMap{
+			"id": &graphql.InputObjectFieldConfig{
+				Type: graphql.NewNonNull(scalars.UUID),
+			},
+			"name": &graphql.InputObjectFieldConfig{
+				Type: graphql.String,
+			},
+			"description": &graphql.InputObjectFieldConfig{
+				Type: graphql.String,
+			},
+			"releaseYear": &graphql.InputObjectFieldConfig{
+				Type: graphql.Int,
+			},
+			"expectedVersion": &graphql.InputObjectFieldConfig{
+				Type:        graphql.Int,
+				Description: "Fail with a VERSION_CONFLICT error unless the movie is still at this version",
+			},
+		},
+	},
+)
+
+// MovieDeleteInputType - A movie deleted by deleteMovies
+var MovieDeleteInputType = graphql.NewInputObject(
+	graphql.InputObjectConfig{
+		Name: "MovieDeleteInput",
+		Fields: graphql.InputObjectConfigFieldMap{
+			"id": &graphql.InputObjectFieldConfig{
+				Type: graphql.NewNonNull(scalars.UUID),
+			},
+			"expectedVersion": &graphql.InputObjectFieldConfig{
+				Type:        graphql.Int,
+				Description: "Fail with a VERSION_CONFLICT error unless the movie is still at this version",
+			},
+		},
+	},
+)
+
+// BulkMovieResultType - Outcome of the change of a single movie by a bulk mutation
+var BulkMovieResultType = graphql.NewObject(
+	graphql.ObjectConfig{
+		Name: "BulkMovieResult",
+		Fields: graphql.Fields{
+			"index": &graphql.Field{
+				Type:        graphql.Int,
+				Description: "Position of the movie in the input starting at 0",
+			},
+			"movie": &graphql.Field{
+				Type:        MovieType,
+				Description: "The movie changed, null when the change failed",
+			},
+			"error": &graphql.Field{
+				Type:        graphql.String,
+				Description: "Why the change failed",
+			},
+			"code": &graphql.Field{
+				Type:        graphql.String,
+				Description: "Machine readable reason e.g. VERSION_CONFLICT, or ROLLED_BACK when another movie of an all-or-nothing change failed",
+			},
+		},
+	},
+)
+
+// BulkMoviesType - Outcome of a bulk mutation
+var BulkMoviesType = graphql.NewObject(
+	graphql.ObjectConfig{
+		Name: "BulkMovies",
+		Fields: graphql.Fields{
+			"results": &graphql.Field{
+				Type:        graphql.NewList(BulkMovieResultType),
+				Description: "Outcome of every movie in the order of the input",
+			},
+			"succeeded": &graphql.Field{
+				Type: graphql.Int,
+			},
+			"failed": &graphql.Field{
+				Type: graphql.Int,
+			},
+		},
+	},
+)
+
+// CollectionType - Named, ordered lists of movies curated by users
+var CollectionType = graphql.NewObject(
+	graphql.ObjectConfig{
+		Name:       "Collection",
+		Interfaces: []*graphql.Interface{node.Interface},
+		IsTypeOf: func(p graphql.IsTypeOfParams) bool {
+			switch p.Value.(type) {
+			case models.Collection, *models.Collection:
+				return true
+			}
+			return false
+		},
+		Fields: graphql.Fields{
+			"id": node.GlobalIDField("Collection"),
+			"uuid": &graphql.Field{
+				Type:    scalars.UUID,
+				Resolve: node.LocalID,
+			},
+			"created_at": &graphql.Field{
+				Type: scalars.DateTime,
+			},
+			"updated_at": &graphql.Field{
+				Type: scalars.DateTime,
+			},
+			"users_id": &graphql.Field{
+				Type:        scalars.UUID,
+				Description: "Owner of the collection",
+			},
+			"name": &graphql.Field{
+				Type: graphql.String,
+			},
+			"description": &graphql.Field{
+				Type: graphql.String,
+			},
+			"public": &graphql.Field{
+				Type:        graphql.Boolean,
+				Description: "Public collections are visible to everyone, private ones only to their owner and through their share link",
+			},
+		},
+	},
+)
+
+// CollectionMovieConnectionType - Page of the movies of a collection in their curated order
+var CollectionMovieConnectionType = node.NewConnectionType("CollectionMovie", MovieType, graphql.Fields{
+	"position": &graphql.Field{
+		Type:        graphql.Int,
+		Description: "Place of the movie in the collection, starting from 0",
+	},
+	"added_at": &graphql.Field{
+		Type:        scalars.DateTime,
+		Description: "When the movie was added to the collection",
+	},
+})
+
+// SavedMovieConnectionType - Page of the movies on the watchlist or favorites of a user
+var SavedMovieConnectionType = node.NewConnectionType("SavedMovie", MovieType, graphql.Fields{
+	"saved_at": &graphql.Field{
+		Type:        scalars.DateTime,
+		Description: "When the movie was added to the list",
+	},
+})
+
+// savedListField - Field paginating the movies on the list of the source user, only the user
+// may see them
+func savedListField(dialect goqu.DialectWrapper, db *sql.DB, list string, description string) *graphql.Field {
+	return &graphql.Field{
+		Type:        SavedMovieConnectionType,
+		Description: description,
+		Args:        node.ConnectionArgs(),
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			id, err := node.LocalID(p)
+			if err != nil {
+				return nil, err
+			}
+
+			viewer, viewerErr := source.Viewer(p.Context, dialect, db)
+			if viewerErr != nil {
+				return nil, viewerErr
+			}
+			if usersID, _ := id.(string); usersID != viewer.ID {
+				return nil, source.ErrForbidden
+			}
+
+			first, after, pageErr := node.PageArgs(p.Args)
+			if pageErr != nil {
+				return nil, pageErr
+			}
+
+			return savedMovies(dialect, db, viewer.ID, list, first, after)
+		},
+	}
+}
+
+// savedByViewerField - Field telling whether the viewer saved the source movie to the list
+func savedByViewerField(dialect goqu.DialectWrapper, db *sql.DB, list string, description string) *graphql.Field {
+	return &graphql.Field{
+		Type:        graphql.Boolean,
+		Description: description,
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			id, err := node.LocalID(p)
+			if err != nil {
+				return nil, err
+			}
+
+			viewer, viewerErr := source.Viewer(p.Context, dialect, db)
+			if viewerErr != nil {
+				return nil, viewerErr
+			}
+
+			count, countErr := countRows(dialect, db, "movies_saves", goqu.Ex{
+				"users_id":  viewer.ID,
+				"movies_id": id,
+				"list":      list,
+			})
+			return count > 0, countErr
+		},
+	}
+}
+
+// savesCountField - Field counting the users who saved the source movie to the list
+func savesCountField(dialect goqu.DialectWrapper, db *sql.DB, list string, description string) *graphql.Field {
+	return &graphql.Field{
+		Type:        graphql.Int,
+		Description: description,
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			id, err := node.LocalID(p)
+			if err != nil {
+				return nil, err
+			}
+
+			return countRows(dialect, db, "movies_saves", goqu.Ex{
+				"movies_id": id,
+				"list":      list,
+			})
+		},
+	}
+}
+
+// BindFields - Add the fields of the movie types which require database access
+func BindFields(dialect goqu.DialectWrapper, db *sql.DB) {
+	MovieType.AddFieldConfig("reviews", &graphql.Field{
+		Type:        graphql.NewList(ReviewType),
+		Description: "Reviews given to the movie",
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			id, err := node.LocalID(p)
+			if err != nil {
+				return nil, err
+			}
+
+			return findReviews(dialect, db, goqu.Ex{
+				"movies_id":  id,
+				"deleted_at": nil,
+			})
+		},
+	})
+
+	MovieType.AddFieldConfig("genres", &graphql.Field{
+		Type:        graphql.NewList(GenreType),
+		Description: "Genres the movie is classified as",
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			id, err := node.LocalID(p)
+			if err != nil {
+				return nil, err
+			}
+
+			return findGenres(dialect, db, goqu.Ex{
+				"genres.id": goqu.Op{"in": dialect.From("movies_genres").Select("genres_id").Where(goqu.Ex{
+					"movies_id": id,
+				})},
+			})
+		},
+	})
+
+	MovieType.AddFieldConfig("tags", &graphql.Field{
+		Type:        graphql.NewList(graphql.String),
+		Description: "Free-form tags of the movie",
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			id, err := node.LocalID(p)
+			if err != nil {
+				return nil, err
+			}
+
+			moviesID, _ := id.(string)
+			return findTags(dialect, db, moviesID)
+		},
+	})
+
+	MovieType.AddFieldConfig("cast", &graphql.Field{
+		Type:        graphql.NewList(CreditType),
+		Description: "Actors of the movie in billing order",
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			id, err := node.LocalID(p)
+			if err != nil {
+				return nil, err
+			}
+
+			return findCredits(dialect, db, goqu.Ex{
+				"movies_credits.movies_id": id,
+				"movies_credits.role":      roleActor,
+			}, goqu.I("movies_credits.position").Asc(), goqu.I("people.name").Asc())
+		},
+	})
+
+	MovieType.AddFieldConfig("crew", &graphql.Field{
+		Type:        graphql.NewList(CreditType),
+		Description: "Everyone credited with a role other than actor, grouped by role",
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			id, err := node.LocalID(p)
+			if err != nil {
+				return nil, err
+			}
+
+			return findCredits(dialect, db, goqu.Ex{
+				"movies_credits.movies_id": id,
+				"movies_credits.role":      goqu.Op{"neq": roleActor},
+			}, goqu.I("movies_credits.role").Asc(), goqu.I("movies_credits.position").Asc(), goqu.I("people.name").Asc())
+		},
+	})
+
+	PersonType.AddFieldConfig("filmography", &graphql.Field{
+		Type:        graphql.NewList(CreditType),
+		Description: "Credits of the person, newest movies first",
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			id, err := node.LocalID(p)
+			if err != nil {
+				return nil, err
+			}
+
+			return findCredits(dialect, db, goqu.Ex{
+				"movies_credits.people_id": id,
+			}, goqu.I("movies.release_year").Desc(), goqu.I("movies.name").Asc(), goqu.I("movies_credits.role").Asc())
+		},
+	})
+
+	CreditType.AddFieldConfig("person", &graphql.Field{
+		Type:        PersonType,
+		Description: "The credited person",
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			credit, _ := p.Source.(models.Credit)
+			if pointer, ok := p.Source.(*models.Credit); ok {
+				credit = *pointer
+			}
+
+			return findPerson(dialect, db, goqu.Ex{
+				"id": credit.PeopleID,
+			})
+		},
+	})
+
+	CreditType.AddFieldConfig("movie", &graphql.Field{
+		Type:        MovieType,
+		Description: "The movie the person is credited in",
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			credit, _ := p.Source.(models.Credit)
+			if pointer, ok := p.Source.(*models.Credit); ok {
+				credit = *pointer
+			}
+
+			return findMovie(dialect, db, goqu.Ex{
+				"id": credit.MoviesID,
+			})
+		},
+	})
+
+	MovieType.AddFieldConfig("history", &graphql.Field{
+		Type:        graphql.NewList(MovieRevisionType),
+		Description: "Changes made to the movie, newest first",
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			id, err := node.LocalID(p)
+			if err != nil {
+				return nil, err
+			}
+
+			return findRevisions(dialect, db, goqu.Ex{
+				"movies_id": id,
+			})
+		},
+	})
+
+	MovieType.AddFieldConfig("inWatchlist", savedByViewerField(dialect, db, models.Watchlist, "Whether the current user added the movie to their watchlist"))
+	MovieType.AddFieldConfig("isFavorite", savedByViewerField(dialect, db, models.Favorites, "Whether the current user added the movie to their favorites"))
+	MovieType.AddFieldConfig("watchlist_count", savesCountField(dialect, db, models.Watchlist, "Number of users with the movie on their watchlist"))
+	MovieType.AddFieldConfig("favorite_count", savesCountField(dialect, db, models.Favorites, "Number of users with the movie among their favorites"))
+
+	users.UserType.AddFieldConfig("watchlist", savedListField(dialect, db, models.Watchlist, "Movies the user wants to see, most recently added first. Only visible to the user"))
+	users.UserType.AddFieldConfig("favorites", savedListField(dialect, db, models.Favorites, "Favorite movies of the user, most recently added first. Only visible to the user"))
+
+	CollectionType.AddFieldConfig("movies", &graphql.Field{
+		Type:        CollectionMovieConnectionType,
+		Description: "Movies of the collection in their curated order",
+		Args:        node.ConnectionArgs(),
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			id, err := node.LocalID(p)
+			if err != nil {
+				return nil, err
+			}
+
+			first, after, pageErr := node.PageArgs(p.Args)
+			if pageErr != nil {
+				return nil, pageErr
+			}
+
+			collectionsID, _ := id.(string)
+			return collectionMovies(dialect, db, collectionsID, first, after)
+		},
+	})
+
+	CollectionType.AddFieldConfig("share_url", &graphql.Field{
+		Type:        graphql.String,
+		Description: "Link revealing the collection to anyone, only shown to its owner once shared with shareCollection",
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			var collection models.Collection
+			switch value := p.Source.(type) {
+			case models.Collection:
+				collection = value
+			case *models.Collection:
+				collection = *value
+			}
+			if len(collection.ShareToken) == 0 {
+				return nil, nil
+			}
+
+			viewer, viewerErr := source.Viewer(p.Context, dialect, db)
+			if viewerErr != nil {
+				return nil, viewerErr
+			}
+			if source.RequireOwnerOrAdmin(viewer, collection.UsersID) != nil {
+				return nil, nil
+			}
+
+			return source.CollectionShareLink(collection.ShareToken)
+		},
+	})
+}
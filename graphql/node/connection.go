@@ -0,0 +1,142 @@
+package node
+
+import (
+	"encoding/base64"
+	"errors"
+	"strings"
+
+	"github.com/graphql-go/graphql"
+)
+
+// Page sizes of connections
+const (
+	// DefaultPageSize - edges returned when first is not given
+	DefaultPageSize = 20
+	// MaxPageSize - most edges returned at once
+	MaxPageSize = 100
+)
+
+// cursorPrefix - distinguishes cursors from global IDs once decoded
+const cursorPrefix = "cursor:"
+
+// ErrInvalidCursor - returned when the after argument is not a cursor returned by the connection
+var ErrInvalidCursor = errors.New("Invalid cursor")
+
+// PageInfoType - Relay pagination details of a connection
+var PageInfoType = graphql.NewObject(
+	graphql.ObjectConfig{
+		Name: "PageInfo",
+		Fields: graphql.Fields{
+			"hasNextPage": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.Boolean),
+			},
+			"hasPreviousPage": &graphql.Field{
+				Type:        graphql.NewNonNull(graphql.Boolean),
+				Description: "Always false, connections are only paginated forwards",
+			},
+			"startCursor": &graphql.Field{
+				Type: graphql.String,
+			},
+			"endCursor": &graphql.Field{
+				Type:        graphql.String,
+				Description: "Pass as after to fetch the next page",
+			},
+		},
+	},
+)
+
+// ConnectionArgs - Arguments of a connection field, paginating forwards
+func ConnectionArgs() graphql.FieldConfigArgument {
+	return graphql.FieldConfigArgument{
+		"first": &graphql.ArgumentConfig{
+			Type:        graphql.Int,
+			Description: "Number of edges to return, 20 by default and at most 100",
+		},
+		"after": &graphql.ArgumentConfig{
+			Type:        graphql.String,
+			Description: "Cursor of the edge to continue after",
+		},
+	}
+}
+
+// NewConnectionType - Relay connection named <name>Connection whose edges of the <name>Edge type
+// hold the node along with the additional edge fields
+func NewConnectionType(name string, nodeType graphql.Output, edgeFields graphql.Fields) *graphql.Object {
+	fields := graphql.Fields{
+		"cursor": &graphql.Field{
+			Type: graphql.NewNonNull(graphql.String),
+		},
+		"node": &graphql.Field{
+			Type: nodeType,
+		},
+	}
+	for k, v := range edgeFields {
+		fields[k] = v
+	}
+
+	edgeType := graphql.NewObject(
+		graphql.ObjectConfig{
+			Name:   name + "Edge",
+			Fields: fields,
+		},
+	)
+
+	return graphql.NewObject(
+		graphql.ObjectConfig{
+			Name: name + "Connection",
+			Fields: graphql.Fields{
+				"edges": &graphql.Field{
+					Type: graphql.NewList(edgeType),
+				},
+				"pageInfo": &graphql.Field{
+					Type: graphql.NewNonNull(PageInfoType),
+				},
+				"totalCount": &graphql.Field{
+					Type: graphql.Int,
+				},
+			},
+		},
+	)
+}
+
+// PageArgs - Read the first and after arguments of a connection field, returns the page size and
+// the decoded cursor, empty for the first page
+func PageArgs(args map[string]interface{}) (int, string, error) {
+	first := DefaultPageSize
+	if value, ok := args["first"].(int); ok {
+		first = value
+	}
+	if first < 0 || first > MaxPageSize {
+		return 0, "", errors.New("first must be between 0 and 100")
+	}
+
+	after, _ := args["after"].(string)
+	if len(after) == 0 {
+		return first, "", nil
+	}
+	cursor, cursorErr := FromCursor(after)
+	if cursorErr != nil {
+		return 0, "", cursorErr
+	}
+	return first, cursor, nil
+}
+
+// ToCursor - Encode the position of an edge into an opaque cursor
+func ToCursor(position string) string {
+	return base64.StdEncoding.EncodeToString([]byte(cursorPrefix + position))
+}
+
+// FromCursor - Decode an opaque cursor, returns the position it refers to or an error if the
+// cursor is malformed
+func FromCursor(cursor string) (string, error) {
+	decoded, err := base64.StdEncoding.DecodeString(cursor)
+	if err != nil || !strings.HasPrefix(string(decoded), cursorPrefix) {
+		return "", ErrInvalidCursor
+	}
+
+	position := strings.TrimPrefix(string(decoded), cursorPrefix)
+	if len(position) == 0 {
+		return "", ErrInvalidCursor
+	}
+	return position, nil
+}
@@ -0,0 +1,85 @@
+package node
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"strings"
+
+	"github.com/graphql-go/graphql"
+	uuid "github.com/satori/go.uuid"
+)
+
+// Fetcher - lookup a single object of a type by its UUID for the request of the context, returns
+// nil if it does not exist or the user of the request may not see it
+type Fetcher func(ctx context.Context, id string) (interface{}, error)
+
+// Interface - Relay Node interface implemented by every globally identifiable type
+var Interface = graphql.NewInterface(
+	graphql.InterfaceConfig{
+		Name:        "Node",
+		Description: "An object with a globally unique, opaque ID",
+		Fields: graphql.Fields{
+			"id": &graphql.Field{
+				Type:        graphql.NewNonNull(graphql.ID),
+				Description: "The global ID of the object",
+			},
+		},
+	},
+)
+
+// types - Names of the types implementing Node, registered by GlobalIDField
+var types = map[string]bool{}
+
+// IsType - Determine whether the type name belongs to a type implementing Node
+func IsType(typeName string) bool {
+	return types[typeName]
+}
+
+// ToGlobalID - Encode the type name and UUID into an opaque global ID
+func ToGlobalID(typeName string, id string) string {
+	return base64.StdEncoding.EncodeToString([]byte(typeName + ":" + id))
+}
+
+// FromGlobalID - Decode an opaque global ID, returns the type name and UUID it refers to
+// or an error if the ID is malformed or does not hold a UUID
+func FromGlobalID(globalID string) (string, string, error) {
+	decoded, err := base64.StdEncoding.DecodeString(globalID)
+	if err != nil {
+		return "", "", errors.New("Invalid global ID")
+	}
+
+	parts := strings.SplitN(string(decoded), ":", 2)
+	if len(parts) != 2 || len(parts[0]) == 0 {
+		return "", "", errors.New("Invalid global ID")
+	}
+	if _, uuidErr := uuid.FromString(parts[1]); uuidErr != nil {
+		return "", "", errors.New("Invalid global ID")
+	}
+
+	return parts[0], parts[1], nil
+}
+
+// LocalID - Resolve the UUID of the source object from its `id` property
+func LocalID(p graphql.ResolveParams) (interface{}, error) {
+	p.Info.FieldName = "id"
+	return graphql.DefaultResolveFn(p)
+}
+
+// GlobalIDField - Field resolving the global ID of a source object of the specified type
+func GlobalIDField(typeName string) *graphql.Field {
+	types[typeName] = true
+	return &graphql.Field{
+		Type:        graphql.NewNonNull(graphql.ID),
+		Description: "The global ID of the object",
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			id, err := LocalID(p)
+			if err != nil {
+				return nil, err
+			}
+
+			localID, _ := id.(string)
+			return ToGlobalID(typeName, localID), nil
+		},
+	}
+}
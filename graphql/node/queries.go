@@ -0,0 +1,87 @@
+package node
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/doug-martin/goqu/v8"
+	"github.com/graphql-go/graphql"
+
+	source "github.com/HencoSmith/graphql-example-go/source"
+)
+
+// resolve - lookup the object referred to by the global ID using the fetcher of its type,
+// returns nil for malformed IDs and IDs of unknown types or objects
+func resolve(ctx context.Context, fetchers map[string]Fetcher, globalID string) (interface{}, error) {
+	typeName, id, err := FromGlobalID(globalID)
+	if err != nil {
+		return nil, nil
+	}
+
+	fetch, ok := fetchers[typeName]
+	if !ok {
+		return nil, nil
+	}
+
+	return fetch(ctx, id)
+}
+
+// Queries - all GraphQL queries related to global object identification
+// fetchers - lookup functions keyed by the GraphQL type name encoded in global IDs
+func Queries(dialect goqu.DialectWrapper, db *sql.DB, fetchers map[string]Fetcher) graphql.Fields {
+	return graphql.Fields{
+		"node": &graphql.Field{
+			Type:        Interface,
+			Description: "Get any object by its global ID, null if it does not exist",
+			Args: graphql.FieldConfigArgument{
+				"id": &graphql.ArgumentConfig{
+					Type: graphql.NewNonNull(graphql.ID),
+				},
+			},
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				user, customError := source.GetUserFromToken(p.Context, dialect, db)
+				if customError != nil {
+					return nil, customError
+				}
+				if scopeErr := source.RequireScope(user, source.ScopeRead); scopeErr != nil {
+					return nil, scopeErr
+				}
+
+				id, _ := p.Args["id"].(string)
+				return resolve(p.Context, fetchers, id)
+			},
+		},
+
+		"nodes": &graphql.Field{
+			Type:        graphql.NewNonNull(graphql.NewList(Interface)),
+			Description: "Get a list of objects by their global IDs, in the same order. IDs which do not refer to an object are null",
+			Args: graphql.FieldConfigArgument{
+				"ids": &graphql.ArgumentConfig{
+					Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(graphql.ID))),
+				},
+			},
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				user, customError := source.GetUserFromToken(p.Context, dialect, db)
+				if customError != nil {
+					return nil, customError
+				}
+				if scopeErr := source.RequireScope(user, source.ScopeRead); scopeErr != nil {
+					return nil, scopeErr
+				}
+
+				ids, _ := p.Args["ids"].([]interface{})
+				nodes := make([]interface{}, len(ids))
+				for i, id := range ids {
+					globalID, _ := id.(string)
+					object, err := resolve(p.Context, fetchers, globalID)
+					if err != nil {
+						return nil, err
+					}
+					nodes[i] = object
+				}
+
+				return nodes, nil
+			},
+		},
+	}
+}
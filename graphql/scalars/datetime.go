@@ -0,0 +1,60 @@
+package scalars
+
+import (
+	"time"
+
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/language/ast"
+)
+
+// serializeDateTime - Format the value as an RFC 3339 string in UTC, returns nil if the
+// value is not a time
+func serializeDateTime(value interface{}) interface{} {
+	switch value := value.(type) {
+	case time.Time:
+		return value.UTC().Format(time.RFC3339)
+	case *time.Time:
+		if value == nil {
+			return nil
+		}
+		return serializeDateTime(*value)
+	case string:
+		return serializeDateTime(parseDateTime(value))
+	default:
+		return nil
+	}
+}
+
+// parseDateTime - Parse an RFC 3339 string into a time, returns nil if the input is invalid
+func parseDateTime(value interface{}) interface{} {
+	switch value := value.(type) {
+	case string:
+		parsed, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return nil
+		}
+		return parsed
+	case *string:
+		if value == nil {
+			return nil
+		}
+		return parseDateTime(*value)
+	default:
+		return nil
+	}
+}
+
+// DateTime - Timestamps stored as timestamptz columns, serialized as RFC 3339 strings
+var DateTime = graphql.NewScalar(graphql.ScalarConfig{
+	Name:        "DateTime",
+	Description: "The `DateTime` scalar type represents a point in time serialized as an RFC 3339 string e.g. 2019-08-09T14:30:00Z",
+	Serialize:   serializeDateTime,
+	ParseValue:  parseDateTime,
+	ParseLiteral: func(valueAST ast.Value) interface{} {
+		switch valueAST := valueAST.(type) {
+		case *ast.StringValue:
+			return parseDateTime(valueAST.Value)
+		}
+		return nil
+	},
+})
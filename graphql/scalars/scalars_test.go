@@ -0,0 +1,67 @@
+package scalars
+
+import (
+	"testing"
+	"time"
+
+	"github.com/graphql-go/graphql/language/ast"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/HencoSmith/graphql-example-go/graphql/node"
+)
+
+// movieID - UUID used by the tests, in a different case than the canonical representation
+const movieID = "D56D4BFF-4E7E-4CF9-A3D2-38973C9DD57D"
+
+func TestParseUUID(t *testing.T) {
+	assert.Equal(t, "d56d4bff-4e7e-4cf9-a3d2-38973c9dd57d", parseUUID(movieID))
+	assert.Equal(t, "d56d4bff-4e7e-4cf9-a3d2-38973c9dd57d", UUID.ParseLiteral(&ast.StringValue{Value: movieID}))
+
+	for _, invalid := range []interface{}{"", "abc", "d56d4bff-4e7e-4cf9-a3d2", 42, nil, (*string)(nil)} {
+		assert.Nil(t, parseUUID(invalid), "Input %v", invalid)
+	}
+	assert.Nil(t, UUID.ParseLiteral(&ast.StringValue{Value: "abc"}))
+	assert.Nil(t, UUID.ParseLiteral(&ast.IntValue{Value: "42"}), "Only strings are UUIDs")
+}
+
+func TestParseUUIDGlobalID(t *testing.T) {
+	// Registers the type
+	node.GlobalIDField("Movie")
+
+	assert.Equal(t, "d56d4bff-4e7e-4cf9-a3d2-38973c9dd57d", parseUUID(node.ToGlobalID("Movie", movieID)))
+	assert.Equal(t, "d56d4bff-4e7e-4cf9-a3d2-38973c9dd57d", UUID.ParseLiteral(&ast.StringValue{Value: node.ToGlobalID("Movie", movieID)}))
+
+	assert.Nil(t, parseUUID(node.ToGlobalID("Unknown", movieID)), "Types not implementing Node")
+	assert.Nil(t, parseUUID(node.ToGlobalID("Movie", "abc")), "Global IDs not holding a UUID")
+	assert.Nil(t, parseUUID("not base64!"))
+}
+
+func TestSerializeUUID(t *testing.T) {
+	assert.Equal(t, "d56d4bff-4e7e-4cf9-a3d2-38973c9dd57d", serializeUUID(movieID))
+	assert.Nil(t, serializeUUID("abc"))
+
+	// Global IDs are input only
+	assert.Nil(t, serializeUUID(node.ToGlobalID("Movie", movieID)))
+}
+
+func TestParseDateTime(t *testing.T) {
+	parsed := parseDateTime("2019-08-09T16:30:00+02:00")
+	assert.Equal(t, time.Date(2019, 8, 9, 14, 30, 0, 0, time.UTC), parsed.(time.Time).UTC())
+	assert.Equal(t, parsed, DateTime.ParseLiteral(&ast.StringValue{Value: "2019-08-09T16:30:00+02:00"}))
+
+	for _, invalid := range []interface{}{"", "2019-08-09", "2019-08-09 14:30:00", "09/08/2019 14:30", 1565361000, nil, (*string)(nil)} {
+		assert.Nil(t, parseDateTime(invalid), "Input %v", invalid)
+	}
+	assert.Nil(t, DateTime.ParseLiteral(&ast.IntValue{Value: "1565361000"}), "Only strings are timestamps")
+}
+
+func TestSerializeDateTime(t *testing.T) {
+	value := time.Date(2019, 8, 9, 16, 30, 0, 0, time.FixedZone("CEST", 2*60*60))
+	assert.Equal(t, "2019-08-09T14:30:00Z", serializeDateTime(value))
+	assert.Equal(t, "2019-08-09T14:30:00Z", serializeDateTime(&value))
+	assert.Equal(t, "2019-08-09T14:30:00Z", serializeDateTime("2019-08-09T16:30:00+02:00"))
+
+	assert.Nil(t, serializeDateTime((*time.Time)(nil)))
+	assert.Nil(t, serializeDateTime("2019-08-09"))
+	assert.Nil(t, serializeDateTime(nil))
+}
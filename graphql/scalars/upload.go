@@ -0,0 +1,38 @@
+package scalars
+
+import (
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/language/ast"
+
+	"github.com/HencoSmith/graphql-example-go/models"
+)
+
+// parseUpload - Accept the files placed in the variables of GraphQL multipart requests, returns
+// nil for any other input
+func parseUpload(value interface{}) interface{} {
+	switch value := value.(type) {
+	case *models.Upload:
+		if value == nil {
+			return nil
+		}
+		return value
+	case models.Upload:
+		return &value
+	default:
+		return nil
+	}
+}
+
+// Upload - Files sent using the GraphQL multipart request specification, input only
+var Upload = graphql.NewScalar(graphql.ScalarConfig{
+	Name: "Upload",
+	Description: "The `Upload` scalar type represents a file sent as part of a GraphQL multipart request" +
+		" (https://github.com/jaydenseric/graphql-multipart-request-spec), it is only valid as a variable",
+	Serialize: func(value interface{}) interface{} {
+		return nil
+	},
+	ParseValue: parseUpload,
+	ParseLiteral: func(valueAST ast.Value) interface{} {
+		return nil
+	},
+})
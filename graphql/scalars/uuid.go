@@ -0,0 +1,75 @@
+package scalars
+
+import (
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/language/ast"
+	uuid "github.com/satori/go.uuid"
+
+	"github.com/HencoSmith/graphql-example-go/graphql/node"
+)
+
+// serializeUUID - Validate the value as a UUID, returns the canonical string representation
+// or nil if the value is not a valid UUID
+func serializeUUID(value interface{}) interface{} {
+	switch value := value.(type) {
+	case string:
+		parsed, err := uuid.FromString(value)
+		if err != nil {
+			return nil
+		}
+		return parsed.String()
+	case *string:
+		if value == nil {
+			return nil
+		}
+		return serializeUUID(*value)
+	case uuid.UUID:
+		return value.String()
+	case *uuid.UUID:
+		if value == nil {
+			return nil
+		}
+		return value.String()
+	default:
+		return nil
+	}
+}
+
+// parseUUID - Validate the input as a UUID or a global ID of a Node type wrapping a UUID,
+// returns the canonical string representation or nil if the input is invalid
+func parseUUID(value interface{}) interface{} {
+	switch value := value.(type) {
+	case string:
+		if parsed := serializeUUID(value); parsed != nil {
+			return parsed
+		}
+		typeName, id, err := node.FromGlobalID(value)
+		if err != nil || !node.IsType(typeName) {
+			return nil
+		}
+		return serializeUUID(id)
+	case *string:
+		if value == nil {
+			return nil
+		}
+		return parseUUID(*value)
+	default:
+		return serializeUUID(value)
+	}
+}
+
+// UUID - Identifiers stored as uuid columns, serialized as the canonical hyphenated string
+var UUID = graphql.NewScalar(graphql.ScalarConfig{
+	Name: "UUID",
+	Description: "The `UUID` scalar type represents an RFC 4122 UUID serialized as a hyphenated string." +
+		" Global IDs of objects are also accepted as input",
+	Serialize:  serializeUUID,
+	ParseValue: parseUUID,
+	ParseLiteral: func(valueAST ast.Value) interface{} {
+		switch valueAST := valueAST.(type) {
+		case *ast.StringValue:
+			return parseUUID(valueAST.Value)
+		}
+		return nil
+	},
+})
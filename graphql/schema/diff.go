@@ -0,0 +1,374 @@
+package schema
+
+import (
+	"sort"
+
+	"github.com/graphql-go/graphql/language/ast"
+	"github.com/graphql-go/graphql/language/parser"
+	"github.com/graphql-go/graphql/language/printer"
+)
+
+// Criticality - how a schema change affects existing clients
+type Criticality string
+
+const (
+	// Breaking - existing operations may no longer validate or execute
+	Breaking Criticality = "BREAKING"
+	// Dangerous - existing operations keep working but may observe different behavior
+	Dangerous Criticality = "DANGEROUS"
+	// Safe - existing operations are not affected
+	Safe Criticality = "SAFE"
+)
+
+// Change - A single difference between two versions of a schema
+type Change struct {
+	Criticality Criticality `json:"criticality"`
+	Path        string      `json:"path"`
+	Message     string      `json:"message"`
+}
+
+// definition - the parts of a named type definition which are compared
+type definition struct {
+	kind        string
+	description string
+	interfaces  []string
+	fields      map[string]*ast.FieldDefinition
+	inputFields map[string]*ast.InputValueDefinition
+	values      map[string]*ast.EnumValueDefinition
+	members     []string
+}
+
+// Diff - Compare two schemas written in SDL and classify every change between them,
+// returns the changes sorted by path or an error if either schema cannot be parsed
+func Diff(oldSDL string, newSDL string) ([]Change, error) {
+	oldDefinitions, oldErr := parseDefinitions(oldSDL)
+	if oldErr != nil {
+		return nil, oldErr
+	}
+	newDefinitions, newErr := parseDefinitions(newSDL)
+	if newErr != nil {
+		return nil, newErr
+	}
+
+	changes := []Change{}
+	for name, oldDefinition := range oldDefinitions {
+		newDefinition, ok := newDefinitions[name]
+		if !ok {
+			changes = append(changes, Change{Breaking, name, "Type " + name + " was removed"})
+			continue
+		}
+		if oldDefinition.kind != newDefinition.kind {
+			changes = append(changes, Change{Breaking, name, "Type " + name + " changed from " + oldDefinition.kind + " to " + newDefinition.kind})
+			continue
+		}
+		changes = append(changes, diffDefinition(name, oldDefinition, newDefinition)...)
+	}
+	for name, newDefinition := range newDefinitions {
+		if _, ok := oldDefinitions[name]; !ok {
+			changes = append(changes, Change{Safe, name, newDefinition.kind + " " + name + " was added"})
+		}
+	}
+
+	sort.SliceStable(changes, func(i, j int) bool {
+		if changes[i].Path == changes[j].Path {
+			return changes[i].Message < changes[j].Message
+		}
+		return changes[i].Path < changes[j].Path
+	})
+
+	return changes, nil
+}
+
+// HasBreaking - Check whether any of the changes are breaking
+func HasBreaking(changes []Change) bool {
+	for _, change := range changes {
+		if change.Criticality == Breaking {
+			return true
+		}
+	}
+	return false
+}
+
+// parseDefinitions - Parse the SDL into its named type definitions
+func parseDefinitions(sdl string) (map[string]*definition, error) {
+	document, err := parser.Parse(parser.ParseParams{Source: sdl})
+	if err != nil {
+		return nil, err
+	}
+
+	definitions := map[string]*definition{}
+	for _, node := range document.Definitions {
+		switch node := node.(type) {
+		case *ast.ScalarDefinition:
+			definitions[node.Name.Value] = &definition{
+				kind:        "scalar",
+				description: describe(node.Description),
+			}
+		case *ast.ObjectDefinition:
+			interfaces := []string{}
+			for _, iface := range node.Interfaces {
+				interfaces = append(interfaces, iface.Name.Value)
+			}
+			definitions[node.Name.Value] = &definition{
+				kind:        "type",
+				description: describe(node.Description),
+				interfaces:  interfaces,
+				fields:      fieldMap(node.Fields),
+			}
+		case *ast.InterfaceDefinition:
+			definitions[node.Name.Value] = &definition{
+				kind:        "interface",
+				description: describe(node.Description),
+				fields:      fieldMap(node.Fields),
+			}
+		case *ast.UnionDefinition:
+			members := []string{}
+			for _, member := range node.Types {
+				members = append(members, member.Name.Value)
+			}
+			definitions[node.Name.Value] = &definition{
+				kind:        "union",
+				description: describe(node.Description),
+				members:     members,
+			}
+		case *ast.EnumDefinition:
+			values := map[string]*ast.EnumValueDefinition{}
+			for _, value := range node.Values {
+				values[value.Name.Value] = value
+			}
+			definitions[node.Name.Value] = &definition{
+				kind:        "enum",
+				description: describe(node.Description),
+				values:      values,
+			}
+		case *ast.InputObjectDefinition:
+			definitions[node.Name.Value] = &definition{
+				kind:        "input",
+				description: describe(node.Description),
+				inputFields: inputValueMap(node.Fields),
+			}
+		}
+	}
+
+	return definitions, nil
+}
+
+// diffDefinition - Compare two definitions of the same name and kind
+func diffDefinition(name string, oldDefinition *definition, newDefinition *definition) []Change {
+	changes := []Change{}
+	if oldDefinition.description != newDefinition.description {
+		changes = append(changes, Change{Safe, name, "Description of " + name + " changed"})
+	}
+
+	for _, iface := range difference(oldDefinition.interfaces, newDefinition.interfaces) {
+		changes = append(changes, Change{Breaking, name, name + " no longer implements " + iface})
+	}
+	for _, iface := range difference(newDefinition.interfaces, oldDefinition.interfaces) {
+		changes = append(changes, Change{Dangerous, name, name + " now implements " + iface})
+	}
+
+	for _, member := range difference(oldDefinition.members, newDefinition.members) {
+		changes = append(changes, Change{Breaking, name, member + " was removed from union " + name})
+	}
+	for _, member := range difference(newDefinition.members, oldDefinition.members) {
+		changes = append(changes, Change{Dangerous, name, member + " was added to union " + name})
+	}
+
+	for valueName, oldValue := range oldDefinition.values {
+		path := name + "." + valueName
+		newValue, ok := newDefinition.values[valueName]
+		if !ok {
+			changes = append(changes, Change{Breaking, path, "Enum value " + path + " was removed"})
+			continue
+		}
+		changes = append(changes, diffDeprecation(path, oldValue.Directives, newValue.Directives)...)
+	}
+	for valueName := range newDefinition.values {
+		if _, ok := oldDefinition.values[valueName]; !ok {
+			changes = append(changes, Change{Dangerous, name + "." + valueName, "Enum value " + name + "." + valueName + " was added"})
+		}
+	}
+
+	for fieldName, oldField := range oldDefinition.fields {
+		path := name + "." + fieldName
+		newField, ok := newDefinition.fields[fieldName]
+		if !ok {
+			changes = append(changes, Change{Breaking, path, "Field " + path + " was removed"})
+			continue
+		}
+		if !isSafeOutputChange(oldField.Type, newField.Type) {
+			changes = append(changes, Change{Breaking, path, "Field " + path + " changed type from " + printNode(oldField.Type) + " to " + printNode(newField.Type)})
+		} else if printNode(oldField.Type) != printNode(newField.Type) {
+			changes = append(changes, Change{Safe, path, "Field " + path + " changed type from " + printNode(oldField.Type) + " to " + printNode(newField.Type)})
+		}
+		if describe(oldField.Description) != describe(newField.Description) {
+			changes = append(changes, Change{Safe, path, "Description of " + path + " changed"})
+		}
+		changes = append(changes, diffDeprecation(path, oldField.Directives, newField.Directives)...)
+		changes = append(changes, diffInputValues(path, "Argument", inputValueMap(oldField.Arguments), inputValueMap(newField.Arguments), Dangerous)...)
+	}
+	for fieldName := range newDefinition.fields {
+		if _, ok := oldDefinition.fields[fieldName]; !ok {
+			changes = append(changes, Change{Safe, name + "." + fieldName, "Field " + name + "." + fieldName + " was added"})
+		}
+	}
+
+	changes = append(changes, diffInputValues(name, "Input field", oldDefinition.inputFields, newDefinition.inputFields, Safe)...)
+
+	return changes
+}
+
+// diffInputValues - Compare the arguments of a field or the fields of an input object
+// optionalAdded - criticality of adding an input value which is not required
+func diffInputValues(parent string, label string, oldValues map[string]*ast.InputValueDefinition, newValues map[string]*ast.InputValueDefinition, optionalAdded Criticality) []Change {
+	changes := []Change{}
+	for valueName, oldValue := range oldValues {
+		path := parent + "." + valueName
+		newValue, ok := newValues[valueName]
+		if !ok {
+			changes = append(changes, Change{Breaking, path, label + " " + path + " was removed"})
+			continue
+		}
+		if !isSafeInputChange(oldValue.Type, newValue.Type) {
+			changes = append(changes, Change{Breaking, path, label + " " + path + " changed type from " + printNode(oldValue.Type) + " to " + printNode(newValue.Type)})
+		} else if printNode(oldValue.Type) != printNode(newValue.Type) {
+			changes = append(changes, Change{Safe, path, label + " " + path + " changed type from " + printNode(oldValue.Type) + " to " + printNode(newValue.Type)})
+		}
+		if printNode(oldValue.DefaultValue) != printNode(newValue.DefaultValue) {
+			changes = append(changes, Change{Dangerous, path, "Default value of " + path + " changed from " + printNode(oldValue.DefaultValue) + " to " + printNode(newValue.DefaultValue)})
+		}
+	}
+	for valueName, newValue := range newValues {
+		if _, ok := oldValues[valueName]; ok {
+			continue
+		}
+		path := parent + "." + valueName
+		if _, required := newValue.Type.(*ast.NonNull); required && newValue.DefaultValue == nil {
+			changes = append(changes, Change{Breaking, path, "Required " + label + " " + path + " was added"})
+		} else {
+			changes = append(changes, Change{optionalAdded, path, label + " " + path + " was added"})
+		}
+	}
+	return changes
+}
+
+// diffDeprecation - Report fields and enum values which became deprecated
+func diffDeprecation(path string, oldDirectives []*ast.Directive, newDirectives []*ast.Directive) []Change {
+	if !isDeprecated(oldDirectives) && isDeprecated(newDirectives) {
+		return []Change{{Safe, path, path + " was deprecated"}}
+	}
+	if isDeprecated(oldDirectives) && !isDeprecated(newDirectives) {
+		return []Change{{Safe, path, path + " is no longer deprecated"}}
+	}
+	return nil
+}
+
+// isSafeOutputChange - Check whether clients reading a value of the old type can read the new type,
+// i.e. the new type is the same or stricter
+func isSafeOutputChange(oldType ast.Type, newType ast.Type) bool {
+	switch oldType := oldType.(type) {
+	case *ast.List:
+		if newList, ok := newType.(*ast.List); ok {
+			return isSafeOutputChange(oldType.Type, newList.Type)
+		}
+		if newNonNull, ok := newType.(*ast.NonNull); ok {
+			return isSafeOutputChange(oldType, newNonNull.Type)
+		}
+		return false
+	case *ast.NonNull:
+		if newNonNull, ok := newType.(*ast.NonNull); ok {
+			return isSafeOutputChange(oldType.Type, newNonNull.Type)
+		}
+		return false
+	case *ast.Named:
+		if newNamed, ok := newType.(*ast.Named); ok {
+			return oldType.Name.Value == newNamed.Name.Value
+		}
+		if newNonNull, ok := newType.(*ast.NonNull); ok {
+			return isSafeOutputChange(oldType, newNonNull.Type)
+		}
+	}
+	return false
+}
+
+// isSafeInputChange - Check whether clients sending a value of the old type can still send it,
+// i.e. the new type is the same or more lenient
+func isSafeInputChange(oldType ast.Type, newType ast.Type) bool {
+	switch oldType := oldType.(type) {
+	case *ast.List:
+		if newList, ok := newType.(*ast.List); ok {
+			return isSafeInputChange(oldType.Type, newList.Type)
+		}
+		return false
+	case *ast.NonNull:
+		if newNonNull, ok := newType.(*ast.NonNull); ok {
+			return isSafeInputChange(oldType.Type, newNonNull.Type)
+		}
+		return isSafeInputChange(oldType.Type, newType)
+	case *ast.Named:
+		if newNamed, ok := newType.(*ast.Named); ok {
+			return oldType.Name.Value == newNamed.Name.Value
+		}
+	}
+	return false
+}
+
+// fieldMap - Index field definitions by name
+func fieldMap(fields []*ast.FieldDefinition) map[string]*ast.FieldDefinition {
+	mapped := map[string]*ast.FieldDefinition{}
+	for _, field := range fields {
+		mapped[field.Name.Value] = field
+	}
+	return mapped
+}
+
+// inputValueMap - Index argument or input field definitions by name
+func inputValueMap(values []*ast.InputValueDefinition) map[string]*ast.InputValueDefinition {
+	mapped := map[string]*ast.InputValueDefinition{}
+	for _, value := range values {
+		mapped[value.Name.Value] = value
+	}
+	return mapped
+}
+
+// isDeprecated - Check whether the directives include @deprecated
+func isDeprecated(directives []*ast.Directive) bool {
+	for _, directive := range directives {
+		if directive.Name != nil && directive.Name.Value == "deprecated" {
+			return true
+		}
+	}
+	return false
+}
+
+// describe - Lookup the text of an optional description
+func describe(description *ast.StringValue) string {
+	if description == nil {
+		return ""
+	}
+	return description.Value
+}
+
+// printNode - Print a type or value node as it would appear in SDL
+func printNode(node ast.Node) string {
+	if node == nil {
+		return ""
+	}
+	printed, _ := printer.Print(node).(string)
+	return printed
+}
+
+// difference - Names found in the first list but not in the second
+func difference(first []string, second []string) []string {
+	found := map[string]bool{}
+	for _, name := range second {
+		found[name] = true
+	}
+	missing := []string{}
+	for _, name := range first {
+		if !found[name] {
+			missing = append(missing, name)
+		}
+	}
+	return missing
+}
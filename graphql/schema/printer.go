@@ -0,0 +1,210 @@
+package schema
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/graphql-go/graphql"
+)
+
+// builtInTypes - types provided by every GraphQL implementation which are not printed
+var builtInTypes = map[string]bool{
+	"String":  true,
+	"Int":     true,
+	"Float":   true,
+	"Boolean": true,
+	"ID":      true,
+}
+
+// PrintSDL - Print the schema in the GraphQL Schema Definition Language, types, fields and
+// arguments are sorted by name so the output is stable between runs
+func PrintSDL(schema graphql.Schema) string {
+	typeMap := schema.TypeMap()
+	names := []string{}
+	for name := range typeMap {
+		if strings.HasPrefix(name, "__") || builtInTypes[name] {
+			continue
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	definitions := []string{}
+	for _, name := range names {
+		definitions = append(definitions, printType(typeMap[name]))
+	}
+
+	return strings.Join(definitions, "\n\n") + "\n"
+}
+
+// printType - Print a single named type definition
+func printType(ttype graphql.Type) string {
+	switch ttype := ttype.(type) {
+	case *graphql.Scalar:
+		return printDescription(ttype.Description(), "") + "scalar " + ttype.Name()
+	case *graphql.Object:
+		implements := ""
+		if len(ttype.Interfaces()) > 0 {
+			interfaceNames := []string{}
+			for _, iface := range ttype.Interfaces() {
+				interfaceNames = append(interfaceNames, iface.Name())
+			}
+			sort.Strings(interfaceNames)
+			implements = " implements " + strings.Join(interfaceNames, " & ")
+		}
+		return printDescription(ttype.Description(), "") +
+			"type " + ttype.Name() + implements + " {\n" + printFields(ttype.Fields()) + "}"
+	case *graphql.Interface:
+		return printDescription(ttype.Description(), "") +
+			"interface " + ttype.Name() + " {\n" + printFields(ttype.Fields()) + "}"
+	case *graphql.Union:
+		memberNames := []string{}
+		for _, member := range ttype.Types() {
+			memberNames = append(memberNames, member.Name())
+		}
+		sort.Strings(memberNames)
+		return printDescription(ttype.Description(), "") +
+			"union " + ttype.Name() + " = " + strings.Join(memberNames, " | ")
+	case *graphql.Enum:
+		values := ttype.Values()
+		sort.Slice(values, func(i, j int) bool {
+			return values[i].Name < values[j].Name
+		})
+		lines := ""
+		for _, value := range values {
+			lines += printDescription(value.Description, "  ") +
+				"  " + value.Name + printDeprecated(value.DeprecationReason) + "\n"
+		}
+		return printDescription(ttype.Description(), "") +
+			"enum " + ttype.Name() + " {\n" + lines + "}"
+	case *graphql.InputObject:
+		fields := ttype.Fields()
+		fieldNames := []string{}
+		for name := range fields {
+			fieldNames = append(fieldNames, name)
+		}
+		sort.Strings(fieldNames)
+		lines := ""
+		for _, name := range fieldNames {
+			field := fields[name]
+			lines += printDescription(field.Description(), "  ") +
+				"  " + printInputValue(name, field.Type, field.DefaultValue) + "\n"
+		}
+		return printDescription(ttype.Description(), "") +
+			"input " + ttype.Name() + " {\n" + lines + "}"
+	}
+	return ""
+}
+
+// printFields - Print the fields of an object or interface, one per line
+func printFields(fields graphql.FieldDefinitionMap) string {
+	fieldNames := []string{}
+	for name := range fields {
+		fieldNames = append(fieldNames, name)
+	}
+	sort.Strings(fieldNames)
+
+	lines := ""
+	for _, name := range fieldNames {
+		field := fields[name]
+		args := ""
+		if len(field.Args) > 0 {
+			sort.Slice(field.Args, func(i, j int) bool {
+				return field.Args[i].Name() < field.Args[j].Name()
+			})
+			printedArgs := []string{}
+			for _, arg := range field.Args {
+				printedArgs = append(printedArgs, printInputValue(arg.Name(), arg.Type, arg.DefaultValue))
+			}
+			args = "(" + strings.Join(printedArgs, ", ") + ")"
+		}
+		lines += printDescription(field.Description, "  ") +
+			"  " + name + args + ": " + field.Type.String() + printDeprecated(field.DeprecationReason) + "\n"
+	}
+	return lines
+}
+
+// printInputValue - Print an argument or input field along with its default value
+func printInputValue(name string, ttype graphql.Input, defaultValue interface{}) string {
+	printed := name + ": " + ttype.String()
+	if defaultValue != nil {
+		printed += " = " + printValue(defaultValue, ttype)
+	}
+	return printed
+}
+
+// printValue - Print a Go value as a GraphQL literal of the specified input type
+func printValue(value interface{}, ttype graphql.Input) string {
+	if nonNull, ok := ttype.(*graphql.NonNull); ok {
+		return printValue(value, nonNull.OfType)
+	}
+
+	switch value := value.(type) {
+	case nil:
+		return "null"
+	case []interface{}:
+		var ofType graphql.Input = ttype
+		if list, ok := ttype.(*graphql.List); ok {
+			ofType = list.OfType
+		}
+		items := []string{}
+		for _, item := range value {
+			items = append(items, printValue(item, ofType))
+		}
+		return "[" + strings.Join(items, ", ") + "]"
+	case map[string]interface{}:
+		keys := []string{}
+		for key := range value {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		fields := []string{}
+		for _, key := range keys {
+			var fieldType graphql.Input = graphql.String
+			if inputObject, ok := ttype.(*graphql.InputObject); ok {
+				if field, ok := inputObject.Fields()[key]; ok {
+					fieldType = field.Type
+				}
+			}
+			fields = append(fields, key+": "+printValue(value[key], fieldType))
+		}
+		return "{" + strings.Join(fields, ", ") + "}"
+	case string:
+		if _, ok := ttype.(*graphql.Enum); ok {
+			return value
+		}
+		quoted, _ := json.Marshal(value)
+		return string(quoted)
+	}
+
+	if enum, ok := ttype.(*graphql.Enum); ok {
+		if serialized, ok := enum.Serialize(value).(string); ok {
+			return serialized
+		}
+	}
+	return fmt.Sprint(value)
+}
+
+// printDescription - Print the description as a block string above a definition
+func printDescription(description string, indent string) string {
+	if len(description) == 0 {
+		return ""
+	}
+	escaped := strings.Replace(description, `"""`, `\"""`, -1)
+	if !strings.Contains(escaped, "\n") {
+		return indent + `"""` + escaped + `"""` + "\n"
+	}
+	lines := strings.Split(escaped, "\n")
+	return indent + `"""` + "\n" + indent + strings.Join(lines, "\n"+indent) + "\n" + indent + `"""` + "\n"
+}
+
+// printDeprecated - Print the deprecated directive if a reason is given
+func printDeprecated(reason string) string {
+	if len(reason) == 0 {
+		return ""
+	}
+	quoted, _ := json.Marshal(reason)
+	return " @deprecated(reason: " + string(quoted) + ")"
+}
@@ -0,0 +1,91 @@
+package schema
+
+import (
+	"database/sql"
+
+	"github.com/doug-martin/goqu/v8"
+	"github.com/graphql-go/graphql"
+
+	"github.com/HencoSmith/graphql-example-go/graphql/movies"
+	"github.com/HencoSmith/graphql-example-go/graphql/node"
+	"github.com/HencoSmith/graphql-example-go/graphql/users"
+	source "github.com/HencoSmith/graphql-example-go/source"
+)
+
+// New - Assemble the GraphQL schema from the queries, mutations and types of every package
+// dialect - Query builder dialect object used
+// db - SQL DB connection to use, may be nil when the schema is only inspected
+func New(dialect goqu.DialectWrapper, db *sql.DB) (graphql.Schema, error) {
+	// Bind type fields which require database access
+	movies.BindFields(dialect, db)
+	users.BindFields(dialect, db)
+
+	// Bind Global Object Identification lookups
+	allNodes := movies.Nodes(dialect, db)
+	userNodes := users.Nodes(dialect, db)
+	for k, v := range userNodes {
+		allNodes[k] = v
+	}
+
+	// Bind Queries
+	allQueries := movies.Queries(dialect, db)
+	userQueries := users.Queries(dialect, db)
+	for k, v := range userQueries {
+		allQueries[k] = v
+	}
+	nodeQueries := node.Queries(dialect, db, allNodes)
+	for k, v := range nodeQueries {
+		allQueries[k] = v
+	}
+
+	var queryType = graphql.NewObject(
+		graphql.ObjectConfig{
+			Name:   "Query",
+			Fields: allQueries,
+		},
+	)
+
+	// Bind Mutations
+	allMutations := movies.Mutations(dialect, db)
+	userMutations := users.Mutations(dialect, db)
+	for k, v := range userMutations {
+		allMutations[k] = v
+	}
+	for name, field := range allMutations {
+		field.Resolve = requireVerified(dialect, db, name, field.Resolve)
+	}
+	var mutationType = graphql.NewObject(
+		graphql.ObjectConfig{
+			Name:   "Mutation",
+			Fields: allMutations,
+		},
+	)
+
+	// Generate the schema
+	return graphql.NewSchema(
+		graphql.SchemaConfig{
+			Query:    queryType,
+			Mutation: mutationType,
+			// Types only reachable through the Node interface
+			Types: []graphql.Type{
+				movies.ReviewType,
+				users.UserType,
+			},
+		},
+	)
+}
+
+// requireVerified - Refuse the mutation for users whose email is unverified unless the mutation is
+// exempt, anonymous requests are left to the authentication of the resolver
+func requireVerified(dialect goqu.DialectWrapper, db *sql.DB, name string, resolve graphql.FieldResolveFn) graphql.FieldResolveFn {
+	return func(params graphql.ResolveParams) (interface{}, error) {
+		if db != nil {
+			if user, viewerErr := source.Viewer(params.Context, dialect, db); viewerErr == nil {
+				if verifiedErr := source.RequireVerified(user, name); verifiedErr != nil {
+					return nil, verifiedErr
+				}
+			}
+		}
+		return resolve(params)
+	}
+}
@@ -0,0 +1,429 @@
+package users
+
+import (
+	"database/sql"
+	"errors"
+	"log"
+
+	"github.com/doug-martin/goqu/v8"
+	"github.com/graphql-go/graphql"
+
+	"github.com/HencoSmith/graphql-example-go/graphql/scalars"
+	"github.com/HencoSmith/graphql-example-go/models"
+	source "github.com/HencoSmith/graphql-example-go/source"
+)
+
+// Mutations - all GraphQL mutations related to users
+func Mutations(dialect goqu.DialectWrapper, db *sql.DB) graphql.Fields {
+	return graphql.Fields{
+		"signup": &graphql.Field{
+			Type:        graphql.String,
+			Description: "Create a new account and email a verification link to it. Returns 'success' whether or not the email is already registered, its owner is notified instead",
+			Args: graphql.FieldConfigArgument{
+				"email": &graphql.ArgumentConfig{
+					Type: graphql.NewNonNull(graphql.String),
+				},
+				"password": &graphql.ArgumentConfig{
+					Type: graphql.NewNonNull(graphql.String),
+				},
+			},
+			Resolve: func(params graphql.ResolveParams) (interface{}, error) {
+				email, _ := params.Args["email"].(string)
+				password, _ := params.Args["password"].(string)
+
+				if signupErr := source.Signup(dialect, db, email, password); signupErr != nil {
+					return "failure", signupErr
+				}
+
+				return "success", nil
+			},
+		},
+
+		"verifyEmail": &graphql.Field{
+			Type:        graphql.String,
+			Description: "Verify the email address using the token of a verification link. Returns 'success' / 'failure'",
+			Args: graphql.FieldConfigArgument{
+				"token": &graphql.ArgumentConfig{
+					Type: graphql.NewNonNull(graphql.String),
+				},
+			},
+			Resolve: func(params graphql.ResolveParams) (interface{}, error) {
+				token, _ := params.Args["token"].(string)
+
+				if verifyErr := source.VerifyEmail(dialect, db, token); verifyErr != nil {
+					return "failure", verifyErr
+				}
+
+				return "success", nil
+			},
+		},
+
+		"resendVerificationEmail": &graphql.Field{
+			Type:        graphql.String,
+			Description: "Email a new verification link to the current user. Returns 'success' / 'failure'",
+			Resolve: func(params graphql.ResolveParams) (interface{}, error) {
+				user, customError := source.GetUserFromToken(params.Context, dialect, db)
+				if customError != nil {
+					return "failure", customError
+				}
+				if scopeErr := source.RequireScope(user, source.ScopeAccount); scopeErr != nil {
+					return "failure", scopeErr
+				}
+
+				if user.VerifiedAt != nil {
+					return "failure", errors.New("Email address is already verified")
+				}
+
+				if verifyErr := source.RequestEmailVerification(dialect, db, user); verifyErr != nil {
+					return "failure", verifyErr
+				}
+
+				return "success", nil
+			},
+		},
+
+		"enrollTwoFactor": &graphql.Field{
+			Type:        TwoFactorEnrollmentType,
+			Description: "Start two-factor authentication enrollment, replacing any pending secret",
+			Resolve: func(params graphql.ResolveParams) (interface{}, error) {
+				user, customError := source.GetUserFromToken(params.Context, dialect, db)
+				if customError != nil {
+					return nil, customError
+				}
+				if scopeErr := source.RequireScope(user, source.ScopeAccount); scopeErr != nil {
+					return nil, scopeErr
+				}
+
+				secret, uri, enrollErr := source.EnrollTOTP(dialect, db, user)
+				if enrollErr != nil {
+					return nil, enrollErr
+				}
+
+				return map[string]interface{}{
+					"secret": secret,
+					"uri":    uri,
+				}, nil
+			},
+		},
+
+		"confirmTwoFactor": &graphql.Field{
+			Type:        graphql.NewList(graphql.String),
+			Description: "Enable two-factor authentication with a code of the enrolled secret. Returns recovery codes, which are only shown once",
+			Args: graphql.FieldConfigArgument{
+				"code": &graphql.ArgumentConfig{
+					Type: graphql.NewNonNull(graphql.String),
+				},
+			},
+			Resolve: func(params graphql.ResolveParams) (interface{}, error) {
+				user, customError := source.GetUserFromToken(params.Context, dialect, db)
+				if customError != nil {
+					return nil, customError
+				}
+				if scopeErr := source.RequireScope(user, source.ScopeAccount); scopeErr != nil {
+					return nil, scopeErr
+				}
+
+				code, _ := params.Args["code"].(string)
+				return source.ConfirmTOTP(dialect, db, user, code)
+			},
+		},
+
+		"regenerateRecoveryCodes": &graphql.Field{
+			Type:        graphql.NewList(graphql.String),
+			Description: "Replace the recovery codes after verifying a TOTP or recovery code. Returns the new codes, which are only shown once",
+			Args: graphql.FieldConfigArgument{
+				"code": &graphql.ArgumentConfig{
+					Type: graphql.NewNonNull(graphql.String),
+				},
+			},
+			Resolve: func(params graphql.ResolveParams) (interface{}, error) {
+				user, customError := source.GetUserFromToken(params.Context, dialect, db)
+				if customError != nil {
+					return nil, customError
+				}
+				if scopeErr := source.RequireScope(user, source.ScopeAccount); scopeErr != nil {
+					return nil, scopeErr
+				}
+
+				code, _ := params.Args["code"].(string)
+				return source.RegenerateRecoveryCodes(dialect, db, user, code)
+			},
+		},
+
+		"disableTwoFactor": &graphql.Field{
+			Type:        graphql.String,
+			Description: "Disable two-factor authentication after verifying a TOTP or recovery code. Returns 'success' / 'failure'",
+			Args: graphql.FieldConfigArgument{
+				"code": &graphql.ArgumentConfig{
+					Type: graphql.NewNonNull(graphql.String),
+				},
+			},
+			Resolve: func(params graphql.ResolveParams) (interface{}, error) {
+				user, customError := source.GetUserFromToken(params.Context, dialect, db)
+				if customError != nil {
+					return "failure", customError
+				}
+				if scopeErr := source.RequireScope(user, source.ScopeAccount); scopeErr != nil {
+					return "failure", scopeErr
+				}
+
+				code, _ := params.Args["code"].(string)
+				if disableErr := source.DisableTOTP(dialect, db, user, code); disableErr != nil {
+					return "failure", disableErr
+				}
+
+				return "success", nil
+			},
+		},
+
+		"updateProfile": &graphql.Field{
+			Type:        UserType,
+			Description: "Set the profile of the current user, omitted fields are left unchanged",
+			Args: graphql.FieldConfigArgument{
+				"displayName": &graphql.ArgumentConfig{
+					Type: graphql.String,
+				},
+				"avatarURL": &graphql.ArgumentConfig{
+					Type: graphql.String,
+				},
+			},
+			Resolve: func(params graphql.ResolveParams) (interface{}, error) {
+				user, customError := source.GetUserFromToken(params.Context, dialect, db)
+				if customError != nil {
+					return nil, customError
+				}
+				if scopeErr := source.RequireScope(user, source.ScopeAccount); scopeErr != nil {
+					return nil, scopeErr
+				}
+
+				var displayName, avatarURL *string
+				if value, ok := params.Args["displayName"].(string); ok {
+					displayName = &value
+				}
+				if value, ok := params.Args["avatarURL"].(string); ok {
+					avatarURL = &value
+				}
+
+				return source.UpdateProfile(dialect, db, user, displayName, avatarURL)
+			},
+		},
+
+		"changeEmail": &graphql.Field{
+			Type:        UserType,
+			Description: "Request a change of the email of the current user, it is replaced once the link mailed to the new address is followed",
+			Args: graphql.FieldConfigArgument{
+				"email": &graphql.ArgumentConfig{
+					Type: graphql.NewNonNull(graphql.String),
+				},
+				"password": &graphql.ArgumentConfig{
+					Type: graphql.NewNonNull(graphql.String),
+				},
+			},
+			Resolve: func(params graphql.ResolveParams) (interface{}, error) {
+				user, customError := source.GetUserFromToken(params.Context, dialect, db)
+				if customError != nil {
+					return nil, customError
+				}
+				if scopeErr := source.RequireScope(user, source.ScopeAccount); scopeErr != nil {
+					return nil, scopeErr
+				}
+
+				email, _ := params.Args["email"].(string)
+				password, _ := params.Args["password"].(string)
+				ip, _ := params.Context.Value(models.ContextKey{Key: "ip"}).(string)
+				return source.ChangeEmail(dialect, db, user, password, email, ip)
+			},
+		},
+
+		"changePassword": &graphql.Field{
+			Type:        graphql.String,
+			Description: "Change the password of the current user. Returns 'success' / 'failure'",
+			Args: graphql.FieldConfigArgument{
+				"oldPassword": &graphql.ArgumentConfig{
+					Type: graphql.NewNonNull(graphql.String),
+				},
+				"newPassword": &graphql.ArgumentConfig{
+					Type: graphql.NewNonNull(graphql.String),
+				},
+			},
+			Resolve: func(params graphql.ResolveParams) (interface{}, error) {
+				user, customError := source.GetUserFromToken(params.Context, dialect, db)
+				if customError != nil {
+					return "failure", customError
+				}
+				if scopeErr := source.RequireScope(user, source.ScopeAccount); scopeErr != nil {
+					return "failure", scopeErr
+				}
+
+				oldPassword, _ := params.Args["oldPassword"].(string)
+				newPassword, _ := params.Args["newPassword"].(string)
+				ip, _ := params.Context.Value(models.ContextKey{Key: "ip"}).(string)
+				if changeErr := source.ChangePassword(dialect, db, user, oldPassword, newPassword, ip); changeErr != nil {
+					return "failure", changeErr
+				}
+
+				return "success", nil
+			},
+		},
+
+		"deleteMyAccount": &graphql.Field{
+			Type:        graphql.String,
+			Description: "Delete the account of the current user, confirmed with the password, a two-factor code or an OpenID Connect login within the last 5 minutes. Returns 'success' / 'failure'",
+			Args: graphql.FieldConfigArgument{
+				"password": &graphql.ArgumentConfig{
+					Type: graphql.String,
+				},
+				"code": &graphql.ArgumentConfig{
+					Type:        graphql.String,
+					Description: "TOTP or recovery code, if two-factor authentication is enabled",
+				},
+			},
+			Resolve: func(params graphql.ResolveParams) (interface{}, error) {
+				user, customError := source.GetUserFromToken(params.Context, dialect, db)
+				if customError != nil {
+					return "failure", customError
+				}
+				if scopeErr := source.RequireScope(user, source.ScopeAccount); scopeErr != nil {
+					return "failure", scopeErr
+				}
+
+				password, _ := params.Args["password"].(string)
+				code, _ := params.Args["code"].(string)
+				if confirmErr := source.ConfirmIdentity(params.Context, dialect, db, user, password, code); confirmErr != nil {
+					return "failure", confirmErr
+				}
+
+				if deleteErr := source.DeleteAccount(dialect, db, user); deleteErr != nil {
+					return "failure", deleteErr
+				}
+
+				return "success", nil
+			},
+		},
+
+		"createApiKey": &graphql.Field{
+			Type:        CreatedAPIKeyType,
+			Description: "Create an API key for machine-to-machine access with the scopes 'read' and/or 'write'",
+			Args: graphql.FieldConfigArgument{
+				"name": &graphql.ArgumentConfig{
+					Type: graphql.NewNonNull(graphql.String),
+				},
+				"scopes": &graphql.ArgumentConfig{
+					Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(graphql.String))),
+				},
+			},
+			Resolve: func(params graphql.ResolveParams) (interface{}, error) {
+				user, customError := source.GetUserFromToken(params.Context, dialect, db)
+				if customError != nil {
+					return nil, customError
+				}
+				if scopeErr := source.RequireScope(user, source.ScopeAccount); scopeErr != nil {
+					return nil, scopeErr
+				}
+
+				name, _ := params.Args["name"].(string)
+				scopes := []string{}
+				scopeArgs, _ := params.Args["scopes"].([]interface{})
+				for _, scopeArg := range scopeArgs {
+					if scope, ok := scopeArg.(string); ok {
+						scopes = append(scopes, scope)
+					}
+				}
+
+				apiKey, key, createErr := source.CreateAPIKey(dialect, db, user, name, scopes)
+				if createErr != nil {
+					return nil, createErr
+				}
+
+				return map[string]interface{}{
+					"key":    key,
+					"apiKey": apiKey,
+				}, nil
+			},
+		},
+
+		"revokeApiKey": &graphql.Field{
+			Type:        graphql.String,
+			Description: "Revoke an API key of the current user. Returns 'success' / 'failure'",
+			Args: graphql.FieldConfigArgument{
+				"id": &graphql.ArgumentConfig{
+					Type: graphql.NewNonNull(scalars.UUID),
+				},
+			},
+			Resolve: func(params graphql.ResolveParams) (interface{}, error) {
+				user, customError := source.GetUserFromToken(params.Context, dialect, db)
+				if customError != nil {
+					return "failure", customError
+				}
+				if scopeErr := source.RequireScope(user, source.ScopeAccount); scopeErr != nil {
+					return "failure", scopeErr
+				}
+
+				id, _ := params.Args["id"].(string)
+				if revokeErr := source.RevokeAPIKey(dialect, db, user.ID, id); revokeErr != nil {
+					return "failure", revokeErr
+				}
+
+				return "success", nil
+			},
+		},
+
+		"requestPasswordReset": &graphql.Field{
+			Type:        graphql.String,
+			Description: "Email a password reset link to the user. Returns 'success' whether or not the email exists",
+			Args: graphql.FieldConfigArgument{
+				"email": &graphql.ArgumentConfig{
+					Type: graphql.NewNonNull(graphql.String),
+				},
+			},
+			Resolve: func(params graphql.ResolveParams) (interface{}, error) {
+				email, _ := params.Args["email"].(string)
+				if len(email) < 2 {
+					return "success", nil
+				}
+
+				user, err := source.GetUser(dialect, db, "", email)
+				if err == source.ErrUserNotFound {
+					return "success", nil
+				}
+				if err != nil {
+					return "failure", err
+				}
+
+				// The link is stored and mailed in the background so the response takes as long as
+				// for unknown emails, delivery problems are not reported to avoid revealing which
+				// emails exist
+				go func() {
+					if resetErr := source.RequestPasswordReset(dialect, db, user); resetErr != nil {
+						log.Println("password reset failed:", resetErr)
+					}
+				}()
+
+				return "success", nil
+			},
+		},
+
+		"resetPassword": &graphql.Field{
+			Type:        graphql.String,
+			Description: "Set a new password using the token of a reset link. Returns 'success' / 'failure'",
+			Args: graphql.FieldConfigArgument{
+				"token": &graphql.ArgumentConfig{
+					Type: graphql.NewNonNull(graphql.String),
+				},
+				"newPassword": &graphql.ArgumentConfig{
+					Type: graphql.NewNonNull(graphql.String),
+				},
+			},
+			Resolve: func(params graphql.ResolveParams) (interface{}, error) {
+				token, _ := params.Args["token"].(string)
+				newPassword, _ := params.Args["newPassword"].(string)
+
+				if resetErr := source.ResetPassword(dialect, db, token, newPassword); resetErr != nil {
+					return "failure", resetErr
+				}
+
+				return "success", nil
+			},
+		},
+	}
+}
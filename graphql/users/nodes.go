@@ -0,0 +1,27 @@
+package users
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/doug-martin/goqu/v8"
+
+	"github.com/HencoSmith/graphql-example-go/graphql/node"
+	source "github.com/HencoSmith/graphql-example-go/source"
+)
+
+// Nodes - lookup functions for the user related types addressable by global ID
+func Nodes(dialect goqu.DialectWrapper, db *sql.DB) map[string]node.Fetcher {
+	return map[string]node.Fetcher{
+		"User": func(ctx context.Context, id string) (interface{}, error) {
+			user, err := source.GetUser(dialect, db, id, "")
+			if err == source.ErrUserNotFound {
+				return nil, nil
+			}
+			if err != nil {
+				return nil, err
+			}
+			return &user, nil
+		},
+	}
+}
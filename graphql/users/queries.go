@@ -2,20 +2,23 @@ package users
 
 import (
 	"database/sql"
-	"errors"
+	"encoding/json"
+	"log"
+	"time"
 
 	"github.com/doug-martin/goqu/v8"
 	"github.com/graphql-go/graphql"
 
+	"github.com/HencoSmith/graphql-example-go/models"
 	source "github.com/HencoSmith/graphql-example-go/source"
 )
 
-// Queries - all GraphQL queries related to movies
+// Queries - all GraphQL queries related to users
 func Queries(dialect goqu.DialectWrapper, db *sql.DB) graphql.Fields {
 	return graphql.Fields{
 		"getToken": &graphql.Field{
-			Type:        graphql.String,
-			Description: "Return a JWT for the specified user",
+			Type:        TokenResultType,
+			Description: "Return a JWT for the specified user, or a challenge to exchange using verifyTwoFactor if two-factor authentication is enabled",
 			Args: graphql.FieldConfigArgument{
 				"email": &graphql.ArgumentConfig{
 					Type: graphql.String,
@@ -27,24 +30,176 @@ func Queries(dialect goqu.DialectWrapper, db *sql.DB) graphql.Fields {
 			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
 				email, _ := p.Args["email"].(string)
 				password, _ := p.Args["password"].(string)
+				ip, _ := p.Context.Value(models.ContextKey{Key: "ip"}).(string)
+
+				// Refuse locked accounts and slow down repeated failures
+				delay, lockErr := source.CheckLogin(dialect, db, email, ip)
+				if lockErr != nil {
+					return nil, lockErr
+				}
+				time.Sleep(delay)
 
-				// Lookup encrypted password from DB
-				user, err := source.GetUser(dialect, db, "", email)
+				// Lookup encrypted password from DB (too short emails would be looked up by ID)
+				user, err := models.User{}, source.ErrUserNotFound
+				if len(email) > 1 {
+					user, err = source.GetUser(dialect, db, "", email)
+				}
+				if err == source.ErrUserNotFound {
+					// Spend the same time hashing as for an existing user
+					source.DummyValidHash(password)
+					if recordErr := source.RecordLoginFailure(dialect, db, email, ip); recordErr != nil {
+						return nil, recordErr
+					}
+					return nil, source.ErrInvalidCredentials
+				}
 				if err != nil {
 					return nil, err
 				}
 
-				valid, err := source.ValidHash(password, user.EncryptedPassword)
+				valid, _ := source.ValidHash(password, user.EncryptedPassword)
+				if !valid {
+					if recordErr := source.RecordLoginFailure(dialect, db, email, ip); recordErr != nil {
+						return nil, recordErr
+					}
+					return nil, source.ErrInvalidCredentials
+				}
+
+				// Upgrade hashes using outdated parameters while the password is known
+				if rehashErr := source.RehashPassword(dialect, db, user, password); rehashErr != nil {
+					log.Println("password rehash failed:", rehashErr)
+				}
+
+				// Failures are only forgotten once the second factor was verified as well
+				if user.TOTPEnabledAt != nil {
+					challenge, challengeErr := source.CreateChallengeJWT(user.ID)
+					if challengeErr != nil {
+						return nil, challengeErr
+					}
+					return map[string]interface{}{
+						"challenge":    challenge,
+						"totpRequired": true,
+					}, nil
+				}
+
+				if clearErr := source.ClearLoginFailures(dialect, db, email); clearErr != nil {
+					return nil, clearErr
+				}
+
+				token, tokenErr := source.CreateJWT(user.ID)
+				if tokenErr != nil {
+					return nil, tokenErr
+				}
+				return map[string]interface{}{
+					"token":        token,
+					"totpRequired": false,
+				}, nil
+			},
+		},
+
+		"verifyTwoFactor": &graphql.Field{
+			Type:        graphql.String,
+			Description: "Exchange the challenge returned by getToken and a TOTP or recovery code for a JWT",
+			Args: graphql.FieldConfigArgument{
+				"challenge": &graphql.ArgumentConfig{
+					Type: graphql.NewNonNull(graphql.String),
+				},
+				"code": &graphql.ArgumentConfig{
+					Type: graphql.NewNonNull(graphql.String),
+				},
+			},
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				challenge, _ := p.Args["challenge"].(string)
+				code, _ := p.Args["code"].(string)
+				ip, _ := p.Context.Value(models.ContextKey{Key: "ip"}).(string)
+
+				userID, challengeErr := source.DecodeChallengeJWT(challenge)
+				if challengeErr != nil {
+					return nil, challengeErr
+				}
+
+				user, err := source.GetUser(dialect, db, userID, "")
 				if err != nil {
 					return nil, err
 				}
 
-				if !valid {
-					return nil, errors.New("User Not Found")
+				// Wrong codes count towards the lockout of the account
+				delay, lockErr := source.CheckLogin(dialect, db, user.Email, ip)
+				if lockErr != nil {
+					return nil, lockErr
+				}
+				time.Sleep(delay)
+
+				if verifyErr := source.VerifySecondFactor(dialect, db, user, code); verifyErr != nil {
+					if recordErr := source.RecordLoginFailure(dialect, db, user.Email, ip); recordErr != nil {
+						return nil, recordErr
+					}
+					return nil, verifyErr
+				}
+
+				if clearErr := source.ClearLoginFailures(dialect, db, user.Email); clearErr != nil {
+					return nil, clearErr
 				}
 
 				return source.CreateJWT(user.ID)
 			},
 		},
+
+		"me": &graphql.Field{
+			Type:        UserType,
+			Description: "Get the authenticated user",
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				user, customError := source.GetUserFromToken(p.Context, dialect, db)
+				if customError != nil {
+					return nil, customError
+				}
+				if scopeErr := source.RequireScope(user, source.ScopeRead); scopeErr != nil {
+					return nil, scopeErr
+				}
+
+				return user, nil
+			},
+		},
+
+		"exportMyData": &graphql.Field{
+			Type:        graphql.String,
+			Description: "Export everything stored about the current user as a JSON document",
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				user, customError := source.GetUserFromToken(p.Context, dialect, db)
+				if customError != nil {
+					return nil, customError
+				}
+				if scopeErr := source.RequireScope(user, source.ScopeAccount); scopeErr != nil {
+					return nil, scopeErr
+				}
+
+				archive, exportErr := source.ExportUserData(dialect, db, user)
+				if exportErr != nil {
+					return nil, exportErr
+				}
+
+				exported, marshalErr := json.Marshal(archive)
+				if marshalErr != nil {
+					return nil, marshalErr
+				}
+
+				return string(exported), nil
+			},
+		},
+
+		"listApiKeys": &graphql.Field{
+			Type:        graphql.NewList(APIKeyType),
+			Description: "List the API keys of the current user, including revoked keys",
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				user, customError := source.GetUserFromToken(p.Context, dialect, db)
+				if customError != nil {
+					return nil, customError
+				}
+				if scopeErr := source.RequireScope(user, source.ScopeAccount); scopeErr != nil {
+					return nil, scopeErr
+				}
+
+				return source.ListAPIKeys(dialect, db, user.ID)
+			},
+		},
 	}
 }
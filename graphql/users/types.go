@@ -0,0 +1,184 @@
+package users
+
+import (
+	"database/sql"
+
+	"github.com/doug-martin/goqu/v8"
+	"github.com/graphql-go/graphql"
+
+	"github.com/HencoSmith/graphql-example-go/graphql/node"
+	"github.com/HencoSmith/graphql-example-go/graphql/scalars"
+	"github.com/HencoSmith/graphql-example-go/models"
+	source "github.com/HencoSmith/graphql-example-go/source"
+)
+
+// UserType - Entries found in the users table, excluding credentials
+var UserType = graphql.NewObject(
+	graphql.ObjectConfig{
+		Name:       "User",
+		Interfaces: []*graphql.Interface{node.Interface},
+		IsTypeOf: func(p graphql.IsTypeOfParams) bool {
+			switch p.Value.(type) {
+			case models.User, *models.User:
+				return true
+			}
+			return false
+		},
+		Fields: graphql.Fields{
+			"id": node.GlobalIDField("User"),
+			"uuid": &graphql.Field{
+				Type:    scalars.UUID,
+				Resolve: node.LocalID,
+			},
+			"created_at": &graphql.Field{
+				Type: scalars.DateTime,
+			},
+			"updated_at": &graphql.Field{
+				Type: scalars.DateTime,
+			},
+			"display_name": &graphql.Field{
+				Type: graphql.String,
+			},
+			"avatar_url": &graphql.Field{
+				Type: graphql.String,
+			},
+			"role": &graphql.Field{
+				Type:        graphql.String,
+				Description: "'user' or 'admin'",
+			},
+		},
+	},
+)
+
+// TwoFactorEnrollmentType - Pending TOTP secret to be added to an authenticator app
+var TwoFactorEnrollmentType = graphql.NewObject(
+	graphql.ObjectConfig{
+		Name: "TwoFactorEnrollment",
+		Fields: graphql.Fields{
+			"secret": &graphql.Field{
+				Type:        graphql.String,
+				Description: "Base32 encoded secret for manual entry",
+			},
+			"uri": &graphql.Field{
+				Type:        graphql.String,
+				Description: "otpauth URI, usually shown as a QR code",
+			},
+		},
+	},
+)
+
+// TokenResultType - Result of a login, either a JWT or a challenge requiring a second factor
+var TokenResultType = graphql.NewObject(
+	graphql.ObjectConfig{
+		Name: "TokenResult",
+		Fields: graphql.Fields{
+			"token": &graphql.Field{
+				Type:        graphql.String,
+				Description: "JWT to send as the authorization header, null if a second factor is required",
+			},
+			"challenge": &graphql.Field{
+				Type:        graphql.String,
+				Description: "Challenge to exchange using verifyTwoFactor, null if no second factor is required",
+			},
+			"totpRequired": &graphql.Field{
+				Type:        graphql.Boolean,
+				Description: "Whether a TOTP or recovery code is required to complete the login",
+			},
+		},
+	},
+)
+
+// APIKeyType - API keys of the current user, the key itself is only returned on creation
+var APIKeyType = graphql.NewObject(
+	graphql.ObjectConfig{
+		Name: "ApiKey",
+		Fields: graphql.Fields{
+			"id": &graphql.Field{
+				Type: scalars.UUID,
+			},
+			"created_at": &graphql.Field{
+				Type: scalars.DateTime,
+			},
+			"name": &graphql.Field{
+				Type: graphql.String,
+			},
+			"prefix": &graphql.Field{
+				Type:        graphql.String,
+				Description: "Leading characters of the key to tell keys apart",
+			},
+			"scopes": &graphql.Field{
+				Type: graphql.NewList(graphql.String),
+			},
+			"last_used_at": &graphql.Field{
+				Type: scalars.DateTime,
+			},
+			"revoked_at": &graphql.Field{
+				Type: scalars.DateTime,
+			},
+		},
+	},
+)
+
+// CreatedAPIKeyType - A new API key along with the key itself, which is only shown once
+var CreatedAPIKeyType = graphql.NewObject(
+	graphql.ObjectConfig{
+		Name: "CreatedApiKey",
+		Fields: graphql.Fields{
+			"key": &graphql.Field{
+				Type:        graphql.String,
+				Description: "Send as the authorization header, it cannot be retrieved again",
+			},
+			"apiKey": &graphql.Field{
+				Type: APIKeyType,
+			},
+		},
+	},
+)
+
+// privateUserField - Field of the source user only resolved for the user themselves and admins,
+// null for everyone else
+func privateUserField(dialect goqu.DialectWrapper, db *sql.DB, field *graphql.Field) *graphql.Field {
+	resolve := field.Resolve
+	if resolve == nil {
+		resolve = graphql.DefaultResolveFn
+	}
+	field.Description = "Only visible to the user and admins"
+	field.Resolve = func(p graphql.ResolveParams) (interface{}, error) {
+		id, err := node.LocalID(p)
+		if err != nil {
+			return nil, err
+		}
+
+		// Anonymous requests such as signup see no private fields
+		viewer, viewerErr := source.Viewer(p.Context, dialect, db)
+		usersID, _ := id.(string)
+		if viewerErr != nil || source.RequireOwnerOrAdmin(viewer, usersID) != nil {
+			return nil, nil
+		}
+
+		return resolve(p)
+	}
+	return field
+}
+
+// BindFields - Add the fields of the user types which require database access
+func BindFields(dialect goqu.DialectWrapper, db *sql.DB) {
+	UserType.AddFieldConfig("email", privateUserField(dialect, db, &graphql.Field{
+		Type: graphql.String,
+	}))
+	UserType.AddFieldConfig("verified_at", privateUserField(dialect, db, &graphql.Field{
+		Type: scalars.DateTime,
+	}))
+	UserType.AddFieldConfig("two_factor_enabled", privateUserField(dialect, db, &graphql.Field{
+		Type: graphql.Boolean,
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			switch user := p.Source.(type) {
+			case models.User:
+				return user.TOTPEnabledAt != nil, nil
+			case *models.User:
+				return user.TOTPEnabledAt != nil, nil
+			}
+			return false, nil
+		},
+	}))
+}
@@ -2,9 +2,16 @@ package main
 
 import (
 	"context"
+	"database/sql"
+	"encoding/json"
 	"fmt"
 	"log"
+	"math"
 	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
 	"time"
 
 	"gopkg.in/tylerb/graceful.v1"
@@ -17,20 +24,297 @@ import (
 	"github.com/doug-martin/goqu/v8"
 	_ "github.com/doug-martin/goqu/v8/dialect/postgres"
 
-	"github.com/HencoSmith/graphql-example-go/graphql/movies"
-	"github.com/HencoSmith/graphql-example-go/graphql/users"
+	configStruct "github.com/HencoSmith/graphql-example-go/config/struct"
+	gqlschema "github.com/HencoSmith/graphql-example-go/graphql/schema"
 	"github.com/HencoSmith/graphql-example-go/models"
 	source "github.com/HencoSmith/graphql-example-go/source"
 )
 
-// ContextMiddleware - Adds HTTP header to GraphQL context
-func ContextMiddleware(next *handler.Handler) http.Handler {
+// requestContext - Context of the request holding the HTTP header, client IP address and the
+// viewer looked up once, as expected by the resolvers
+func requestContext(req *http.Request, trustProxy bool) context.Context {
+	ctx := context.WithValue(req.Context(), models.ContextKey{Key: "header"}, req.Header)
+	ctx = context.WithValue(ctx, models.ContextKey{Key: "ip"}, source.ClientIP(req, trustProxy))
+	return source.WithViewer(ctx)
+}
+
+// ContextMiddleware - Adds HTTP header and client IP address to GraphQL context, multipart
+// requests carrying file uploads are executed here as the handler does not support them
+func ContextMiddleware(trustProxy bool, schema *graphql.Schema, next *handler.Handler) http.Handler {
 	return http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
-		ctx := context.WithValue(req.Context(), models.ContextKey{Key: "header"}, req.Header)
+		ctx := requestContext(req, trustProxy)
+		if source.IsMultipart(req) {
+			MultipartHandler(ctx, schema, res, req)
+			return
+		}
 		next.ContextHandler(ctx, res, req)
 	})
 }
 
+// MultipartHandler - Executes a GraphQL multipart request, the uploaded files are passed as
+// variables of the Upload type
+func MultipartHandler(ctx context.Context, schema *graphql.Schema, res http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		writeJSONError(res, http.StatusMethodNotAllowed, "Multipart requests have to be sent as POST")
+		return
+	}
+
+	options, closeUploads, parseErr := source.ParseMultipartRequest(req)
+	if req.MultipartForm != nil {
+		// Remove the files buffered on disk
+		defer req.MultipartForm.RemoveAll()
+	}
+	// Close the files before they are removed
+	defer closeUploads()
+	if parseErr != nil {
+		writeJSONError(res, http.StatusBadRequest, parseErr.Error())
+		return
+	}
+
+	result := graphql.Do(graphql.Params{
+		Schema:         *schema,
+		RequestString:  options.Query,
+		VariableValues: options.Variables,
+		OperationName:  options.OperationName,
+		Context:        ctx,
+	})
+
+	res.Header().Set("Content-Type", "application/json; charset=utf-8")
+	json.NewEncoder(res).Encode(result)
+}
+
+// RateLimitMiddleware - Limits the request rate per authenticated user or anonymous IP address,
+// sensitive operations are additionally limited by their own budget. Request bodies larger than
+// the limit are refused before they are read, 0 allows any size
+func RateLimitMiddleware(dialect goqu.DialectWrapper, db *sql.DB, rateConfig configStruct.RateLimitConfiguration, bodyLimit int64, next http.Handler) http.Handler {
+	addresses := source.NewRateLimiter(rateConfig.Authenticated)
+	anonymous := source.NewRateLimiter(rateConfig.Anonymous)
+	authenticated := source.NewRateLimiter(rateConfig.Authenticated)
+	operations := map[string]*source.RateLimiter{}
+	for name, bucket := range rateConfig.Operations {
+		// Configuration keys are case insensitive
+		operations[strings.ToLower(name)] = source.NewRateLimiter(bucket)
+	}
+
+	return http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		if bodyLimit > 0 {
+			if req.ContentLength > bodyLimit {
+				writeJSONError(res, http.StatusRequestEntityTooLarge, source.ErrBodyTooLarge.Error())
+				return
+			}
+			req.Body = http.MaxBytesReader(res, req.Body, bodyLimit)
+		}
+
+		// Addresses are limited before tokens are verified so invalid tokens are not checked
+		// at any rate, an address is allowed as many requests as a single user
+		key := "ip:" + source.ClientIP(req, rateConfig.TrustProxy)
+		allowed, retryAfter := addresses.Allow(key)
+		limit := "request"
+
+		// Identify the client by the user of the JWT or API key, falling back to the IP address
+		if allowed {
+			limiter := anonymous
+			if token := req.Header.Get("Authorization"); len(token) > 0 {
+				if userID, err := source.TokenUserID(dialect, db, token); err == nil {
+					key = "user:" + userID
+					limiter = authenticated
+				}
+			}
+			allowed, retryAfter = limiter.Allow(key)
+		}
+
+		if allowed {
+			fields, fieldsErr := source.RequestFields(req)
+			if fieldsErr != nil {
+				writeJSONError(res, http.StatusRequestEntityTooLarge, source.ErrBodyTooLarge.Error())
+				return
+			}
+			for _, field := range fields {
+				operation, ok := operations[strings.ToLower(field)]
+				if !ok {
+					continue
+				}
+				if allowed, retryAfter = operation.Allow(key); !allowed {
+					limit = field
+					break
+				}
+			}
+		}
+
+		if !allowed {
+			seconds := int(math.Ceil(retryAfter.Seconds()))
+			res.Header().Set("Content-Type", "application/json; charset=utf-8")
+			res.Header().Set("Retry-After", strconv.Itoa(seconds))
+			res.WriteHeader(http.StatusTooManyRequests)
+			json.NewEncoder(res).Encode(map[string]interface{}{
+				"data": nil,
+				"errors": []map[string]interface{}{
+					{
+						"message": "Too many requests, retry after " + strconv.Itoa(seconds) + " seconds",
+						"extensions": map[string]interface{}{
+							"code":       "RATE_LIMITED",
+							"limit":      limit,
+							"retryAfter": seconds,
+						},
+					},
+				},
+			})
+			return
+		}
+
+		next.ServeHTTP(res, req)
+	})
+}
+
+// maxRequestBody - largest body accepted by endpoints other than /graphql, which expect none
+const maxRequestBody = 1 << 20
+
+// oidcStateCookie - cookie binding an OpenID Connect callback to the browser which started the login
+const oidcStateCookie = "oidc_state"
+
+// writeJSONError - Respond with the status and an error message
+func writeJSONError(res http.ResponseWriter, status int, message string) {
+	res.Header().Set("Content-Type", "application/json; charset=utf-8")
+	res.WriteHeader(status)
+	json.NewEncoder(res).Encode(map[string]interface{}{
+		"error": message,
+	})
+}
+
+// ExportHandler - Streams a dataset as a file named by the path e.g. /export/movies.csv, movies
+// and reviews are filtered by the genre and tag query parameters like the movie list
+func ExportHandler(dialect goqu.DialectWrapper, db *sql.DB, trustProxy bool) http.Handler {
+	return http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		if req.Method != http.MethodGet {
+			writeJSONError(res, http.StatusMethodNotAllowed, "Exports have to be requested using GET")
+			return
+		}
+
+		user, userErr := source.GetUserFromToken(requestContext(req, trustProxy), dialect, db)
+		if userErr != nil {
+			writeJSONError(res, http.StatusUnauthorized, userErr.Error())
+			return
+		}
+		if scopeErr := source.RequireScope(user, source.ScopeRead); scopeErr != nil {
+			writeJSONError(res, http.StatusForbidden, scopeErr.Error())
+			return
+		}
+
+		filename := path.Base(req.URL.Path)
+		format := strings.TrimPrefix(path.Ext(filename), ".")
+		dataset := strings.TrimSuffix(filename, path.Ext(filename))
+		if exportErr := source.CheckExport(user, dataset, format); exportErr != nil {
+			status := http.StatusNotFound
+			if exportErr == source.ErrForbidden {
+				status = http.StatusForbidden
+			}
+			writeJSONError(res, status, exportErr.Error())
+			return
+		}
+
+		res.Header().Set("Content-Type", source.ExportContentTypes[format])
+		res.Header().Set("Content-Disposition", "attachment; filename=\""+filename+"\"")
+		query := req.URL.Query()
+		if exportErr := source.ExportData(dialect, db, dataset, format, query.Get("genre"), query.Get("tag"), res); exportErr != nil {
+			// The response has started, the client is left with an incomplete file
+			log.Println("export failed:", exportErr)
+		}
+	})
+}
+
+// OIDCLoginHandler - Redirects the browser to the OpenID Connect provider to login
+func OIDCLoginHandler(dialect goqu.DialectWrapper, db *sql.DB, oidcConfig configStruct.OIDCConfiguration, provider *source.OIDCProvider) http.Handler {
+	return http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		state, authURL, err := source.StartOIDCLogin(dialect, db, provider)
+		if err != nil {
+			log.Println("OpenID Connect login failed:", err)
+			writeJSONError(res, http.StatusBadGateway, "OpenID Connect provider unavailable")
+			return
+		}
+
+		http.SetCookie(res, &http.Cookie{
+			Name:     oidcStateCookie,
+			Value:    state,
+			Path:     "/oidc/",
+			MaxAge:   int((oidcConfig.Expiration * time.Minute).Seconds()),
+			HttpOnly: true,
+			Secure:   strings.HasPrefix(oidcConfig.RedirectURL, "https://"),
+			// The provider redirects back with a top-level navigation
+			SameSite: http.SameSiteLaxMode,
+		})
+		http.Redirect(res, req, authURL, http.StatusFound)
+	})
+}
+
+// OIDCCallbackHandler - Completes the OpenID Connect login and responds with a JWT, or a challenge
+// like getToken for users with two-factor authentication, or redirects to the configured success
+// URL with it in the fragment
+func OIDCCallbackHandler(dialect goqu.DialectWrapper, db *sql.DB, oidcConfig configStruct.OIDCConfiguration, provider *source.OIDCProvider) http.Handler {
+	return http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		query := req.URL.Query()
+		if providerErr := query.Get("error"); len(providerErr) > 0 {
+			writeJSONError(res, http.StatusUnauthorized, "OpenID Connect login failed: "+providerErr)
+			return
+		}
+
+		// The state must match the browser which started the login
+		state := query.Get("state")
+		cookie, cookieErr := req.Cookie(oidcStateCookie)
+		if cookieErr != nil || len(state) == 0 || cookie.Value != state {
+			writeJSONError(res, http.StatusBadRequest, source.ErrInvalidOIDCLogin.Error())
+			return
+		}
+		http.SetCookie(res, &http.Cookie{
+			Name:   oidcStateCookie,
+			Path:   "/oidc/",
+			MaxAge: -1,
+		})
+
+		user, err := source.FinishOIDCLogin(dialect, db, provider, state, query.Get("code"))
+		if err != nil {
+			log.Println("OpenID Connect callback failed:", err)
+			writeJSONError(res, http.StatusUnauthorized, "OpenID Connect login failed")
+			return
+		}
+
+		// Users with two-factor authentication enabled have to complete the login using verifyTwoFactor
+		response := map[string]interface{}{
+			"totpRequired": user.TOTPEnabledAt != nil,
+		}
+		if user.TOTPEnabledAt != nil {
+			challenge, challengeErr := source.CreateChallengeJWT(user.ID)
+			if challengeErr != nil {
+				writeJSONError(res, http.StatusInternalServerError, challengeErr.Error())
+				return
+			}
+			response["challenge"] = challenge
+		} else {
+			token, tokenErr := source.CreateOIDCJWT(user.ID)
+			if tokenErr != nil {
+				writeJSONError(res, http.StatusInternalServerError, tokenErr.Error())
+				return
+			}
+			response["token"] = token
+		}
+
+		res.Header().Set("Cache-Control", "no-store")
+		res.Header().Set("Referrer-Policy", "no-referrer")
+
+		// Browsers do not send the fragment to servers, keeping the token out of their logs
+		if len(oidcConfig.SuccessURL) > 0 {
+			fragment := url.Values{}
+			for key, value := range response {
+				fragment.Set(key, fmt.Sprint(value))
+			}
+			http.Redirect(res, req, oidcConfig.SuccessURL+"#"+fragment.Encode(), http.StatusFound)
+			return
+		}
+
+		res.Header().Set("Content-Type", "application/json; charset=utf-8")
+		json.NewEncoder(res).Encode(response)
+	})
+}
+
 func main() {
 	// Read configuration file
 	config := source.GetConfig(".")
@@ -50,36 +334,26 @@ func main() {
 		log.Fatal(errInit)
 	}
 
-	// Bind Queries
-	allQueries := movies.Queries(dialect, db)
-	userQueries := users.Queries(dialect, db)
-	for k, v := range userQueries {
-		allQueries[k] = v
+	// Load the keys used to sign JWTs
+	errKeys := source.InitSigningKeys(dialect, db)
+	if errKeys != nil {
+		log.Fatal(errKeys)
 	}
 
-	var queryType = graphql.NewObject(
-		graphql.ObjectConfig{
-			Name:   "Query",
-			Fields: allQueries,
-		},
-	)
-
-	// Bind Mutations
-	allMutations := movies.Mutations(dialect, db)
-	var mutationType = graphql.NewObject(
-		graphql.ObjectConfig{
-			Name:   "Mutation",
-			Fields: allMutations,
-		},
-	)
+	// Storage of uploaded posters
+	storage, errStorage := source.InitBlobStorage(config.Storage)
+	if errStorage != nil {
+		log.Fatal(errStorage)
+	}
+
+	// Permanently remove movies deleted longer than the retention
+	source.StartPurge(dialect, db)
 
 	// Generate the schema
-	var schema, _ = graphql.NewSchema(
-		graphql.SchemaConfig{
-			Query:    queryType,
-			Mutation: mutationType,
-		},
-	)
+	schema, errSchema := gqlschema.New(dialect, db)
+	if errSchema != nil {
+		log.Fatal(errSchema)
+	}
 
 	// Handle Playground Hosting
 	h := handler.New(&handler.Config{
@@ -92,7 +366,34 @@ func main() {
 	mux := http.NewServeMux()
 
 	// GraphQL endpoint
-	mux.Handle("/graphql", ContextMiddleware(h))
+	// Uploads may exceed the configured size by the rest of the multipart request
+	var bodyLimit int64
+	if config.Upload.MaxSize > 0 {
+		bodyLimit = config.Upload.MaxSize*1024 + 1<<20
+	}
+	mux.Handle("/graphql", RateLimitMiddleware(dialect, db, config.RateLimit, bodyLimit, ContextMiddleware(config.RateLimit.TrustProxy, &schema, h)))
+
+	// Uploaded files, unless the storage is served elsewhere
+	if files, ok := storage.(http.Handler); ok {
+		mux.Handle("/media/", http.StripPrefix("/media", files))
+	}
+
+	// Login through an external OpenID Connect provider
+	if config.OIDC.Enabled {
+		provider := source.NewOIDCProvider(config.OIDC)
+		mux.Handle("/oidc/login", RateLimitMiddleware(dialect, db, config.RateLimit, maxRequestBody, OIDCLoginHandler(dialect, db, config.OIDC, provider)))
+		mux.Handle("/oidc/callback", RateLimitMiddleware(dialect, db, config.RateLimit, maxRequestBody, OIDCCallbackHandler(dialect, db, config.OIDC, provider)))
+	}
+
+	// Bulk export of the catalog
+	mux.Handle("/export/", RateLimitMiddleware(dialect, db, config.RateLimit, maxRequestBody, ExportHandler(dialect, db, config.RateLimit.TrustProxy)))
+
+	// Public keys verifying JWTs
+	mux.HandleFunc("/.well-known/jwks.json", func(res http.ResponseWriter, req *http.Request) {
+		res.Header().Set("Content-Type", "application/json; charset=utf-8")
+		res.Header().Set("Cache-Control", "public, max-age="+strconv.Itoa(int(source.JWKSMaxAge.Seconds())))
+		json.NewEncoder(res).Encode(source.JWKS())
+	})
 
 	// Prisma GraphQL playground
 	mux.Handle("/playground/", http.StripPrefix("/playground/", http.FileServer(http.Dir("views"))))
@@ -0,0 +1,16 @@
+package models
+
+import "time"
+
+// APIKey - A long-lived credential for machine-to-machine access on behalf of a user, the key
+// itself is only known when created
+type APIKey struct {
+	ID         string     `json:"id"`
+	CreatedAt  *time.Time `json:"created_at"`
+	UsersID    string     `json:"users_id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	Scopes     []string   `json:"scopes"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+}
@@ -0,0 +1,16 @@
+package models
+
+// BulkMovies - Outcome of a change applied to many movies at once
+type BulkMovies struct {
+	Results   []BulkMovieResult `json:"results"`
+	Succeeded int               `json:"succeeded"`
+	Failed    int               `json:"failed"`
+}
+
+// BulkMovieResult - Outcome of the change of a single movie, in the order of the input
+type BulkMovieResult struct {
+	Index int    `json:"index"`
+	Movie *Movie `json:"movie"`
+	Error string `json:"error,omitempty"`
+	Code  string `json:"code,omitempty"`
+}
@@ -0,0 +1,30 @@
+package models
+
+import "time"
+
+// Collection - Named, ordered list of movies curated by a user
+type Collection struct {
+	ID          string     `json:"id"`
+	CreatedAt   *time.Time `json:"created_at"`
+	UpdatedAt   *time.Time `json:"updated_at"`
+	UsersID     string     `json:"users_id"`
+	Name        string     `json:"name"`
+	Description string     `json:"description,omitempty"`
+	Public      bool       `json:"public"`
+	ShareToken  string     `json:"-"`
+}
+
+// CollectionMovieEdge - A movie of a collection along with its place in it
+type CollectionMovieEdge struct {
+	Cursor   string     `json:"cursor"`
+	Position int64      `json:"position"`
+	AddedAt  *time.Time `json:"added_at"`
+	Node     Movie      `json:"node"`
+}
+
+// CollectionMovieConnection - A page of the movies of a collection in their curated order
+type CollectionMovieConnection struct {
+	Edges      []CollectionMovieEdge `json:"edges"`
+	PageInfo   PageInfo              `json:"pageInfo"`
+	TotalCount int                   `json:"totalCount"`
+}
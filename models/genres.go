@@ -0,0 +1,11 @@
+package models
+
+import "time"
+
+// Genre - An entry of the genre taxonomy movies are classified by
+type Genre struct {
+	ID         string     `json:"id"`
+	CreatedAt  *time.Time `json:"created_at"`
+	Name       string     `json:"name"`
+	MovieCount int64      `json:"movie_count"`
+}
@@ -0,0 +1,8 @@
+package models
+
+// Mail - A plain text email sent to a single recipient
+type Mail struct {
+	To      string `json:"to"`
+	Subject string `json:"subject"`
+	Body    string `json:"body"`
+}
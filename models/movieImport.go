@@ -0,0 +1,15 @@
+package models
+
+// MovieImport - Outcome of a bulk import of movies
+type MovieImport struct {
+	Imported int                `json:"imported"`
+	Skipped  int                `json:"skipped"`
+	Errors   []MovieImportError `json:"errors"`
+	Error    string             `json:"error,omitempty"`
+}
+
+// MovieImportError - A row of the import which was refused
+type MovieImportError struct {
+	Row     int    `json:"row"`
+	Message string `json:"message"`
+}
@@ -0,0 +1,14 @@
+package models
+
+import "time"
+
+// MovieRevision - A change made to a movie, holding the movie before and after the change
+type MovieRevision struct {
+	ID        string     `json:"id"`
+	CreatedAt *time.Time `json:"created_at"`
+	MoviesID  string     `json:"movies_id"`
+	UsersID   string     `json:"users_id"`
+	Action    string     `json:"action"`
+	Before    *Movie     `json:"before,omitempty"`
+	After     *Movie     `json:"after,omitempty"`
+}
@@ -4,14 +4,17 @@ import "time"
 
 // Movie - Basic information about a movie
 type Movie struct {
-	ID          string     `json:"id"`
-	CreatedAt   *time.Time `json:"created_at"`
-	UpdatedAt   *time.Time `json:"updated_at"`
-	DeletedAt   *time.Time `json:"deleted_at,omitempty"`
-	UsersID     string     `json:"users_id"`
-	Name        string     `json:"name"`
-	ReleaseYear int64      `json:"release_year"`
-	Description string     `json:"description,omitempty"`
-	Rating      float64    `json:"rating"`
-	ReviewCount int64      `json:"review_count"`
+	ID           string     `json:"id"`
+	CreatedAt    *time.Time `json:"created_at"`
+	UpdatedAt    *time.Time `json:"updated_at"`
+	DeletedAt    *time.Time `json:"deleted_at,omitempty"`
+	UsersID      string     `json:"users_id"`
+	Name         string     `json:"name"`
+	ReleaseYear  int64      `json:"release_year"`
+	Description  string     `json:"description,omitempty"`
+	Rating       float64    `json:"rating"`
+	ReviewCount  int64      `json:"review_count"`
+	Version      int64      `json:"version"`
+	PosterKey    string     `json:"poster_key,omitempty"`
+	ThumbnailKey string     `json:"thumbnail_key,omitempty"`
 }
@@ -0,0 +1,25 @@
+package models
+
+import "time"
+
+// Person - Someone who worked on movies, as an actor, director or another role
+type Person struct {
+	ID        string     `json:"id"`
+	CreatedAt *time.Time `json:"created_at"`
+	UpdatedAt *time.Time `json:"updated_at"`
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
+	UsersID   string     `json:"users_id"`
+	Name      string     `json:"name"`
+	Biography string     `json:"biography,omitempty"`
+}
+
+// Credit - The role a person had in a movie
+type Credit struct {
+	ID            string     `json:"id"`
+	CreatedAt     *time.Time `json:"created_at"`
+	MoviesID      string     `json:"movies_id"`
+	PeopleID      string     `json:"people_id"`
+	Role          string     `json:"role"`
+	CharacterName string     `json:"character_name,omitempty"`
+	Position      int64      `json:"position"`
+}
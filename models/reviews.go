@@ -0,0 +1,14 @@
+package models
+
+import "time"
+
+// Review - A single rating given to a movie by a user
+type Review struct {
+	ID        string     `json:"id"`
+	CreatedAt *time.Time `json:"created_at"`
+	UpdatedAt *time.Time `json:"updated_at"`
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
+	MoviesID  string     `json:"movies_id"`
+	UsersID   string     `json:"users_id"`
+	Rating    float64    `json:"rating"`
+}
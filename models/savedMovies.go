@@ -0,0 +1,33 @@
+package models
+
+import "time"
+
+// Lists a user saves movies to
+const (
+	// Watchlist - movies the user wants to see
+	Watchlist = "watchlist"
+	// Favorites - movies the user likes most
+	Favorites = "favorites"
+)
+
+// PageInfo - Position of a page within a paginated connection
+type PageInfo struct {
+	HasNextPage     bool    `json:"hasNextPage"`
+	HasPreviousPage bool    `json:"hasPreviousPage"`
+	StartCursor     *string `json:"startCursor"`
+	EndCursor       *string `json:"endCursor"`
+}
+
+// SavedMovieEdge - A movie on the watchlist or favorites of a user along with when it was saved
+type SavedMovieEdge struct {
+	Cursor  string     `json:"cursor"`
+	SavedAt *time.Time `json:"saved_at"`
+	Node    Movie      `json:"node"`
+}
+
+// SavedMovieConnection - A page of the movies saved by a user, most recently saved first
+type SavedMovieConnection struct {
+	Edges      []SavedMovieEdge `json:"edges"`
+	PageInfo   PageInfo         `json:"pageInfo"`
+	TotalCount int              `json:"totalCount"`
+}
@@ -0,0 +1,11 @@
+package models
+
+import "mime/multipart"
+
+// Upload - A file sent along with a GraphQL multipart request
+type Upload struct {
+	Filename    string
+	ContentType string
+	Size        int64
+	File        multipart.File
+}
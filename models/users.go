@@ -10,4 +10,14 @@ type User struct {
 	DeletedAt         *time.Time `json:"deleted_at,omitempty"`
 	Email             string     `json:"email"`
 	EncryptedPassword string     `json:"encrypted_password"`
+	VerifiedAt        *time.Time `json:"verified_at,omitempty"`
+	DisplayName       string     `json:"display_name"`
+	AvatarURL         string     `json:"avatar_url"`
+	Role              string     `json:"role"`
+	TOTPSecret        string     `json:"-"`
+	TOTPEnabledAt     *time.Time `json:"totp_enabled_at,omitempty"`
+	TOTPLastStep      int64      `json:"-"`
+	// Set when authenticated using an API key instead of a JWT
+	APIKeyID     string   `json:"-"`
+	APIKeyScopes []string `json:"-"`
 }
@@ -0,0 +1,329 @@
+package source
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/doug-martin/goqu/v8"
+
+	"github.com/HencoSmith/graphql-example-go/models"
+)
+
+// Policies for the content of deleted accounts
+const (
+	// ContentKeep - content stays, attributed to the anonymized account
+	ContentKeep = "keep"
+	// ContentDelete - content is removed along with the account
+	ContentDelete = "delete"
+)
+
+// deleteAccountContent - Apply the configured policy to the movies and reviews of the user
+func deleteAccountContent(dialect goqu.DialectWrapper, tx *sql.Tx, userID string, now string) error {
+	// Read configuration file
+	config := GetConfig(".")
+	policy := config.AccountDeletion
+
+	if policy.Movies == ContentDelete {
+		moviesDialect := dialect.Update("movies").Set(
+			goqu.Record{
+				"deleted_at": now,
+			},
+		).Where(goqu.Ex{
+			"users_id":   userID,
+			"deleted_at": nil,
+		}).Returning(goqu.L("to_jsonb(movies.*)"))
+		moviesQuery, _, moviesToSQLErr := moviesDialect.ToSQL()
+		if moviesToSQLErr != nil {
+			return moviesToSQLErr
+		}
+
+		rows, moviesErr := tx.Query(moviesQuery)
+		if moviesErr != nil {
+			return moviesErr
+		}
+		deleted := []models.Movie{}
+		for rows.Next() {
+			var snapshot []byte
+			if scanErr := rows.Scan(&snapshot); scanErr != nil {
+				rows.Close()
+				return scanErr
+			}
+			movie := models.Movie{}
+			if unmarshalErr := json.Unmarshal(snapshot, &movie); unmarshalErr != nil {
+				rows.Close()
+				return unmarshalErr
+			}
+			deleted = append(deleted, movie)
+		}
+		rows.Close()
+		if errRows := rows.Err(); errRows != nil {
+			return errRows
+		}
+
+		// Record the deletions like those made through the API
+		for i := range deleted {
+			before := deleted[i]
+			before.DeletedAt = nil
+			if revisionErr := RecordMovieRevision(dialect, tx, userID, RevisionDelete, &before, &deleted[i]); revisionErr != nil {
+				return revisionErr
+			}
+		}
+	}
+
+	if policy.Reviews == ContentDelete {
+		// Ratings are running totals of every review, so reviews are removed rather than
+		// marked deleted and the totals of the affected movies are recalculated
+		deleteDialect := dialect.Delete("movies_reviews").Where(goqu.Ex{
+			"users_id": userID,
+		}).Returning("movies_id")
+		deleteQuery, _, deleteToSQLErr := deleteDialect.ToSQL()
+		if deleteToSQLErr != nil {
+			return deleteToSQLErr
+		}
+
+		rows, deleteErr := tx.Query(deleteQuery)
+		if deleteErr != nil {
+			return deleteErr
+		}
+		moviesIDs := map[string]bool{}
+		for rows.Next() {
+			var moviesID string
+			if scanErr := rows.Scan(&moviesID); scanErr != nil {
+				rows.Close()
+				return scanErr
+			}
+			moviesIDs[moviesID] = true
+		}
+		rows.Close()
+		if errRows := rows.Err(); errRows != nil {
+			return errRows
+		}
+
+		for moviesID := range moviesIDs {
+			_, updateErr := tx.Exec(`
+			UPDATE movies SET
+				rating = COALESCE((SELECT AVG(rating) FROM movies_reviews WHERE movies_id = $1), 0),
+				review_count = (SELECT COUNT(*) FROM movies_reviews WHERE movies_id = $1)
+			WHERE id = $1`, moviesID)
+			if updateErr != nil {
+				return updateErr
+			}
+		}
+	}
+
+	return nil
+}
+
+// ConfirmIdentity - Check the proof of a signed in user confirming an irreversible change: the
+// password, a TOTP or recovery code if two-factor authentication is enabled, or a JWT of a recent
+// OpenID Connect login for users who never set a password. Failures count towards the lockout
+func ConfirmIdentity(currentContext context.Context, dialect goqu.DialectWrapper, db *sql.DB, user models.User, password string, code string) error {
+	ip, _ := currentContext.Value(models.ContextKey{Key: "ip"}).(string)
+
+	if len(password) > 0 {
+		return VerifyPassword(dialect, db, user, password, ip)
+	}
+
+	if len(code) > 0 {
+		delay, lockErr := CheckLogin(dialect, db, user.Email, ip)
+		if lockErr != nil {
+			return lockErr
+		}
+		time.Sleep(delay)
+
+		if verifyErr := VerifySecondFactor(dialect, db, user, code); verifyErr != nil {
+			if recordErr := RecordLoginFailure(dialect, db, user.Email, ip); recordErr != nil {
+				return recordErr
+			}
+			return verifyErr
+		}
+		return nil
+	}
+
+	header, _ := currentContext.Value(models.ContextKey{Key: "header"}).(http.Header)
+	if RecentOIDCLogin(header.Get("Authorization")) {
+		return nil
+	}
+
+	return ErrInvalidCredentials
+}
+
+// DeleteAccount - Delete the account of the user, whose identity has to be confirmed using
+// ConfirmIdentity first. The user is soft-deleted and anonymized, credentials are removed and the
+// movies and reviews of the user are handled according to the configured policy
+func DeleteAccount(dialect goqu.DialectWrapper, db *sql.DB, user models.User) error {
+
+	tx, txErr := db.Begin()
+	if txErr != nil {
+		return txErr
+	}
+	defer tx.Rollback()
+
+	now := time.Now().Format(time.RFC3339)
+
+	if contentErr := deleteAccountContent(dialect, tx, user.ID, now); contentErr != nil {
+		return contentErr
+	}
+
+	// Credentials and personal data stored alongside the account
+	for _, table := range []string{
+		"collections",
+		"movies_saves",
+		"users_api_keys",
+		"users_email_verifications",
+		"users_external_identities",
+		"users_password_resets",
+		"users_recovery_codes",
+	} {
+		deleteDialect := dialect.Delete(table).Where(goqu.Ex{
+			"users_id": user.ID,
+		})
+		deleteQuery, _, toSQLErr := deleteDialect.ToSQL()
+		if toSQLErr != nil {
+			return toSQLErr
+		}
+		if _, deleteErr := tx.Exec(deleteQuery); deleteErr != nil {
+			return deleteErr
+		}
+	}
+
+	failuresDialect := dialect.Delete("users_login_failures").Where(goqu.Ex{
+		"email": NormalizeEmail(user.Email),
+	})
+	failuresQuery, _, failuresToSQLErr := failuresDialect.ToSQL()
+	if failuresToSQLErr != nil {
+		return failuresToSQLErr
+	}
+	if _, failuresErr := tx.Exec(failuresQuery); failuresErr != nil {
+		return failuresErr
+	}
+
+	// The row stays for content kept by the policy, nothing identifies the person any more and
+	// the empty password hash never validates
+	if updateErr := updateUser(dialect, tx, user.ID, goqu.Record{
+		"deleted_at":         now,
+		"updated_at":         now,
+		"email":              "deleted-" + user.ID + "@deleted.invalid",
+		"encrypted_password": "",
+		"verified_at":        nil,
+		"display_name":       "",
+		"avatar_url":         "",
+		"totp_secret":        "",
+		"totp_enabled_at":    nil,
+		"totp_last_step":     0,
+	}); updateErr != nil {
+		return updateErr
+	}
+
+	return tx.Commit()
+}
+
+// exportRows - Select the columns of every row of the table matching the expression
+func exportRows(dialect goqu.DialectWrapper, db *sql.DB, table string, columns []string, expression goqu.Ex) ([]map[string]interface{}, error) {
+	selectColumns := []interface{}{}
+	for _, column := range columns {
+		selectColumns = append(selectColumns, column)
+	}
+	dialectString := dialect.From(table).Select(selectColumns...).Where(expression).Order(goqu.C("created_at").Asc())
+	query, _, dialectErr := dialectString.ToSQL()
+	if dialectErr != nil {
+		return nil, dialectErr
+	}
+
+	rows, queryErr := db.Query(query)
+	if queryErr != nil {
+		return nil, queryErr
+	}
+	defer rows.Close()
+
+	exported := []map[string]interface{}{}
+	for rows.Next() {
+		values := make([]interface{}, len(columns))
+		pointers := make([]interface{}, len(columns))
+		for i := range values {
+			pointers[i] = &values[i]
+		}
+		if scanErr := rows.Scan(pointers...); scanErr != nil {
+			return nil, scanErr
+		}
+
+		row := map[string]interface{}{}
+		for i, column := range columns {
+			// Numeric and text columns may be returned as bytes
+			if buff, ok := values[i].([]byte); ok {
+				row[column] = string(buff)
+			} else {
+				row[column] = values[i]
+			}
+		}
+		exported = append(exported, row)
+	}
+	if errRows := rows.Err(); errRows != nil {
+		return nil, errRows
+	}
+
+	return exported, nil
+}
+
+// ExportUserData - Collect everything stored about the user into a JSON serializable archive,
+// secrets such as password and token hashes are left out
+func ExportUserData(dialect goqu.DialectWrapper, db *sql.DB, user models.User) (map[string]interface{}, error) {
+	if len(user.ID) == 0 {
+		return nil, errors.New("Invalid user")
+	}
+
+	byUser := goqu.Ex{
+		"users_id": user.ID,
+	}
+	sections := []struct {
+		name       string
+		table      string
+		columns    []string
+		expression goqu.Ex
+	}{
+		{"movies", "movies", []string{"id", "created_at", "updated_at", "deleted_at", "name", "release_year", "description", "rating", "review_count", "version"}, byUser},
+		{"reviews", "movies_reviews", []string{"id", "created_at", "updated_at", "deleted_at", "movies_id", "rating"}, byUser},
+		{"watchlist", "movies_saves", []string{"created_at", "movies_id"}, goqu.Ex{"users_id": user.ID, "list": models.Watchlist}},
+		{"favorites", "movies_saves", []string{"created_at", "movies_id"}, goqu.Ex{"users_id": user.ID, "list": models.Favorites}},
+		{"revisions", "movie_revisions", []string{"id", "created_at", "movies_id", "action", "before", "after"}, byUser},
+		{"collections", "collections", []string{"id", "created_at", "updated_at", "name", "description", "public"}, byUser},
+		{"collectionMovies", "collections_movies", []string{"collections_id", "created_at", "movies_id", "position"}, goqu.Ex{
+			"collections_id": goqu.Op{"in": dialect.From("collections").Select("id").Where(byUser)},
+		}},
+		{"apiKeys", "users_api_keys", []string{"id", "created_at", "name", "prefix", "scopes", "last_used_at", "revoked_at"}, byUser},
+		{"externalIdentities", "users_external_identities", []string{"id", "created_at", "issuer", "subject"}, byUser},
+		{"emailVerifications", "users_email_verifications", []string{"id", "created_at", "expires_at", "used_at", "email"}, byUser},
+		{"passwordResets", "users_password_resets", []string{"id", "created_at", "expires_at", "used_at"}, byUser},
+		{"recoveryCodes", "users_recovery_codes", []string{"id", "created_at", "used_at"}, byUser},
+		{"loginFailures", "users_login_failures", []string{"id", "created_at", "email", "ip"}, goqu.Ex{
+			"email": NormalizeEmail(user.Email),
+		}},
+	}
+
+	archive := map[string]interface{}{
+		"exported_at": time.Now().UTC().Format(time.RFC3339),
+		"user": map[string]interface{}{
+			"id":              user.ID,
+			"created_at":      user.CreatedAt,
+			"updated_at":      user.UpdatedAt,
+			"email":           user.Email,
+			"verified_at":     user.VerifiedAt,
+			"display_name":    user.DisplayName,
+			"avatar_url":      user.AvatarURL,
+			"totp_enabled_at": user.TOTPEnabledAt,
+		},
+	}
+	for _, section := range sections {
+		rows, exportErr := exportRows(dialect, db, section.table, section.columns, section.expression)
+		if exportErr != nil {
+			return nil, exportErr
+		}
+		archive[section.name] = rows
+	}
+
+	return archive, nil
+}
@@ -0,0 +1,288 @@
+package source
+
+import (
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/doug-martin/goqu/v8"
+	uuid "github.com/satori/go.uuid"
+
+	"github.com/HencoSmith/graphql-example-go/models"
+)
+
+// APIKeyPrefix - prefix distinguishing API keys from JWTs in the Authorization header
+const APIKeyPrefix = "gqe_"
+
+// apiKeyDisplayLength - characters of the key stored in plain text to identify it in listings
+const apiKeyDisplayLength = 12
+
+// apiKeyUsageInterval - how often the last use of a key is written, avoiding a write per request
+const apiKeyUsageInterval = time.Minute
+
+// Scopes granted to API keys, users authenticated by JWT hold every scope
+const (
+	// ScopeRead - queries
+	ScopeRead = "read"
+	// ScopeWrite - mutations of movies and reviews
+	ScopeWrite = "write"
+	// ScopeAccount - management of the account and its credentials, never granted to API keys
+	ScopeAccount = "account"
+)
+
+// APIKeyScopes - scopes which may be granted to API keys
+var APIKeyScopes = []string{ScopeRead, ScopeWrite}
+
+// ErrInvalidAPIKey - returned when an API key does not exist or was revoked
+var ErrInvalidAPIKey = errors.New("Invalid API key")
+
+// ErrAPIKeyNotFound - returned when revoking a key which does not belong to the user
+var ErrAPIKeyNotFound = errors.New("API key not found")
+
+// ErrInsufficientScope - returned when the API key lacks the scope required by the operation
+var ErrInsufficientScope = errors.New("API key lacks the scope required for this operation")
+
+// IsAPIKey - Determine whether the Authorization token is an API key rather than a JWT
+func IsAPIKey(token string) bool {
+	return strings.HasPrefix(token, APIKeyPrefix)
+}
+
+// RequireScope - Check the scope is held by the API key the user authenticated with, users
+// authenticated by JWT hold every scope
+func RequireScope(user models.User, scope string) error {
+	if len(user.APIKeyID) == 0 {
+		return nil
+	}
+
+	for _, granted := range user.APIKeyScopes {
+		if granted == scope {
+			return nil
+		}
+	}
+
+	return ErrInsufficientScope
+}
+
+// apiKeyColumns - columns scanned by scanAPIKey
+var apiKeyColumns = []interface{}{
+	"id",
+	"created_at",
+	"users_id",
+	"name",
+	"prefix",
+	"scopes",
+	"last_used_at",
+	"revoked_at",
+}
+
+// scanAPIKey - Scan a row selected with apiKeyColumns
+func scanAPIKey(scanner interface {
+	Scan(dest ...interface{}) error
+}) (models.APIKey, error) {
+	var key models.APIKey
+	var scopes string
+	scanErr := scanner.Scan(
+		&key.ID,
+		&key.CreatedAt,
+		&key.UsersID,
+		&key.Name,
+		&key.Prefix,
+		&scopes,
+		&key.LastUsedAt,
+		&key.RevokedAt,
+	)
+	if scanErr != nil {
+		return models.APIKey{}, scanErr
+	}
+
+	key.Scopes = []string{}
+	if len(scopes) > 0 {
+		key.Scopes = strings.Split(scopes, ",")
+	}
+
+	return key, nil
+}
+
+// CreateAPIKey - Store a new API key of the user with the scopes, returns the key model along
+// with the key itself which is only available now
+func CreateAPIKey(dialect goqu.DialectWrapper, db *sql.DB, user models.User, name string, scopes []string) (models.APIKey, string, error) {
+	name = strings.TrimSpace(name)
+	if length := utf8.RuneCountInString(name); length == 0 || length > 64 {
+		return models.APIKey{}, "", errors.New("API key name must be between 1 and 64 characters")
+	}
+
+	unique := map[string]bool{}
+	granted := []string{}
+	for _, scope := range scopes {
+		valid := false
+		for _, allowed := range APIKeyScopes {
+			valid = valid || scope == allowed
+		}
+		if !valid {
+			return models.APIKey{}, "", errors.New("Invalid API key scope: " + scope)
+		}
+		if !unique[scope] {
+			unique[scope] = true
+			granted = append(granted, scope)
+		}
+	}
+	if len(granted) == 0 {
+		return models.APIKey{}, "", errors.New("API keys require at least one scope")
+	}
+
+	token, tokenErr := RandomToken()
+	if tokenErr != nil {
+		return models.APIKey{}, "", tokenErr
+	}
+	key := APIKeyPrefix + token
+
+	id := uuid.NewV4().String()
+	insertDialect := dialect.Insert("users_api_keys").Rows(
+		goqu.Record{
+			"id":       id,
+			"users_id": user.ID,
+			"name":     name,
+			"prefix":   key[:apiKeyDisplayLength],
+			"key_hash": HashToken(key),
+			"scopes":   strings.Join(granted, ","),
+		},
+	).Returning(apiKeyColumns...)
+	insertQuery, _, toSQLErr := insertDialect.ToSQL()
+	if toSQLErr != nil {
+		return models.APIKey{}, "", toSQLErr
+	}
+
+	apiKey, insertErr := scanAPIKey(db.QueryRow(insertQuery))
+	if insertErr != nil {
+		return models.APIKey{}, "", insertErr
+	}
+
+	return apiKey, key, nil
+}
+
+// ListAPIKeys - Lookup every API key of the user including revoked keys, newest first
+func ListAPIKeys(dialect goqu.DialectWrapper, db *sql.DB, userID string) ([]models.APIKey, error) {
+	dialectString := dialect.From("users_api_keys").Select(apiKeyColumns...).Where(goqu.Ex{
+		"users_id": userID,
+	}).Order(goqu.C("created_at").Desc())
+	query, _, dialectErr := dialectString.ToSQL()
+	if dialectErr != nil {
+		return nil, dialectErr
+	}
+
+	rows, queryErr := db.Query(query)
+	if queryErr != nil {
+		return nil, queryErr
+	}
+	defer rows.Close()
+
+	keys := []models.APIKey{}
+	for rows.Next() {
+		key, scanErr := scanAPIKey(rows)
+		if scanErr != nil {
+			return nil, scanErr
+		}
+		keys = append(keys, key)
+	}
+	if errRows := rows.Err(); errRows != nil {
+		return nil, errRows
+	}
+
+	return keys, nil
+}
+
+// RevokeAPIKey - Revoke the API key of the user, it is rejected from then on
+func RevokeAPIKey(dialect goqu.DialectWrapper, db *sql.DB, userID string, id string) error {
+	updateDialect := dialect.Update("users_api_keys").Set(
+		goqu.Record{
+			"revoked_at": time.Now().Format(time.RFC3339),
+		},
+	).Where(goqu.Ex{
+		"id":         id,
+		"users_id":   userID,
+		"revoked_at": nil,
+	})
+	updateQuery, _, toSQLErr := updateDialect.ToSQL()
+	if toSQLErr != nil {
+		return toSQLErr
+	}
+
+	updateRes, updateErr := db.Exec(updateQuery)
+	if updateErr != nil {
+		return updateErr
+	}
+	if updated, _ := updateRes.RowsAffected(); updated < 1 {
+		return ErrAPIKeyNotFound
+	}
+
+	return nil
+}
+
+// lookupAPIKey - Find the unrevoked API key
+func lookupAPIKey(dialect goqu.DialectWrapper, db *sql.DB, key string) (models.APIKey, error) {
+	dialectString := dialect.From("users_api_keys").Select(apiKeyColumns...).Where(goqu.Ex{
+		"key_hash":   HashToken(key),
+		"revoked_at": nil,
+	})
+	query, _, dialectErr := dialectString.ToSQL()
+	if dialectErr != nil {
+		return models.APIKey{}, dialectErr
+	}
+
+	apiKey, scanErr := scanAPIKey(db.QueryRow(query))
+	if scanErr == sql.ErrNoRows {
+		return models.APIKey{}, ErrInvalidAPIKey
+	}
+
+	return apiKey, scanErr
+}
+
+// GetUserFromAPIKey - Lookup the user the API key belongs to and record its use, the user
+// carries the scopes of the key
+func GetUserFromAPIKey(dialect goqu.DialectWrapper, db *sql.DB, key string) (models.User, error) {
+	apiKey, lookupErr := lookupAPIKey(dialect, db, key)
+	if lookupErr != nil {
+		return models.User{}, lookupErr
+	}
+
+	if apiKey.LastUsedAt == nil || time.Since(*apiKey.LastUsedAt) > apiKeyUsageInterval {
+		updateDialect := dialect.Update("users_api_keys").Set(
+			goqu.Record{
+				"last_used_at": time.Now().Format(time.RFC3339),
+			},
+		).Where(goqu.Ex{
+			"id": apiKey.ID,
+		})
+		updateQuery, _, toSQLErr := updateDialect.ToSQL()
+		if toSQLErr != nil {
+			return models.User{}, toSQLErr
+		}
+		if _, updateErr := db.Exec(updateQuery); updateErr != nil {
+			return models.User{}, updateErr
+		}
+	}
+
+	user, userErr := GetUser(dialect, db, apiKey.UsersID, "")
+	if userErr != nil {
+		return models.User{}, userErr
+	}
+	user.APIKeyID = apiKey.ID
+	user.APIKeyScopes = apiKey.Scopes
+
+	return user, nil
+}
+
+// TokenUserID - Lookup the user ID of a JWT or API key without recording its use
+func TokenUserID(dialect goqu.DialectWrapper, db *sql.DB, token string) (string, error) {
+	if !IsAPIKey(token) {
+		return DecodeJWT(token)
+	}
+
+	apiKey, lookupErr := lookupAPIKey(dialect, db, token)
+	if lookupErr != nil {
+		return "", lookupErr
+	}
+	return apiKey.UsersID, nil
+}
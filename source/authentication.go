@@ -5,27 +5,47 @@ import (
 	"database/sql"
 	"errors"
 	"net/http"
+	"sync"
 
 	"github.com/doug-martin/goqu/v8"
+	"github.com/doug-martin/goqu/v8/exp"
 
 	"github.com/HencoSmith/graphql-example-go/models"
 )
 
-// GetUser - Lookup the user based on ID, returns the model or alternatively an empty user
-// along with an error
-func GetUser(dialect goqu.DialectWrapper, db *sql.DB, userID string, userEmail string) (models.User, error) {
+// ErrUserNotFound - returned when no user matches the lookup
+var ErrUserNotFound = errors.New("User Not Found")
+
+// GetUser - Lookup the user based on ID, or the email compared case-insensitively, returns the
+// model or alternatively an empty user along with an error
+func GetUser(dialect goqu.DialectWrapper, db queryer, userID string, userEmail string) (models.User, error) {
 	// Find user
-	expression := goqu.Ex{
+	var expression exp.Expression = goqu.Ex{
 		"id":         userID,
 		"deleted_at": nil,
 	}
 	if len(userEmail) > 1 {
-		expression = goqu.Ex{
-			"email":      userEmail,
-			"deleted_at": nil,
-		}
+		// Matches the users_email_idx index
+		expression = goqu.And(
+			goqu.Func("lower", goqu.C("email")).Eq(NormalizeEmail(userEmail)),
+			goqu.C("deleted_at").IsNull(),
+		)
 	}
-	dialectString := dialect.From("users").Where(expression)
+	dialectString := dialect.From("users").Select(
+		"id",
+		"created_at",
+		"updated_at",
+		"deleted_at",
+		"email",
+		"encrypted_password",
+		"verified_at",
+		"display_name",
+		"avatar_url",
+		"role",
+		"totp_secret",
+		"totp_enabled_at",
+		"totp_last_step",
+	).Where(expression)
 	query, _, dialectErr := dialectString.ToSQL()
 	if dialectErr != nil {
 		return models.User{}, dialectErr
@@ -47,6 +67,13 @@ func GetUser(dialect goqu.DialectWrapper, db *sql.DB, userID string, userEmail s
 			&row.DeletedAt,
 			&row.Email,
 			&row.EncryptedPassword,
+			&row.VerifiedAt,
+			&row.DisplayName,
+			&row.AvatarURL,
+			&row.Role,
+			&row.TOTPSecret,
+			&row.TOTPEnabledAt,
+			&row.TOTPLastStep,
 		)
 		if scanErr != nil {
 			return models.User{}, scanErr
@@ -58,19 +85,24 @@ func GetUser(dialect goqu.DialectWrapper, db *sql.DB, userID string, userEmail s
 	}
 
 	if len(usersArr) < 1 {
-		return models.User{}, errors.New("User Not Found")
+		return models.User{}, ErrUserNotFound
 	}
 
 	return usersArr[0], nil
 }
 
-// GetUserFromToken - Lookup the user based on context (Authorization token) returns the user model or alternatively
+// GetUserFromToken - Lookup the user based on context (Authorization JWT or API key) returns the user model or alternatively
 // an error
 func GetUserFromToken(currentContext context.Context, dialect goqu.DialectWrapper, db *sql.DB) (models.User, error) {
 	// Extract the token from the header
 	contextValue := currentContext.Value(models.ContextKey{Key: "header"}).(http.Header)
 	authorizationToken := contextValue.Get("Authorization")
 
+	// Machine-to-machine clients authenticate using API keys
+	if IsAPIKey(authorizationToken) {
+		return GetUserFromAPIKey(dialect, db, authorizationToken)
+	}
+
 	// Check if the token is valid
 	userID, err := DecodeJWT(authorizationToken)
 	if err != nil {
@@ -80,3 +112,30 @@ func GetUserFromToken(currentContext context.Context, dialect goqu.DialectWrappe
 	// Find user
 	return GetUser(dialect, db, userID, "")
 }
+
+// viewer - User of a request, looked up once however many fields need it
+type viewer struct {
+	once sync.Once
+	user models.User
+	err  error
+}
+
+// WithViewer - Prepare the request context for Viewer, without it every call of Viewer looks the
+// user up again
+func WithViewer(currentContext context.Context) context.Context {
+	return context.WithValue(currentContext, models.ContextKey{Key: "viewer"}, &viewer{})
+}
+
+// Viewer - Lookup the user of the request like GetUserFromToken, remembering the result for the
+// rest of the request. Meant for type fields resolved for every object of a list
+func Viewer(currentContext context.Context, dialect goqu.DialectWrapper, db *sql.DB) (models.User, error) {
+	cached, ok := currentContext.Value(models.ContextKey{Key: "viewer"}).(*viewer)
+	if !ok {
+		return GetUserFromToken(currentContext, dialect, db)
+	}
+
+	cached.once.Do(func() {
+		cached.user, cached.err = GetUserFromToken(currentContext, dialect, db)
+	})
+	return cached.user, cached.err
+}
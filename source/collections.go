@@ -0,0 +1,9 @@
+package source
+
+// CollectionShareLink - Share link of a collection, built from the configured URL template
+func CollectionShareLink(shareToken string) (string, error) {
+	// Read configuration file
+	config := GetConfig(".")
+
+	return FormatLink(config.Collections.ShareURL, shareToken)
+}
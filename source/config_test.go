@@ -0,0 +1,23 @@
+package source
+
+import (
+	"os"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+// TestMain - Read the configuration of the repository, tests run in the package directory
+func TestMain(m *testing.M) {
+	viper.AddConfigPath("../config/")
+	os.Exit(m.Run())
+}
+
+// withConfig - Override a configuration value, returns a function restoring the previous value
+func withConfig(key string, value interface{}) func() {
+	previous := viper.Get(key)
+	viper.Set(key, value)
+	return func() {
+		viper.Set(key, previous)
+	}
+}
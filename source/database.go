@@ -99,6 +99,9 @@ func InitTables(dialect goqu.DialectWrapper, db *sql.DB) error {
 		description text,
 		rating numeric NOT NULL DEFAULT '0.0',
 		review_count bigint NOT NULL DEFAULT 0,
+		version bigint NOT NULL DEFAULT 1,
+		poster_key character varying(256) NOT NULL DEFAULT '',
+		thumbnail_key character varying(256) NOT NULL DEFAULT '',
 		PRIMARY KEY (id)
 	)
 	WITH (
@@ -108,6 +111,13 @@ func InitTables(dialect goqu.DialectWrapper, db *sql.DB) error {
 	ALTER TABLE public.movies
 		OWNER to "user";
 
+	ALTER TABLE public.movies
+		ADD COLUMN IF NOT EXISTS version bigint NOT NULL DEFAULT 1;
+
+	ALTER TABLE public.movies
+		ADD COLUMN IF NOT EXISTS poster_key character varying(256) NOT NULL DEFAULT '',
+		ADD COLUMN IF NOT EXISTS thumbnail_key character varying(256) NOT NULL DEFAULT '';
+
 	CREATE TABLE IF NOT EXISTS public.movies_reviews
 	(
 		id uuid NOT NULL,
@@ -134,6 +144,13 @@ func InitTables(dialect goqu.DialectWrapper, db *sql.DB) error {
 		deleted_at timestamp with time zone,
 		email character varying(64) NOT NULL,
 		encrypted_password character varying(512) NOT NULL,
+		verified_at timestamp with time zone,
+		display_name character varying(64) NOT NULL DEFAULT '',
+		avatar_url character varying(512) NOT NULL DEFAULT '',
+		role character varying(16) NOT NULL DEFAULT 'user',
+		totp_secret character varying(256) NOT NULL DEFAULT '',
+		totp_enabled_at timestamp with time zone,
+		totp_last_step bigint NOT NULL DEFAULT 0,
 		PRIMARY KEY (id)
 	)
 	WITH (
@@ -144,6 +161,323 @@ func InitTables(dialect goqu.DialectWrapper, db *sql.DB) error {
 	ALTER TABLE public.users
 		OWNER to "user";
 
+	DO $$
+	BEGIN
+		IF NOT EXISTS (
+			SELECT 1 FROM information_schema.columns
+			WHERE table_schema = 'public' AND table_name = 'users' AND column_name = 'verified_at'
+		) THEN
+			ALTER TABLE public.users
+				ADD COLUMN verified_at timestamp with time zone;
+			-- Accounts created before email verification existed are trusted
+			UPDATE public.users SET verified_at = created_at;
+		END IF;
+	END $$;
+
+	ALTER TABLE public.users
+		ADD COLUMN IF NOT EXISTS totp_secret character varying(256) NOT NULL DEFAULT '',
+		ADD COLUMN IF NOT EXISTS totp_enabled_at timestamp with time zone,
+		ADD COLUMN IF NOT EXISTS totp_last_step bigint NOT NULL DEFAULT 0,
+		ADD COLUMN IF NOT EXISTS display_name character varying(64) NOT NULL DEFAULT '',
+		ADD COLUMN IF NOT EXISTS avatar_url character varying(512) NOT NULL DEFAULT '',
+		ADD COLUMN IF NOT EXISTS role character varying(16) NOT NULL DEFAULT 'user';
+
+	-- Encrypted TOTP secrets are longer than the plain ones
+	ALTER TABLE public.users
+		ALTER COLUMN totp_secret TYPE character varying(256);
+
+	CREATE TABLE IF NOT EXISTS public.users_recovery_codes
+	(
+		id uuid NOT NULL,
+		created_at timestamp with time zone NOT NULL DEFAULT now(),
+		used_at timestamp with time zone,
+		users_id uuid NOT NULL,
+		code_hash character varying(64) NOT NULL,
+		PRIMARY KEY (id)
+	)
+	WITH (
+		OIDS = FALSE
+	);
+
+	ALTER TABLE public.users_recovery_codes
+		OWNER to "user";
+
+	CREATE TABLE IF NOT EXISTS public.users_email_verifications
+	(
+		id uuid NOT NULL,
+		created_at timestamp with time zone NOT NULL DEFAULT now(),
+		expires_at timestamp with time zone NOT NULL,
+		used_at timestamp with time zone,
+		users_id uuid NOT NULL,
+		email character varying(64) NOT NULL,
+		token_hash character varying(64) NOT NULL,
+		PRIMARY KEY (id)
+	)
+	WITH (
+		OIDS = FALSE
+	);
+
+	ALTER TABLE public.users_email_verifications
+		OWNER to "user";
+
+	CREATE TABLE IF NOT EXISTS public.users_login_failures
+	(
+		id uuid NOT NULL,
+		created_at timestamp with time zone NOT NULL DEFAULT now(),
+		email character varying(64) NOT NULL,
+		ip character varying(64) NOT NULL,
+		PRIMARY KEY (id)
+	)
+	WITH (
+		OIDS = FALSE
+	);
+
+	ALTER TABLE public.users_login_failures
+		OWNER to "user";
+
+	CREATE TABLE IF NOT EXISTS public.users_password_resets
+	(
+		id uuid NOT NULL,
+		created_at timestamp with time zone NOT NULL DEFAULT now(),
+		expires_at timestamp with time zone NOT NULL,
+		used_at timestamp with time zone,
+		users_id uuid NOT NULL,
+		token_hash character varying(64) NOT NULL,
+		PRIMARY KEY (id)
+	)
+	WITH (
+		OIDS = FALSE
+	);
+
+	ALTER TABLE public.users_password_resets
+		OWNER to "user";
+
+	CREATE TABLE IF NOT EXISTS public.users_oidc_logins
+	(
+		id uuid NOT NULL,
+		created_at timestamp with time zone NOT NULL DEFAULT now(),
+		expires_at timestamp with time zone NOT NULL,
+		used_at timestamp with time zone,
+		state_hash character varying(64) NOT NULL,
+		nonce character varying(64) NOT NULL,
+		code_verifier character varying(128) NOT NULL,
+		PRIMARY KEY (id)
+	)
+	WITH (
+		OIDS = FALSE
+	);
+
+	ALTER TABLE public.users_oidc_logins
+		OWNER to "user";
+
+	CREATE TABLE IF NOT EXISTS public.users_external_identities
+	(
+		id uuid NOT NULL,
+		created_at timestamp with time zone NOT NULL DEFAULT now(),
+		users_id uuid NOT NULL,
+		issuer character varying(256) NOT NULL,
+		subject character varying(256) NOT NULL,
+		PRIMARY KEY (id)
+	)
+	WITH (
+		OIDS = FALSE
+	);
+
+	ALTER TABLE public.users_external_identities
+		OWNER to "user";
+
+	CREATE TABLE IF NOT EXISTS public.users_api_keys
+	(
+		id uuid NOT NULL,
+		created_at timestamp with time zone NOT NULL DEFAULT now(),
+		last_used_at timestamp with time zone,
+		revoked_at timestamp with time zone,
+		users_id uuid NOT NULL,
+		name character varying(64) NOT NULL,
+		prefix character varying(16) NOT NULL,
+		key_hash character varying(64) NOT NULL,
+		scopes character varying(256) NOT NULL,
+		PRIMARY KEY (id)
+	)
+	WITH (
+		OIDS = FALSE
+	);
+
+	ALTER TABLE public.users_api_keys
+		OWNER to "user";
+
+	CREATE TABLE IF NOT EXISTS public.jwt_keys
+	(
+		id character varying(64) NOT NULL,
+		created_at timestamp with time zone NOT NULL DEFAULT now(),
+		algorithm character varying(16) NOT NULL,
+		private_key text NOT NULL,
+		PRIMARY KEY (id)
+	)
+	WITH (
+		OIDS = FALSE
+	);
+
+	ALTER TABLE public.jwt_keys
+		OWNER to "user";
+
+	CREATE TABLE IF NOT EXISTS public.genres
+	(
+		id uuid NOT NULL,
+		created_at timestamp with time zone NOT NULL DEFAULT now(),
+		name character varying(64) NOT NULL,
+		PRIMARY KEY (id)
+	)
+	WITH (
+		OIDS = FALSE
+	);
+
+	ALTER TABLE public.genres
+		OWNER to "user";
+
+	CREATE TABLE IF NOT EXISTS public.movies_genres
+	(
+		movies_id uuid NOT NULL,
+		genres_id uuid NOT NULL,
+		PRIMARY KEY (movies_id, genres_id)
+	)
+	WITH (
+		OIDS = FALSE
+	);
+
+	ALTER TABLE public.movies_genres
+		OWNER to "user";
+
+	CREATE TABLE IF NOT EXISTS public.tags
+	(
+		id uuid NOT NULL,
+		created_at timestamp with time zone NOT NULL DEFAULT now(),
+		name character varying(32) NOT NULL,
+		PRIMARY KEY (id)
+	)
+	WITH (
+		OIDS = FALSE
+	);
+
+	ALTER TABLE public.tags
+		OWNER to "user";
+
+	CREATE TABLE IF NOT EXISTS public.movies_tags
+	(
+		movies_id uuid NOT NULL,
+		tags_id uuid NOT NULL,
+		PRIMARY KEY (movies_id, tags_id)
+	)
+	WITH (
+		OIDS = FALSE
+	);
+
+	ALTER TABLE public.movies_tags
+		OWNER to "user";
+
+	CREATE TABLE IF NOT EXISTS public.people
+	(
+		id uuid NOT NULL,
+		created_at timestamp with time zone NOT NULL DEFAULT now(),
+		updated_at timestamp with time zone NOT NULL DEFAULT now(),
+		deleted_at timestamp with time zone,
+		users_id uuid NOT NULL,
+		name character varying(128) NOT NULL,
+		biography text NOT NULL DEFAULT '',
+		PRIMARY KEY (id)
+	)
+	WITH (
+		OIDS = FALSE
+	);
+
+	ALTER TABLE public.people
+		OWNER to "user";
+
+	CREATE TABLE IF NOT EXISTS public.movies_credits
+	(
+		id uuid NOT NULL,
+		created_at timestamp with time zone NOT NULL DEFAULT now(),
+		movies_id uuid NOT NULL,
+		people_id uuid NOT NULL,
+		role character varying(32) NOT NULL,
+		character_name character varying(128) NOT NULL DEFAULT '',
+		position integer NOT NULL DEFAULT 0,
+		PRIMARY KEY (id)
+	)
+	WITH (
+		OIDS = FALSE
+	);
+
+	ALTER TABLE public.movies_credits
+		OWNER to "user";
+
+	CREATE TABLE IF NOT EXISTS public.movie_revisions
+	(
+		id uuid NOT NULL,
+		created_at timestamp with time zone NOT NULL DEFAULT now(),
+		movies_id uuid NOT NULL,
+		users_id uuid NOT NULL,
+		action character varying(16) NOT NULL,
+		before jsonb,
+		after jsonb,
+		PRIMARY KEY (id)
+	)
+	WITH (
+		OIDS = FALSE
+	);
+
+	ALTER TABLE public.movie_revisions
+		OWNER to "user";
+
+	CREATE TABLE IF NOT EXISTS public.movies_saves
+	(
+		users_id uuid NOT NULL,
+		movies_id uuid NOT NULL,
+		list character varying(16) NOT NULL,
+		created_at timestamp with time zone NOT NULL DEFAULT now(),
+		PRIMARY KEY (users_id, list, movies_id)
+	)
+	WITH (
+		OIDS = FALSE
+	);
+
+	ALTER TABLE public.movies_saves
+		OWNER to "user";
+
+	CREATE TABLE IF NOT EXISTS public.collections
+	(
+		id uuid NOT NULL,
+		created_at timestamp with time zone NOT NULL DEFAULT now(),
+		updated_at timestamp with time zone NOT NULL DEFAULT now(),
+		users_id uuid NOT NULL,
+		name character varying(128) NOT NULL,
+		description text NOT NULL DEFAULT '',
+		public boolean NOT NULL DEFAULT false,
+		share_token character varying(64) NOT NULL DEFAULT '',
+		PRIMARY KEY (id)
+	)
+	WITH (
+		OIDS = FALSE
+	);
+
+	ALTER TABLE public.collections
+		OWNER to "user";
+
+	CREATE TABLE IF NOT EXISTS public.collections_movies
+	(
+		collections_id uuid NOT NULL,
+		movies_id uuid NOT NULL,
+		position integer NOT NULL DEFAULT 0,
+		created_at timestamp with time zone NOT NULL DEFAULT now(),
+		PRIMARY KEY (collections_id, movies_id)
+	)
+	WITH (
+		OIDS = FALSE
+	);
+
+	ALTER TABLE public.collections_movies
+		OWNER to "user";
+
 	DROP INDEX IF EXISTS movies_id_idx;
 
 	CREATE INDEX movies_id_idx
@@ -227,6 +561,292 @@ func InitTables(dialect goqu.DialectWrapper, db *sql.DB) error {
 		ON public.users USING btree
 		(deleted_at ASC NULLS FIRST)
 		TABLESPACE pg_default;
+
+	DROP INDEX IF EXISTS users_login_failures_email_idx;
+
+	CREATE INDEX users_login_failures_email_idx
+		ON public.users_login_failures USING btree
+		(email, created_at DESC);
+
+	DROP INDEX IF EXISTS users_login_failures_ip_idx;
+
+	CREATE INDEX users_login_failures_ip_idx
+		ON public.users_login_failures USING btree
+		(ip, created_at DESC);
+
+	ALTER TABLE public.users_password_resets
+		DROP CONSTRAINT IF EXISTS users_password_resets_users_id_fkey;
+
+	ALTER TABLE public.users_password_resets
+		ADD CONSTRAINT users_password_resets_users_id_fkey FOREIGN KEY (users_id)
+		REFERENCES public.users (id) MATCH SIMPLE
+		ON UPDATE NO ACTION
+		ON DELETE CASCADE;
+
+	DROP INDEX IF EXISTS users_password_resets_token_hash_idx;
+
+	CREATE UNIQUE INDEX users_password_resets_token_hash_idx
+		ON public.users_password_resets(token_hash);
+
+	DROP INDEX IF EXISTS users_email_idx;
+
+	-- Emails are unique regardless of case
+	CREATE UNIQUE INDEX users_email_idx
+		ON public.users(lower(email))
+		WHERE deleted_at IS NULL;
+
+	ALTER TABLE public.users_email_verifications
+		DROP CONSTRAINT IF EXISTS users_email_verifications_users_id_fkey;
+
+	ALTER TABLE public.users_email_verifications
+		ADD CONSTRAINT users_email_verifications_users_id_fkey FOREIGN KEY (users_id)
+		REFERENCES public.users (id) MATCH SIMPLE
+		ON UPDATE NO ACTION
+		ON DELETE CASCADE;
+
+	DROP INDEX IF EXISTS users_email_verifications_token_hash_idx;
+
+	CREATE UNIQUE INDEX users_email_verifications_token_hash_idx
+		ON public.users_email_verifications(token_hash);
+
+	ALTER TABLE public.users_recovery_codes
+		DROP CONSTRAINT IF EXISTS users_recovery_codes_users_id_fkey;
+
+	ALTER TABLE public.users_recovery_codes
+		ADD CONSTRAINT users_recovery_codes_users_id_fkey FOREIGN KEY (users_id)
+		REFERENCES public.users (id) MATCH SIMPLE
+		ON UPDATE NO ACTION
+		ON DELETE CASCADE;
+
+	DROP INDEX IF EXISTS fki_users_recovery_codes_users_id_fkey;
+
+	CREATE INDEX fki_users_recovery_codes_users_id_fkey
+		ON public.users_recovery_codes(users_id);
+
+	DROP INDEX IF EXISTS users_oidc_logins_state_hash_idx;
+
+	CREATE UNIQUE INDEX users_oidc_logins_state_hash_idx
+		ON public.users_oidc_logins(state_hash);
+
+	ALTER TABLE public.users_external_identities
+		DROP CONSTRAINT IF EXISTS users_external_identities_users_id_fkey;
+
+	ALTER TABLE public.users_external_identities
+		ADD CONSTRAINT users_external_identities_users_id_fkey FOREIGN KEY (users_id)
+		REFERENCES public.users (id) MATCH SIMPLE
+		ON UPDATE NO ACTION
+		ON DELETE CASCADE;
+
+	DROP INDEX IF EXISTS users_external_identities_subject_idx;
+
+	CREATE UNIQUE INDEX users_external_identities_subject_idx
+		ON public.users_external_identities(issuer, subject);
+
+	ALTER TABLE public.users_api_keys
+		DROP CONSTRAINT IF EXISTS users_api_keys_users_id_fkey;
+
+	ALTER TABLE public.users_api_keys
+		ADD CONSTRAINT users_api_keys_users_id_fkey FOREIGN KEY (users_id)
+		REFERENCES public.users (id) MATCH SIMPLE
+		ON UPDATE NO ACTION
+		ON DELETE CASCADE;
+
+	DROP INDEX IF EXISTS fki_users_api_keys_users_id_fkey;
+
+	CREATE INDEX fki_users_api_keys_users_id_fkey
+		ON public.users_api_keys(users_id);
+
+	DROP INDEX IF EXISTS users_api_keys_key_hash_idx;
+
+	CREATE UNIQUE INDEX users_api_keys_key_hash_idx
+		ON public.users_api_keys(key_hash);
+
+	DROP INDEX IF EXISTS genres_name_idx;
+
+	CREATE UNIQUE INDEX genres_name_idx
+		ON public.genres(lower(name));
+
+	ALTER TABLE public.movies_genres
+		DROP CONSTRAINT IF EXISTS movies_genres_movies_id_fkey;
+
+	ALTER TABLE public.movies_genres
+		ADD CONSTRAINT movies_genres_movies_id_fkey FOREIGN KEY (movies_id)
+		REFERENCES public.movies (id) MATCH SIMPLE
+		ON UPDATE NO ACTION
+		ON DELETE CASCADE;
+
+	ALTER TABLE public.movies_genres
+		DROP CONSTRAINT IF EXISTS movies_genres_genres_id_fkey;
+
+	ALTER TABLE public.movies_genres
+		ADD CONSTRAINT movies_genres_genres_id_fkey FOREIGN KEY (genres_id)
+		REFERENCES public.genres (id) MATCH SIMPLE
+		ON UPDATE NO ACTION
+		ON DELETE CASCADE;
+
+	DROP INDEX IF EXISTS fki_movies_genres_genres_id_fkey;
+
+	CREATE INDEX fki_movies_genres_genres_id_fkey
+		ON public.movies_genres(genres_id);
+
+	DROP INDEX IF EXISTS tags_name_idx;
+
+	CREATE UNIQUE INDEX tags_name_idx
+		ON public.tags(name);
+
+	ALTER TABLE public.movies_tags
+		DROP CONSTRAINT IF EXISTS movies_tags_movies_id_fkey;
+
+	ALTER TABLE public.movies_tags
+		ADD CONSTRAINT movies_tags_movies_id_fkey FOREIGN KEY (movies_id)
+		REFERENCES public.movies (id) MATCH SIMPLE
+		ON UPDATE NO ACTION
+		ON DELETE CASCADE;
+
+	ALTER TABLE public.movies_tags
+		DROP CONSTRAINT IF EXISTS movies_tags_tags_id_fkey;
+
+	ALTER TABLE public.movies_tags
+		ADD CONSTRAINT movies_tags_tags_id_fkey FOREIGN KEY (tags_id)
+		REFERENCES public.tags (id) MATCH SIMPLE
+		ON UPDATE NO ACTION
+		ON DELETE CASCADE;
+
+	DROP INDEX IF EXISTS fki_movies_tags_tags_id_fkey;
+
+	CREATE INDEX fki_movies_tags_tags_id_fkey
+		ON public.movies_tags(tags_id);
+
+	ALTER TABLE public.people
+		DROP CONSTRAINT IF EXISTS people_users_id_fkey;
+
+	ALTER TABLE public.people
+		ADD CONSTRAINT people_users_id_fkey FOREIGN KEY (users_id)
+		REFERENCES public.users (id) MATCH SIMPLE
+		ON UPDATE NO ACTION
+		ON DELETE NO ACTION;
+
+	DROP INDEX IF EXISTS people_name_idx;
+
+	CREATE INDEX people_name_idx
+		ON public.people(lower(name));
+
+	ALTER TABLE public.movies_credits
+		DROP CONSTRAINT IF EXISTS movies_credits_movies_id_fkey;
+
+	ALTER TABLE public.movies_credits
+		ADD CONSTRAINT movies_credits_movies_id_fkey FOREIGN KEY (movies_id)
+		REFERENCES public.movies (id) MATCH SIMPLE
+		ON UPDATE NO ACTION
+		ON DELETE CASCADE;
+
+	DROP INDEX IF EXISTS fki_movies_credits_movies_id_fkey;
+
+	CREATE INDEX fki_movies_credits_movies_id_fkey
+		ON public.movies_credits(movies_id);
+
+	ALTER TABLE public.movies_credits
+		DROP CONSTRAINT IF EXISTS movies_credits_people_id_fkey;
+
+	ALTER TABLE public.movies_credits
+		ADD CONSTRAINT movies_credits_people_id_fkey FOREIGN KEY (people_id)
+		REFERENCES public.people (id) MATCH SIMPLE
+		ON UPDATE NO ACTION
+		ON DELETE CASCADE;
+
+	DROP INDEX IF EXISTS fki_movies_credits_people_id_fkey;
+
+	CREATE INDEX fki_movies_credits_people_id_fkey
+		ON public.movies_credits(people_id);
+
+	DROP INDEX IF EXISTS movie_revisions_movies_id_idx;
+
+	CREATE INDEX movie_revisions_movies_id_idx
+		ON public.movie_revisions(movies_id, created_at);
+
+	-- Revisions outlive purged movies, they do not reference the movies table
+	ALTER TABLE public.movie_revisions
+		DROP CONSTRAINT IF EXISTS movie_revisions_movies_id_fkey;
+
+	ALTER TABLE public.movie_revisions
+		DROP CONSTRAINT IF EXISTS movie_revisions_users_id_fkey;
+
+	ALTER TABLE public.movie_revisions
+		ADD CONSTRAINT movie_revisions_users_id_fkey FOREIGN KEY (users_id)
+		REFERENCES public.users (id) MATCH SIMPLE
+		ON UPDATE NO ACTION
+		ON DELETE NO ACTION;
+
+	ALTER TABLE public.movies_saves
+		DROP CONSTRAINT IF EXISTS movies_saves_users_id_fkey;
+
+	ALTER TABLE public.movies_saves
+		ADD CONSTRAINT movies_saves_users_id_fkey FOREIGN KEY (users_id)
+		REFERENCES public.users (id) MATCH SIMPLE
+		ON UPDATE NO ACTION
+		ON DELETE CASCADE;
+
+	ALTER TABLE public.movies_saves
+		DROP CONSTRAINT IF EXISTS movies_saves_movies_id_fkey;
+
+	ALTER TABLE public.movies_saves
+		ADD CONSTRAINT movies_saves_movies_id_fkey FOREIGN KEY (movies_id)
+		REFERENCES public.movies (id) MATCH SIMPLE
+		ON UPDATE NO ACTION
+		ON DELETE CASCADE;
+
+	DROP INDEX IF EXISTS movies_saves_movies_id_idx;
+
+	CREATE INDEX movies_saves_movies_id_idx
+		ON public.movies_saves(movies_id, list);
+
+	DROP INDEX IF EXISTS movies_saves_created_at_idx;
+
+	CREATE INDEX movies_saves_created_at_idx
+		ON public.movies_saves(users_id, list, created_at DESC, movies_id DESC);
+
+	ALTER TABLE public.collections
+		DROP CONSTRAINT IF EXISTS collections_users_id_fkey;
+
+	ALTER TABLE public.collections
+		ADD CONSTRAINT collections_users_id_fkey FOREIGN KEY (users_id)
+		REFERENCES public.users (id) MATCH SIMPLE
+		ON UPDATE NO ACTION
+		ON DELETE CASCADE;
+
+	DROP INDEX IF EXISTS fki_collections_users_id_fkey;
+
+	CREATE INDEX fki_collections_users_id_fkey
+		ON public.collections(users_id);
+
+	DROP INDEX IF EXISTS collections_share_token_idx;
+
+	CREATE UNIQUE INDEX collections_share_token_idx
+		ON public.collections(share_token)
+		WHERE share_token <> '';
+
+	ALTER TABLE public.collections_movies
+		DROP CONSTRAINT IF EXISTS collections_movies_collections_id_fkey;
+
+	ALTER TABLE public.collections_movies
+		ADD CONSTRAINT collections_movies_collections_id_fkey FOREIGN KEY (collections_id)
+		REFERENCES public.collections (id) MATCH SIMPLE
+		ON UPDATE NO ACTION
+		ON DELETE CASCADE;
+
+	ALTER TABLE public.collections_movies
+		DROP CONSTRAINT IF EXISTS collections_movies_movies_id_fkey;
+
+	ALTER TABLE public.collections_movies
+		ADD CONSTRAINT collections_movies_movies_id_fkey FOREIGN KEY (movies_id)
+		REFERENCES public.movies (id) MATCH SIMPLE
+		ON UPDATE NO ACTION
+		ON DELETE CASCADE;
+
+	DROP INDEX IF EXISTS fki_collections_movies_movies_id_fkey;
+
+	CREATE INDEX fki_collections_movies_movies_id_fkey
+		ON public.collections_movies(movies_id);
 	`)
 	if createErr != nil {
 		return createErr
@@ -245,6 +865,7 @@ func InitTables(dialect goqu.DialectWrapper, db *sql.DB) error {
 			"id":                 "d56d4bff-4e7e-4cf9-a3d2-38973c9dd57d",
 			"email":              "test@mail.com",
 			"encrypted_password": encryptedPassword,
+			"verified_at":        goqu.L("now()"),
 		},
 	})
 	if usersSeedErr != nil {
@@ -279,6 +900,69 @@ func InitTables(dialect goqu.DialectWrapper, db *sql.DB) error {
 		return movieSeedErr
 	}
 
+	// Build Genres Table Seed
+	genreSeedErr := seedDB(db, "genres", []interface{}{
+		goqu.Record{
+			"id":   "ca932e4e-afb7-418d-9c85-a2cf98d8af28",
+			"name": "Action",
+		},
+		goqu.Record{
+			"id":   "d1a49c9b-e149-4e40-b1c1-0aea46cfc387",
+			"name": "Adventure",
+		},
+		goqu.Record{
+			"id":   "89ed1008-eb26-4c04-a31b-2c929c58c858",
+			"name": "Animation",
+		},
+		goqu.Record{
+			"id":   "e4f07c40-605a-44fe-aead-4c3a46f9e268",
+			"name": "Comedy",
+		},
+		goqu.Record{
+			"id":   "283af5a7-98af-403f-b16d-b823554c3394",
+			"name": "Crime",
+		},
+		goqu.Record{
+			"id":   "a9ed60c5-7bf6-4b16-a02b-eab8b7c785a4",
+			"name": "Documentary",
+		},
+		goqu.Record{
+			"id":   "8b2fb2c8-e652-48d3-8097-67299eddfb88",
+			"name": "Drama",
+		},
+		goqu.Record{
+			"id":   "6fcb270d-8333-4254-ac3a-9f263be8d19a",
+			"name": "Family",
+		},
+		goqu.Record{
+			"id":   "2574a77d-6024-4bac-b186-d579b566252d",
+			"name": "Fantasy",
+		},
+		goqu.Record{
+			"id":   "11f3cbad-8a01-4042-9be4-cb9bc7a0aa64",
+			"name": "Horror",
+		},
+		goqu.Record{
+			"id":   "e8cb9ef6-46ed-4f59-9f05-e77c5c4e3b88",
+			"name": "Mystery",
+		},
+		goqu.Record{
+			"id":   "03c400b5-294a-45ee-9ced-69cfccfdddec",
+			"name": "Romance",
+		},
+		goqu.Record{
+			"id":   "a3042932-b0e3-4b23-a628-9f2270c66a83",
+			"name": "Science Fiction",
+		},
+		goqu.Record{
+			"id":   "a174fc2d-a694-4647-8b85-dadb5d1e0bd5",
+			"name": "Thriller",
+		},
+	})
+	if genreSeedErr != nil {
+		return genreSeedErr
+	}
+
 	fmt.Println("OK")
 	return nil
 }
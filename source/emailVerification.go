@@ -0,0 +1,166 @@
+package source
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/doug-martin/goqu/v8"
+	"github.com/lib/pq"
+	uuid "github.com/satori/go.uuid"
+
+	"github.com/HencoSmith/graphql-example-go/models"
+)
+
+// ErrInvalidVerificationToken - returned when a verification token does not exist, expired, was
+// already used or belongs to a previous email of the user
+var ErrInvalidVerificationToken = errors.New("Invalid or expired email verification token")
+
+// ErrEmailNotVerified - returned when an unverified user attempts an operation requiring verification
+var ErrEmailNotVerified = errors.New("Email address has not been verified")
+
+// RequestEmailVerification - Store a single-use verification token for the email of the user and
+// email a link containing it, links previously sent to the user stop working
+func RequestEmailVerification(dialect goqu.DialectWrapper, db *sql.DB, user models.User) error {
+	// Read configuration file
+	config := GetConfig(".")
+
+	token, tokenErr := RandomToken()
+	if tokenErr != nil {
+		return tokenErr
+	}
+
+	invalidateDialect := dialect.Update("users_email_verifications").Set(
+		goqu.Record{
+			"used_at": time.Now().Format(time.RFC3339),
+		},
+	).Where(goqu.Ex{
+		"users_id": user.ID,
+		"used_at":  nil,
+	})
+	invalidateQuery, _, invalidateToSQLErr := invalidateDialect.ToSQL()
+	if invalidateToSQLErr != nil {
+		return invalidateToSQLErr
+	}
+	if _, invalidateErr := db.Exec(invalidateQuery); invalidateErr != nil {
+		return invalidateErr
+	}
+
+	insertDialect := dialect.Insert("users_email_verifications").Rows(
+		goqu.Record{
+			"id":         uuid.NewV4(),
+			"users_id":   user.ID,
+			"email":      user.Email,
+			"token_hash": HashToken(token),
+			"expires_at": time.Now().Add(config.Verification.Expiration * time.Minute).Format(time.RFC3339),
+		},
+	)
+	insertQuery, _, toSQLErr := insertDialect.ToSQL()
+	if toSQLErr != nil {
+		return toSQLErr
+	}
+
+	if _, insertErr := db.Exec(insertQuery); insertErr != nil {
+		return insertErr
+	}
+
+	link, linkErr := FormatLink(config.Verification.URL, token)
+	if linkErr != nil {
+		return linkErr
+	}
+
+	return NewMailer(config.Mail).Send(models.Mail{
+		To:      user.Email,
+		Subject: "Verify your email address",
+		Body: "Follow the link below to verify your email address, it expires in " +
+			(config.Verification.Expiration * time.Minute).String() + ":\n" +
+			link + "\n\n" +
+			"If you did not create an account you can ignore this email.",
+	})
+}
+
+// VerifyEmail - Consume the verification token and mark the email it was sent to as the verified
+// email of the associated user, replacing the email if a change was requested
+func VerifyEmail(dialect goqu.DialectWrapper, db *sql.DB, token string) error {
+	tx, txErr := db.Begin()
+	if txErr != nil {
+		return txErr
+	}
+	defer tx.Rollback()
+
+	now := time.Now().Format(time.RFC3339)
+
+	// Mark the token as used, only succeeds once for unexpired tokens
+	consumeDialect := dialect.Update("users_email_verifications").Set(
+		goqu.Record{
+			"used_at": now,
+		},
+	).Where(
+		goqu.Ex{
+			"token_hash": HashToken(token),
+			"used_at":    nil,
+		},
+		goqu.C("expires_at").Gt(now),
+	).Returning("users_id", "email")
+	consumeQuery, _, toSQLErr := consumeDialect.ToSQL()
+	if toSQLErr != nil {
+		return toSQLErr
+	}
+
+	var usersID, email string
+	scanErr := tx.QueryRow(consumeQuery).Scan(&usersID, &email)
+	if scanErr == sql.ErrNoRows {
+		return ErrInvalidVerificationToken
+	}
+	if scanErr != nil {
+		return scanErr
+	}
+
+	updateDialect := dialect.Update("users").Set(
+		goqu.Record{
+			"email":       email,
+			"verified_at": now,
+			"updated_at":  now,
+		},
+	).Where(goqu.Ex{
+		"id":         usersID,
+		"deleted_at": nil,
+	})
+	updateQuery, _, updateToSQLErr := updateDialect.ToSQL()
+	if updateToSQLErr != nil {
+		return updateToSQLErr
+	}
+
+	// The address may have been registered by another account since the link was sent
+	updateRes, updateErr := tx.Exec(updateQuery)
+	if pqErr, ok := updateErr.(*pq.Error); ok && pqErr.Code == "23505" {
+		return ErrEmailTaken
+	}
+	if updateErr != nil {
+		return updateErr
+	}
+	if updated, _ := updateRes.RowsAffected(); updated < 1 {
+		return ErrInvalidVerificationToken
+	}
+
+	return tx.Commit()
+}
+
+// RequireVerified - Check the verification policy for the operation (GraphQL field name), every
+// operation requires a verified email unless it is exempt, returns ErrEmailNotVerified otherwise
+func RequireVerified(user models.User, operation string) error {
+	if user.VerifiedAt != nil {
+		return nil
+	}
+
+	// Read configuration file
+	config := GetConfig(".")
+
+	for _, exempt := range config.Verification.Exempt {
+		if exempt == operation {
+			return nil
+		}
+	}
+
+	return ErrEmailNotVerified
+}
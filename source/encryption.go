@@ -1,43 +1,57 @@
 package source
 
 import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"os"
 	"time"
 
 	"github.com/dgrijalva/jwt-go"
-	"golang.org/x/crypto/bcrypt"
 )
 
 // Claims associated with the JWT data stored
 type Claims struct {
-	UserID string `json:"userID"`
+	UserID     string `json:"userID"`
+	Purpose    string `json:"purpose,omitempty"`
+	AuthMethod string `json:"authMethod,omitempty"`
 	jwt.StandardClaims
 }
 
-// Hash - Hash the text using bcrypt, return the hash or an error
-func Hash(text string) (string, error) {
-	hash, err := bcrypt.GenerateFromPassword([]byte(text), bcrypt.MinCost)
-	if err != nil {
+// RandomToken - Generate a random URL safe token with 256 bits of entropy
+func RandomToken() (string, error) {
+	buff := make([]byte, 32)
+	if _, err := rand.Read(buff); err != nil {
 		return "", err
 	}
-
-	return string(hash), nil
+	return base64.RawURLEncoding.EncodeToString(buff), nil
 }
 
-// ValidHash - Compare the hash and text using bcrypt, returns true if valid or false along
-// with an error if invalid
-func ValidHash(text string, hashedText string) (bool, error) {
-	err := bcrypt.CompareHashAndPassword([]byte(hashedText), []byte(text))
-	if err != nil {
-		return false, err
-	}
-	return true, nil
+// HashToken - Hash a random token using SHA-256 for storage, tokens carry enough entropy
+// that a slow password hash is not required
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
 }
 
-// CreateJWT return a JWT token for the given user ID input
-func CreateJWT(userID string) (string, error) {
+// ChallengePurpose - purpose of tokens only exchangeable for a JWT along with a second factor
+const ChallengePurpose = "2fa"
+
+// challengeExpiration - how long a second factor challenge may be exchanged
+const challengeExpiration = 5 * time.Minute
+
+// OIDCAuthMethod - authentication method of JWTs issued by OpenID Connect logins
+const OIDCAuthMethod = "oidc"
+
+// reauthenticationWindow - how long after an OpenID Connect login its JWT proves the identity of
+// the user for irreversible changes
+const reauthenticationWindow = 5 * time.Minute
+
+// jwtKey - Lookup the key used to sign and verify HS256 JWTs, the environment overrides the configuration
+func jwtKey() []byte {
 	// Read configuration file
 	config := GetConfig(".")
 
@@ -46,43 +60,152 @@ func CreateJWT(userID string) (string, error) {
 	if len(envKey) != 0 {
 		key = envKey
 	}
+	return []byte(key)
+}
+
+// challengeKey - Key signing second factor challenges. It is derived from the HS256 key and never
+// published, so challenges cannot pass as tokens wherever tokens are verified, be it against the
+// JWKS or the shared HS256 key
+func challengeKey() []byte {
+	sum := sha256.Sum256(append([]byte("2fa-challenge:"), jwtKey()...))
+	return sum[:]
+}
+
+// signJWT - Sign the claims with the current signing key and return the token string
+func signJWT(claims *Claims) (string, error) {
+	if signingKeys == nil {
+		// Create the token
+		token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+		// Generate the token string
+		return token.SignedString(jwtKey())
+	}
+
+	key, keyErr := signingKeys.current()
+	if keyErr != nil {
+		return "", keyErr
+	}
+
+	token := jwt.NewWithClaims(signingMethod(key.Algorithm), claims)
+	// Identify the key so it can be verified after rotation
+	token.Header["kid"] = key.ID
+	return token.SignedString(key.Private)
+}
+
+// decodeClaims - Verify the token and return the claims stored in it
+func decodeClaims(JWT string) (*Claims, error) {
+	token, err := jwt.ParseWithClaims(JWT, &Claims{}, func(token *jwt.Token) (interface{}, error) {
+		if signingKeys == nil {
+			_, ok := token.Method.(*jwt.SigningMethodHMAC)
+			if !ok {
+				return nil, fmt.Errorf("Unexpected token signing method: %v", token.Header["alg"])
+			}
+			return jwtKey(), nil
+		}
+
+		id, _ := token.Header["kid"].(string)
+		key, ok := signingKeys.lookup(id)
+		if !ok {
+			return nil, fmt.Errorf("Unknown token signing key: %v", token.Header["kid"])
+		}
+		// The algorithm is bound to the key, never trust the header alone
+		if token.Method.Alg() != key.Algorithm {
+			return nil, fmt.Errorf("Unexpected token signing method: %v", token.Header["alg"])
+		}
+		return key.Private.Public(), nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	if claims, ok := token.Claims.(*Claims); ok && token.Valid {
+		return claims, nil
+	}
+
+	return nil, errors.New("Invalid token")
+}
+
+// CreateJWT return a JWT token for the given user ID input
+func CreateJWT(userID string) (string, error) {
+	return createJWT(userID, "")
+}
+
+// CreateOIDCJWT return a JWT token for the given user ID input logged in through OpenID Connect
+func CreateOIDCJWT(userID string) (string, error) {
+	return createJWT(userID, OIDCAuthMethod)
+}
+
+// createJWT - Sign a JWT for the user recording how and when the user logged in
+func createJWT(userID string, method string) (string, error) {
+	// Read configuration file
+	config := GetConfig(".")
+
 	// Setup data to be stored in the token
-	claims := &Claims{
-		UserID: userID,
+	now := time.Now()
+	return signJWT(&Claims{
+		UserID:     userID,
+		AuthMethod: method,
 		StandardClaims: jwt.StandardClaims{
-			ExpiresAt: time.Now().Add(config.JWT.Expiration * time.Hour).Unix(),
+			IssuedAt:  now.Unix(),
+			ExpiresAt: now.Add(config.JWT.Expiration * time.Hour).Unix(),
 		},
+	})
+}
+
+// RecentOIDCLogin - Whether the JWT was issued by an OpenID Connect login within the last
+// reauthenticationWindow, proving the user just authenticated at the provider
+func RecentOIDCLogin(JWT string) bool {
+	claims, err := decodeClaims(JWT)
+	if err != nil || len(claims.Purpose) > 0 || claims.AuthMethod != OIDCAuthMethod {
+		return false
 	}
-	// Create the token
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	// Generate the token string
+	return time.Since(time.Unix(claims.IssuedAt, 0)) <= reauthenticationWindow
+}
 
-	signingKey := []byte(key)
-	return token.SignedString(signingKey)
+// CreateChallengeJWT return a short-lived token for the given user ID input which can only be
+// exchanged for a JWT along with a second factor
+func CreateChallengeJWT(userID string) (string, error) {
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, &Claims{
+		UserID:  userID,
+		Purpose: ChallengePurpose,
+		StandardClaims: jwt.StandardClaims{
+			ExpiresAt: time.Now().Add(challengeExpiration).Unix(),
+		},
+	})
+	return token.SignedString(challengeKey())
 }
 
 // DecodeJWT decode the specified token and return the associated user ID
 func DecodeJWT(JWT string) (string, error) {
-	// Read configuration file
-	config := GetConfig(".")
+	claims, err := decodeClaims(JWT)
+	if err != nil {
+		return "", err
+	}
 
-	key := config.JWT.Key
+	// Challenges do not grant access by themselves
+	if len(claims.Purpose) > 0 {
+		return "", errors.New("Invalid token")
+	}
+
+	return claims.UserID, nil
+}
 
+// DecodeChallengeJWT decode the specified second factor challenge and return the associated user ID
+func DecodeChallengeJWT(JWT string) (string, error) {
 	token, err := jwt.ParseWithClaims(JWT, &Claims{}, func(token *jwt.Token) (interface{}, error) {
-		_, ok := token.Method.(*jwt.SigningMethodHMAC)
-		if !ok {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
 			return nil, fmt.Errorf("Unexpected token signing method: %v", token.Header["alg"])
 		}
-		return []byte(key), nil
+		return challengeKey(), nil
 	})
-
 	if err != nil {
 		return "", err
 	}
 
-	if claims, ok := token.Claims.(*Claims); ok && token.Valid {
-		return claims.UserID, nil
+	claims, ok := token.Claims.(*Claims)
+	if !ok || !token.Valid || claims.Purpose != ChallengePurpose {
+		return "", errors.New("Invalid challenge")
 	}
 
-	return "", errors.New("Invalid token")
+	return claims.UserID, nil
 }
@@ -0,0 +1,82 @@
+package source
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"testing"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/stretchr/testify/assert"
+)
+
+// assertChallengeSeparate - Challenges and tokens must only decode as what they are
+func assertChallengeSeparate(t *testing.T) {
+	token, err := CreateJWT("d56d4bff-4e7e-4cf9-a3d2-38973c9dd57d")
+	if err != nil {
+		t.Fatal(err)
+	}
+	challenge, err := CreateChallengeJWT("d56d4bff-4e7e-4cf9-a3d2-38973c9dd57d")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	userID, err := DecodeJWT(token)
+	assert.NoError(t, err)
+	assert.Equal(t, "d56d4bff-4e7e-4cf9-a3d2-38973c9dd57d", userID)
+	userID, err = DecodeChallengeJWT(challenge)
+	assert.NoError(t, err)
+	assert.Equal(t, "d56d4bff-4e7e-4cf9-a3d2-38973c9dd57d", userID)
+
+	_, err = DecodeJWT(challenge)
+	assert.Error(t, err, "Challenges must not grant access")
+	_, err = DecodeChallengeJWT(token)
+	assert.Error(t, err, "Tokens must not pass as challenges")
+}
+
+func TestChallengeHS256(t *testing.T) {
+	assertChallengeSeparate(t)
+
+	// Services sharing the HS256 key cannot verify challenges either
+	challenge, err := CreateChallengeJWT("d56d4bff-4e7e-4cf9-a3d2-38973c9dd57d")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = jwt.Parse(challenge, func(token *jwt.Token) (interface{}, error) {
+		return jwtKey(), nil
+	})
+	assert.Error(t, err)
+}
+
+func TestChallengeJWKS(t *testing.T) {
+	private, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signingKeys = &keyStore{
+		algorithm: jwt.SigningMethodES256.Alg(),
+		keys: []signingKey{{
+			ID:        "test",
+			Algorithm: jwt.SigningMethodES256.Alg(),
+			CreatedAt: time.Now().Add(-24 * time.Hour),
+			Private:   private,
+		}},
+		refreshedAt: time.Now(),
+	}
+	defer func() {
+		signingKeys = nil
+	}()
+
+	assertChallengeSeparate(t)
+
+	// A service verifying signatures against the published keys only must refuse challenges
+	challenge, err := CreateChallengeJWT("d56d4bff-4e7e-4cf9-a3d2-38973c9dd57d")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = jwt.Parse(challenge, func(token *jwt.Token) (interface{}, error) {
+		return &private.PublicKey, nil
+	})
+	assert.Error(t, err)
+}
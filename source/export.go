@@ -0,0 +1,331 @@
+package source
+
+import (
+	"bufio"
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/doug-martin/goqu/v8"
+	"github.com/doug-martin/goqu/v8/exp"
+
+	"github.com/HencoSmith/graphql-example-go/models"
+)
+
+// Formats data is exported in
+const (
+	// ExportCSV - comma separated values with a header row naming the columns
+	ExportCSV = "csv"
+	// ExportJSONL - JSON Lines, one object per row
+	ExportJSONL = "jsonl"
+	// ExportParquet - Apache Parquet, uncompressed
+	ExportParquet = "parquet"
+)
+
+// Kinds of values of exported columns, determining their Go type and how they are encoded
+const (
+	// ExportString - string
+	ExportString = iota
+	// ExportInt - int64
+	ExportInt
+	// ExportFloat - float64
+	ExportFloat
+	// ExportTime - time.Time, exported in UTC
+	ExportTime
+)
+
+// ErrUnknownExportFormat - returned when the format of an export is neither CSV, JSON Lines nor
+// Parquet
+var ErrUnknownExportFormat = errors.New("Unknown export format, expected csv, jsonl or parquet")
+
+// ErrUnknownDataset - returned when the exported dataset does not exist
+var ErrUnknownDataset = errors.New("Unknown dataset, expected movies, reviews or users")
+
+// ExportColumn - A column of the export, named like the column of the table it is read from
+type ExportColumn struct {
+	Name string
+	Kind int
+}
+
+// exportDataset - Table exported and the columns read from it
+type exportDataset struct {
+	Table   string
+	Columns []ExportColumn
+	// AdminOnly - the dataset holds personal data of other users
+	AdminOnly bool
+}
+
+// exportDatasets - datasets which can be exported by name
+var exportDatasets = map[string]exportDataset{
+	"movies": {
+		Table: "movies",
+		Columns: []ExportColumn{
+			{Name: "id", Kind: ExportString},
+			{Name: "created_at", Kind: ExportTime},
+			{Name: "updated_at", Kind: ExportTime},
+			{Name: "users_id", Kind: ExportString},
+			{Name: "name", Kind: ExportString},
+			{Name: "release_year", Kind: ExportInt},
+			{Name: "description", Kind: ExportString},
+			{Name: "rating", Kind: ExportFloat},
+			{Name: "review_count", Kind: ExportInt},
+			{Name: "version", Kind: ExportInt},
+		},
+	},
+	"reviews": {
+		Table: "movies_reviews",
+		Columns: []ExportColumn{
+			{Name: "id", Kind: ExportString},
+			{Name: "created_at", Kind: ExportTime},
+			{Name: "updated_at", Kind: ExportTime},
+			{Name: "movies_id", Kind: ExportString},
+			{Name: "users_id", Kind: ExportString},
+			{Name: "rating", Kind: ExportFloat},
+		},
+	},
+	"users": {
+		Table: "users",
+		Columns: []ExportColumn{
+			{Name: "id", Kind: ExportString},
+			{Name: "created_at", Kind: ExportTime},
+			{Name: "email", Kind: ExportString},
+			{Name: "display_name", Kind: ExportString},
+			{Name: "role", Kind: ExportString},
+			{Name: "verified_at", Kind: ExportTime},
+		},
+		AdminOnly: true,
+	},
+}
+
+// ExportContentTypes - media types of the export formats
+var ExportContentTypes = map[string]string{
+	ExportCSV:     "text/csv; charset=utf-8",
+	ExportJSONL:   "application/x-ndjson; charset=utf-8",
+	ExportParquet: "application/vnd.apache.parquet",
+}
+
+// rowWriter - Writes the rows of an export one at a time, Close completes the file
+type rowWriter interface {
+	Write(values []interface{}) error
+	Close() error
+}
+
+// csvRowWriter - Writes rows as CSV, nil values are left empty
+type csvRowWriter struct {
+	writer *csv.Writer
+	record []string
+}
+
+// Write - Write the row as a record
+func (w *csvRowWriter) Write(values []interface{}) error {
+	for i, value := range values {
+		switch value := value.(type) {
+		case nil:
+			w.record[i] = ""
+		case string:
+			w.record[i] = value
+		case int64:
+			w.record[i] = strconv.FormatInt(value, 10)
+		case float64:
+			w.record[i] = strconv.FormatFloat(value, 'f', -1, 64)
+		case time.Time:
+			w.record[i] = value.UTC().Format(time.RFC3339Nano)
+		}
+	}
+	return w.writer.Write(w.record)
+}
+
+// Close - Flush the buffered records
+func (w *csvRowWriter) Close() error {
+	w.writer.Flush()
+	return w.writer.Error()
+}
+
+// jsonlRowWriter - Writes rows as JSON objects keeping the order of the columns
+type jsonlRowWriter struct {
+	writer  *bufio.Writer
+	columns []ExportColumn
+}
+
+// Write - Write the row as a line
+func (w *jsonlRowWriter) Write(values []interface{}) error {
+	w.writer.WriteByte('{')
+	for i, value := range values {
+		if i > 0 {
+			w.writer.WriteByte(',')
+		}
+		name, _ := json.Marshal(w.columns[i].Name)
+		w.writer.Write(name)
+		w.writer.WriteByte(':')
+
+		if timeValue, ok := value.(time.Time); ok {
+			value = timeValue.UTC().Format(time.RFC3339Nano)
+		}
+		encoded, marshalErr := json.Marshal(value)
+		if marshalErr != nil {
+			return marshalErr
+		}
+		w.writer.Write(encoded)
+	}
+	w.writer.WriteByte('}')
+	return w.writer.WriteByte('\n')
+}
+
+// Close - Flush the buffered lines
+func (w *jsonlRowWriter) Close() error {
+	return w.writer.Flush()
+}
+
+// newRowWriter - Create the writer of the format, writing the header if the format has one
+func newRowWriter(format string, out io.Writer, columns []ExportColumn) (rowWriter, error) {
+	switch format {
+	case ExportCSV:
+		writer := &csvRowWriter{writer: csv.NewWriter(out), record: make([]string, len(columns))}
+		for i, column := range columns {
+			writer.record[i] = column.Name
+		}
+		if headerErr := writer.writer.Write(writer.record); headerErr != nil {
+			return nil, headerErr
+		}
+		return writer, nil
+	case ExportJSONL:
+		return &jsonlRowWriter{writer: bufio.NewWriter(out), columns: columns}, nil
+	case ExportParquet:
+		return newParquetWriter(out, columns)
+	default:
+		return nil, ErrUnknownExportFormat
+	}
+}
+
+// scanDestination - Value a column of the kind is scanned into, pointers stay nil for NULL
+func scanDestination(kind int) interface{} {
+	switch kind {
+	case ExportInt:
+		return new(*int64)
+	case ExportFloat:
+		return new(*float64)
+	case ExportTime:
+		return new(*time.Time)
+	default:
+		return new(*string)
+	}
+}
+
+// scannedValue - Value of the destination passed to the row writer, nil for NULL
+func scannedValue(destination interface{}) interface{} {
+	switch value := destination.(type) {
+	case **int64:
+		if *value != nil {
+			return **value
+		}
+	case **float64:
+		if *value != nil {
+			return **value
+		}
+	case **time.Time:
+		if *value != nil {
+			return **value
+		}
+	case **string:
+		if *value != nil {
+			return **value
+		}
+	}
+	return nil
+}
+
+// CheckExport - Determine whether the user may export the dataset in the format
+func CheckExport(user models.User, dataset string, format string) error {
+	definition, ok := exportDatasets[dataset]
+	if !ok {
+		return ErrUnknownDataset
+	}
+	if _, ok := ExportContentTypes[format]; !ok {
+		return ErrUnknownExportFormat
+	}
+	if definition.AdminOnly && !IsAdmin(user) {
+		return ErrForbidden
+	}
+	return nil
+}
+
+// ExportData - Stream the rows of the dataset to the writer in the format. Movies and reviews are
+// limited to those the movie list shows for the genre and tag, reviews being those of the listed
+// movies, users are not filtered. Rows are read one at a time rather than loading the table
+// dialect - Query builder dialect object used
+// db - SQL DB connection to use
+// dataset - 'movies', 'reviews' or 'users'
+// format - ExportCSV, ExportJSONL or ExportParquet
+// genre - Name of the genre, empty for movies of any genre
+// tag - Tag the movies are labelled with, empty for movies with any tags
+// out - Writer receiving the file
+func ExportData(dialect goqu.DialectWrapper, db *sql.DB, dataset string, format string, genre string, tag string, out io.Writer) error {
+	definition, ok := exportDatasets[dataset]
+	if !ok {
+		return ErrUnknownDataset
+	}
+
+	columns := []interface{}{}
+	for _, column := range definition.Columns {
+		columns = append(columns, goqu.I(definition.Table+"."+column.Name))
+	}
+
+	var filter exp.Expression
+	movies := MovieListFilter(dialect, genre, tag)
+	switch dataset {
+	case "movies":
+		filter = movies
+	case "reviews":
+		filter = goqu.And(
+			goqu.Ex{"movies_reviews.deleted_at": nil},
+			goqu.I("movies_reviews.movies_id").In(dialect.From("movies").Select("movies.id").Where(movies)),
+		)
+	default:
+		filter = goqu.Ex{definition.Table + ".deleted_at": nil}
+	}
+
+	selectDialect := dialect.From(definition.Table).Select(columns...).Where(filter).Order(
+		goqu.I(definition.Table + ".id").Asc(),
+	)
+	selectQuery, _, toSQLErr := selectDialect.ToSQL()
+	if toSQLErr != nil {
+		return toSQLErr
+	}
+
+	rows, queryErr := db.Query(selectQuery)
+	if queryErr != nil {
+		return queryErr
+	}
+	defer rows.Close()
+
+	writer, writerErr := newRowWriter(format, out, definition.Columns)
+	if writerErr != nil {
+		return writerErr
+	}
+
+	destinations := make([]interface{}, len(definition.Columns))
+	values := make([]interface{}, len(definition.Columns))
+	for rows.Next() {
+		for i, column := range definition.Columns {
+			destinations[i] = scanDestination(column.Kind)
+		}
+		if scanErr := rows.Scan(destinations...); scanErr != nil {
+			return scanErr
+		}
+		for i, destination := range destinations {
+			values[i] = scannedValue(destination)
+		}
+		if writeErr := writer.Write(values); writeErr != nil {
+			return writeErr
+		}
+	}
+	if errRows := rows.Err(); errRows != nil {
+		return errRows
+	}
+
+	return writer.Close()
+}
@@ -0,0 +1,203 @@
+package source
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/jpeg"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+
+	// Register the decoders of the supported image formats
+	_ "image/gif"
+	_ "image/png"
+
+	uuid "github.com/satori/go.uuid"
+
+	"github.com/HencoSmith/graphql-example-go/models"
+)
+
+// thumbnailQuality - JPEG quality of generated thumbnails
+const thumbnailQuality = 85
+
+// imageExtensions - file extensions of the image content types
+var imageExtensions = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+	"image/gif":  ".gif",
+}
+
+// ErrUploadTooLarge - returned when an uploaded file exceeds the configured size
+var ErrUploadTooLarge = errors.New("Uploaded file is too large")
+
+// ErrUnsupportedImage - returned when an uploaded file is not an image of an allowed type
+var ErrUnsupportedImage = errors.New("Uploaded file is not a supported image")
+
+// ReadImage - Read and validate an uploaded image against the configured size, types and
+// dimensions. The content type is determined from the data rather than trusted from the client,
+// returns the data, its content type and file extension along with the decoded image
+func ReadImage(upload models.Upload) ([]byte, string, string, image.Image, error) {
+	// Read configuration file
+	config := GetConfig(".")
+	maxSize := config.Upload.MaxSize * 1024
+
+	if upload.File == nil {
+		return nil, "", "", nil, errors.New("No file uploaded")
+	}
+	if maxSize > 0 && upload.Size > maxSize {
+		return nil, "", "", nil, ErrUploadTooLarge
+	}
+
+	reader := io.Reader(upload.File)
+	if maxSize > 0 {
+		reader = io.LimitReader(upload.File, maxSize+1)
+	}
+	data, readErr := ioutil.ReadAll(reader)
+	if readErr != nil {
+		return nil, "", "", nil, readErr
+	}
+	if maxSize > 0 && int64(len(data)) > maxSize {
+		return nil, "", "", nil, ErrUploadTooLarge
+	}
+
+	contentType := http.DetectContentType(data)
+	allowed := false
+	for _, allowedType := range config.Upload.Types {
+		allowed = allowed || allowedType == contentType
+	}
+	extension, known := imageExtensions[contentType]
+	if !allowed || !known {
+		return nil, "", "", nil, ErrUnsupportedImage
+	}
+
+	// Check the dimensions before decoding, a small file may describe a huge image
+	imageConfig, _, configErr := image.DecodeConfig(bytes.NewReader(data))
+	if configErr != nil {
+		return nil, "", "", nil, ErrUnsupportedImage
+	}
+	maxDimension := config.Upload.MaxDimension
+	if maxDimension > 0 && (imageConfig.Width > maxDimension || imageConfig.Height > maxDimension) {
+		return nil, "", "", nil, errors.New("Images must not exceed " + strconv.Itoa(maxDimension) + " pixels in width or height")
+	}
+	maxPixels := config.Upload.MaxPixels
+	if maxPixels > 0 && int64(imageConfig.Width)*int64(imageConfig.Height) > maxPixels {
+		return nil, "", "", nil, errors.New("Images must not exceed " + strconv.FormatInt(maxPixels, 10) + " pixels in total")
+	}
+
+	decoded, _, decodeErr := image.Decode(bytes.NewReader(data))
+	if decodeErr != nil {
+		return nil, "", "", nil, ErrUnsupportedImage
+	}
+
+	return data, contentType, extension, decoded, nil
+}
+
+// StoreImage - Validate the uploaded image and store it along with its thumbnail under the
+// prefix, returns the keys of the image and the thumbnail
+func StoreImage(upload models.Upload, prefix string) (string, string, error) {
+	storage := GetBlobStorage()
+	if storage == nil {
+		return "", "", errors.New("File storage is not configured")
+	}
+
+	data, contentType, extension, decoded, readErr := ReadImage(upload)
+	if readErr != nil {
+		return "", "", readErr
+	}
+	thumbnail, thumbnailErr := Thumbnail(decoded, GetConfig(".").Upload.ThumbnailWidth)
+	if thumbnailErr != nil {
+		return "", "", thumbnailErr
+	}
+
+	// Random names keep cached copies of a replaced image from being served
+	name := prefix + "/" + uuid.NewV4().String()
+	imageKey := name + extension
+	thumbnailKey := name + "-thumbnail.jpg"
+	if putErr := storage.Put(imageKey, contentType, bytes.NewReader(data)); putErr != nil {
+		return "", "", putErr
+	}
+	if putErr := storage.Put(thumbnailKey, "image/jpeg", bytes.NewReader(thumbnail)); putErr != nil {
+		DeleteBlobs(imageKey)
+		return "", "", putErr
+	}
+
+	return imageKey, thumbnailKey, nil
+}
+
+// Thumbnail - Scale the image down to the width keeping its aspect ratio, each pixel averaging
+// the area of the image it covers. Transparent areas are filled with white, returns the
+// thumbnail encoded as JPEG
+func Thumbnail(source image.Image, width int) ([]byte, error) {
+	bounds := source.Bounds()
+	if bounds.Dx() < 1 || bounds.Dy() < 1 {
+		return nil, ErrUnsupportedImage
+	}
+
+	sourceWidth, sourceHeight := bounds.Dx(), bounds.Dy()
+	if width < 1 || width > sourceWidth {
+		width = sourceWidth
+	}
+	height := sourceHeight * width / sourceWidth
+	if height < 1 {
+		height = 1
+	}
+
+	// Columns of the source each thumbnail column averages
+	lefts := make([]int, width)
+	rights := make([]int, width)
+	for x := 0; x < width; x++ {
+		lefts[x] = x * sourceWidth / width
+		rights[x] = (x + 1) * sourceWidth / width
+		if rights[x] <= lefts[x] {
+			rights[x] = lefts[x] + 1
+		}
+	}
+
+	// Only a single row of the source is flattened onto white at a time, RGBA gives direct
+	// access to the pixels without copying the whole image
+	row := image.NewRGBA(image.Rect(0, 0, sourceWidth, 1))
+	sums := make([]int, width*3)
+	scaled := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		top := y * sourceHeight / height
+		bottom := (y + 1) * sourceHeight / height
+		if bottom <= top {
+			bottom = top + 1
+		}
+
+		for i := range sums {
+			sums[i] = 0
+		}
+		for sy := top; sy < bottom; sy++ {
+			draw.Draw(row, row.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+			draw.Draw(row, row.Bounds(), source, image.Pt(bounds.Min.X, bounds.Min.Y+sy), draw.Over)
+			for x := 0; x < width; x++ {
+				for offset := lefts[x] * 4; offset < rights[x]*4; offset += 4 {
+					sums[x*3] += int(row.Pix[offset])
+					sums[x*3+1] += int(row.Pix[offset+1])
+					sums[x*3+2] += int(row.Pix[offset+2])
+				}
+			}
+		}
+
+		for x := 0; x < width; x++ {
+			count := (bottom - top) * (rights[x] - lefts[x])
+			scaled.SetRGBA(x, y, color.RGBA{
+				R: uint8(sums[x*3] / count),
+				G: uint8(sums[x*3+1] / count),
+				B: uint8(sums[x*3+2] / count),
+				A: 255,
+			})
+		}
+	}
+
+	var encoded bytes.Buffer
+	if encodeErr := jpeg.Encode(&encoded, scaled, &jpeg.Options{Quality: thumbnailQuality}); encodeErr != nil {
+		return nil, encodeErr
+	}
+	return encoded.Bytes(), nil
+}
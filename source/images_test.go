@@ -0,0 +1,95 @@
+package source
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/HencoSmith/graphql-example-go/models"
+)
+
+// memoryFile - Uploaded file kept in memory
+type memoryFile struct {
+	*bytes.Reader
+}
+
+func (memoryFile) Close() error {
+	return nil
+}
+
+// pngUpload - Encode the image as a PNG upload
+func pngUpload(t *testing.T, img image.Image) models.Upload {
+	var encoded bytes.Buffer
+	if err := png.Encode(&encoded, img); err != nil {
+		t.Fatal(err)
+	}
+	return models.Upload{
+		Filename: "poster.png",
+		Size:     int64(encoded.Len()),
+		File:     memoryFile{bytes.NewReader(encoded.Bytes())},
+	}
+}
+
+func TestReadImagePixelLimit(t *testing.T) {
+	img := image.NewGray(image.Rect(0, 0, 200, 100))
+
+	_, contentType, extension, decoded, err := ReadImage(pngUpload(t, img))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "image/png", contentType)
+	assert.Equal(t, ".png", extension)
+	assert.Equal(t, img.Bounds(), decoded.Bounds())
+
+	// Both dimensions are allowed on their own but not their product
+	defer withConfig("upload.maxPixels", 19999)()
+	_, _, _, _, err = ReadImage(pngUpload(t, img))
+	assert.EqualError(t, err, "Images must not exceed 19999 pixels in total")
+}
+
+func TestThumbnail(t *testing.T) {
+	// Transparent on the left, opaque red on the right, offset to check the bounds are honoured
+	img := image.NewNRGBA(image.Rect(10, 10, 74, 42))
+	for y := 10; y < 42; y++ {
+		for x := 42; x < 74; x++ {
+			img.SetNRGBA(x, y, color.NRGBA{R: 255, A: 255})
+		}
+	}
+
+	encoded, err := Thumbnail(img, 16)
+	if err != nil {
+		t.Fatal(err)
+	}
+	thumbnail, err := jpeg.Decode(bytes.NewReader(encoded))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, image.Rect(0, 0, 16, 8), thumbnail.Bounds(), "Aspect ratio should be kept")
+
+	// JPEG compression is lossy, compare approximately
+	near := func(expected uint8, actual uint32) bool {
+		difference := int(expected) - int(actual>>8)
+		return difference > -24 && difference < 24
+	}
+	r, g, b, _ := thumbnail.At(2, 4).RGBA()
+	assert.True(t, near(255, r) && near(255, g) && near(255, b), "Transparent areas should be white, got %d %d %d", r>>8, g>>8, b>>8)
+	r, g, b, _ = thumbnail.At(13, 4).RGBA()
+	assert.True(t, near(255, r) && near(0, g) && near(0, b), "Opaque areas should be kept, got %d %d %d", r>>8, g>>8, b>>8)
+
+	// Images narrower than the width are not scaled up
+	encoded, err = Thumbnail(img, 200)
+	if err != nil {
+		t.Fatal(err)
+	}
+	config, err := jpeg.DecodeConfig(bytes.NewReader(encoded))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, 64, config.Width)
+	assert.Equal(t, 32, config.Height)
+}
@@ -0,0 +1,170 @@
+package source
+
+import (
+	"database/sql"
+	"errors"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/doug-martin/goqu/v8"
+	uuid "github.com/satori/go.uuid"
+
+	config "github.com/HencoSmith/graphql-example-go/config/struct"
+	"github.com/HencoSmith/graphql-example-go/models"
+)
+
+// ErrInvalidCredentials - returned for every failed login so responses do not reveal whether
+// the email exists
+var ErrInvalidCredentials = errors.New("Invalid email or password")
+
+// ErrLoginLocked - returned while the account or IP address is locked after too many failures
+var ErrLoginLocked = errors.New("Too many failed login attempts, try again later")
+
+var dummyHash string
+var dummyHashOnce sync.Once
+
+// countFailures - Count the failed logins matching the expression since the specified time,
+// returns the count and the time of the latest failure
+func countFailures(dialect goqu.DialectWrapper, db *sql.DB, expression goqu.Ex, since time.Time) (int64, *time.Time, error) {
+	dialectString := dialect.From("users_login_failures").Select(
+		goqu.COUNT("id"),
+		goqu.MAX("created_at"),
+	).Where(expression, goqu.C("created_at").Gt(since))
+	query, _, dialectErr := dialectString.ToSQL()
+	if dialectErr != nil {
+		return 0, nil, dialectErr
+	}
+
+	var count int64
+	var latest *time.Time
+	scanErr := db.QueryRow(query).Scan(&count, &latest)
+	if scanErr != nil {
+		return 0, nil, scanErr
+	}
+
+	return count, latest, nil
+}
+
+// CheckLogin - Determine whether a login attempt for the email from the IP address may proceed,
+// returns the delay to apply before verifying the password or ErrLoginLocked
+func CheckLogin(dialect goqu.DialectWrapper, db *sql.DB, email string, ip string) (time.Duration, error) {
+	// Read configuration file
+	config := GetConfig(".")
+	login := config.Login
+
+	now := time.Now()
+	since := now.Add(-login.Window * time.Minute)
+
+	emailCount, emailLatest, emailErr := countFailures(dialect, db, goqu.Ex{
+		"email": NormalizeEmail(email),
+	}, since)
+	if emailErr != nil {
+		return 0, emailErr
+	}
+	if login.MaxAttempts > 0 && emailCount >= int64(login.MaxAttempts) && now.Before(emailLatest.Add(login.Lockout*time.Minute)) {
+		return 0, ErrLoginLocked
+	}
+
+	ipCount, ipLatest, ipErr := countFailures(dialect, db, goqu.Ex{
+		"ip": ip,
+	}, since)
+	if ipErr != nil {
+		return 0, ipErr
+	}
+	if login.IPMaxAttempts > 0 && ipCount >= int64(login.IPMaxAttempts) && now.Before(ipLatest.Add(login.Lockout*time.Minute)) {
+		return 0, ErrLoginLocked
+	}
+
+	return loginDelay(login, emailCount), nil
+}
+
+// loginDelay - Delay before verifying the password of an account with the amount of recent
+// failures, doubling with every failure up to the configured maximum
+func loginDelay(login config.LoginConfiguration, failures int64) time.Duration {
+	if failures < 1 || login.Delay <= 0 {
+		return 0
+	}
+	delay := float64(login.Delay) * math.Pow(2, float64(failures-1))
+	delay = math.Min(delay, float64(login.MaxDelay))
+
+	return time.Duration(delay) * time.Millisecond
+}
+
+// RecordLoginFailure - Store a failed login attempt for the email from the IP address
+func RecordLoginFailure(dialect goqu.DialectWrapper, db *sql.DB, email string, ip string) error {
+	insertDialect := dialect.Insert("users_login_failures").Rows(
+		goqu.Record{
+			"id":    uuid.NewV4(),
+			"email": NormalizeEmail(email),
+			"ip":    ip,
+		},
+	)
+	insertQuery, _, toSQLErr := insertDialect.ToSQL()
+	if toSQLErr != nil {
+		return toSQLErr
+	}
+
+	_, insertErr := db.Exec(insertQuery)
+	return insertErr
+}
+
+// PurgeLoginFailures - Remove failed login attempts recorded before the cutoff, returns the
+// amount of attempts removed
+func PurgeLoginFailures(dialect goqu.DialectWrapper, db *sql.DB, cutoff time.Time) (int64, error) {
+	deleteDialect := dialect.Delete("users_login_failures").Where(
+		goqu.C("created_at").Lt(cutoff.Format(time.RFC3339)),
+	)
+	deleteQuery, _, toSQLErr := deleteDialect.ToSQL()
+	if toSQLErr != nil {
+		return 0, toSQLErr
+	}
+
+	result, deleteErr := db.Exec(deleteQuery)
+	if deleteErr != nil {
+		return 0, deleteErr
+	}
+	return result.RowsAffected()
+}
+
+// ClearLoginFailures - Forget the failed login attempts of the email after a successful login
+func ClearLoginFailures(dialect goqu.DialectWrapper, db *sql.DB, email string) error {
+	deleteDialect := dialect.Delete("users_login_failures").Where(goqu.Ex{
+		"email": NormalizeEmail(email),
+	})
+	deleteQuery, _, toSQLErr := deleteDialect.ToSQL()
+	if toSQLErr != nil {
+		return toSQLErr
+	}
+
+	_, deleteErr := db.Exec(deleteQuery)
+	return deleteErr
+}
+
+// DummyValidHash - Compare the text against a fixed hash, used when the user does not exist so
+// the response takes as long as for an existing user
+func DummyValidHash(text string) {
+	dummyHashOnce.Do(func() {
+		dummyHash, _ = Hash(uuid.NewV4().String())
+	})
+	ValidHash(text, dummyHash)
+}
+
+// VerifyPassword - Check the password of a signed in user confirming a sensitive change, subject to
+// the same delays, lockout and failure recording as logins
+func VerifyPassword(dialect goqu.DialectWrapper, db *sql.DB, user models.User, password string, ip string) error {
+	delay, lockErr := CheckLogin(dialect, db, user.Email, ip)
+	if lockErr != nil {
+		return lockErr
+	}
+	time.Sleep(delay)
+
+	if valid, _ := ValidHash(password, user.EncryptedPassword); !valid {
+		if recordErr := RecordLoginFailure(dialect, db, user.Email, ip); recordErr != nil {
+			return recordErr
+		}
+		return ErrInvalidCredentials
+	}
+
+	return nil
+}
@@ -0,0 +1,37 @@
+package source
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	config "github.com/HencoSmith/graphql-example-go/config/struct"
+)
+
+func TestLoginDelay(t *testing.T) {
+	login := config.LoginConfiguration{
+		Delay:    250,
+		MaxDelay: 4000,
+	}
+
+	// Doubles with every failure, starting at the configured delay
+	for failures, delay := range map[int64]time.Duration{
+		0:  0,
+		1:  250 * time.Millisecond,
+		2:  500 * time.Millisecond,
+		3:  time.Second,
+		4:  2 * time.Second,
+		5:  4 * time.Second,
+		6:  4 * time.Second,
+		60: 4 * time.Second,
+	} {
+		assert.Equal(t, delay, loginDelay(login, failures), "%d failures", failures)
+	}
+
+	// A huge amount of failures must not overflow
+	assert.Equal(t, 4*time.Second, loginDelay(login, 10000))
+
+	login.Delay = 0
+	assert.Equal(t, time.Duration(0), loginDelay(login, 3), "Delays are disabled")
+}
@@ -0,0 +1,112 @@
+package source
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"net/smtp"
+	"os"
+	"strings"
+	"text/template"
+	"time"
+
+	configStruct "github.com/HencoSmith/graphql-example-go/config/struct"
+	"github.com/HencoSmith/graphql-example-go/models"
+)
+
+// Mailer - delivers emails to users
+type Mailer interface {
+	Send(mail models.Mail) error
+}
+
+// SMTPMailer - Mailer delivering emails through an SMTP server
+type SMTPMailer struct {
+	From     string
+	Host     string
+	Port     string
+	Username string
+	Password string
+}
+
+// Send - Deliver the email through the SMTP server
+func (mailer SMTPMailer) Send(mail models.Mail) error {
+	var auth smtp.Auth
+	if len(mailer.Username) > 0 {
+		auth = smtp.PlainAuth("", mailer.Username, mailer.Password, mailer.Host)
+	}
+
+	message := "From: " + mailer.From + "\r\n" +
+		"To: " + mail.To + "\r\n" +
+		"Subject: " + mail.Subject + "\r\n" +
+		"Date: " + time.Now().Format(time.RFC1123Z) + "\r\n" +
+		"Content-Type: text/plain; charset=UTF-8\r\n" +
+		"\r\n" +
+		strings.Replace(mail.Body, "\n", "\r\n", -1)
+
+	return smtp.SendMail(mailer.Host+":"+mailer.Port, auth, mailer.From, []string{mail.To}, []byte(message))
+}
+
+// FileMailer - Mailer appending emails to a file for local development and tests, or writing
+// them to the log if no path is set
+type FileMailer struct {
+	From string
+	Path string
+}
+
+// Send - Append the email to the file or log
+func (mailer FileMailer) Send(mail models.Mail) error {
+	message := fmt.Sprintf("Date: %s\nFrom: %s\nTo: %s\nSubject: %s\n\n%s\n\n",
+		time.Now().Format(time.RFC3339), mailer.From, mail.To, mail.Subject, mail.Body)
+
+	if len(mailer.Path) == 0 {
+		log.Print(message)
+		return nil
+	}
+
+	file, err := os.OpenFile(mailer.Path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	_, err = file.WriteString(message)
+	return err
+}
+
+// NewMailer - Create the mailer selected by the configured driver ("smtp", "file" or "log")
+func NewMailer(config configStruct.MailConfiguration) Mailer {
+	switch config.Driver {
+	case "smtp":
+		password := config.Password
+		envPassword := os.Getenv("SMTP_PASSWORD")
+		if len(envPassword) != 0 {
+			password = envPassword
+		}
+		return SMTPMailer{
+			From:     config.From,
+			Host:     config.Host,
+			Port:     config.Port,
+			Username: config.Username,
+			Password: password,
+		}
+	case "file":
+		return FileMailer{From: config.From, Path: config.Path}
+	default:
+		return FileMailer{From: config.From}
+	}
+}
+
+// FormatLink - Build a link mailed to users by replacing {{.Token}} in the URL template
+func FormatLink(urlTemplate string, token string) (string, error) {
+	linkTemplate, parseErr := template.New("url").Parse(urlTemplate)
+	if parseErr != nil {
+		return "", parseErr
+	}
+
+	var link bytes.Buffer
+	if errExecute := linkTemplate.Execute(&link, map[string]string{"Token": token}); errExecute != nil {
+		return "", errExecute
+	}
+
+	return link.String(), nil
+}
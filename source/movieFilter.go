@@ -0,0 +1,54 @@
+package source
+
+import (
+	"strings"
+
+	"github.com/doug-martin/goqu/v8"
+	"github.com/doug-martin/goqu/v8/exp"
+)
+
+// NormalizeTag - Tags are compared trimmed and in lower case
+func NormalizeTag(tag string) string {
+	return strings.ToLower(strings.TrimSpace(tag))
+}
+
+// genreMovies - Select the IDs of the movies in the genre, matching the name case-insensitively
+func genreMovies(dialect goqu.DialectWrapper, name string) *goqu.SelectDataset {
+	return dialect.From("movies_genres").Select("movies_genres.movies_id").Join(
+		goqu.T("genres"),
+		goqu.On(goqu.Ex{"genres.id": goqu.I("movies_genres.genres_id")}),
+	).Where(
+		goqu.Func("lower", goqu.I("genres.name")).Eq(strings.ToLower(strings.TrimSpace(name))),
+	)
+}
+
+// tagMovies - Select the IDs of the movies labelled with the tag
+func tagMovies(dialect goqu.DialectWrapper, name string) *goqu.SelectDataset {
+	return dialect.From("movies_tags").Select("movies_tags.movies_id").Join(
+		goqu.T("tags"),
+		goqu.On(goqu.Ex{"tags.id": goqu.I("movies_tags.tags_id")}),
+	).Where(goqu.Ex{
+		"tags.name": NormalizeTag(name),
+	})
+}
+
+// MovieListFilter - Expression selecting the movies which are listed, those which are not
+// deleted, optionally only those of the genre and labelled with the tag
+// dialect - Query builder dialect object used
+// genre - Name of the genre, empty for movies of any genre
+// tag - Tag the movies are labelled with, empty for movies with any tags
+func MovieListFilter(dialect goqu.DialectWrapper, genre string, tag string) exp.Expression {
+	filters := []exp.Expression{
+		goqu.Ex{
+			"movies.deleted_at": nil,
+		},
+	}
+	if len(genre) > 0 {
+		filters = append(filters, goqu.I("movies.id").In(genreMovies(dialect, genre)))
+	}
+	if len(tag) > 0 {
+		filters = append(filters, goqu.I("movies.id").In(tagMovies(dialect, tag)))
+	}
+
+	return goqu.And(filters...)
+}
@@ -0,0 +1,422 @@
+package source
+
+import (
+	"bufio"
+	"bytes"
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"io"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/doug-martin/goqu/v8"
+	"github.com/doug-martin/goqu/v8/exp"
+	uuid "github.com/satori/go.uuid"
+
+	"github.com/HencoSmith/graphql-example-go/models"
+)
+
+// Formats movies are imported from
+const (
+	// ImportCSV - comma separated values with a header row naming the columns
+	ImportCSV = "csv"
+	// ImportJSONL - JSON Lines, one movie object per line
+	ImportJSONL = "jsonl"
+)
+
+// importLock - advisory lock serializing imports, so concurrent imports cannot both insert a
+// movie the other one is inserting
+const importLock = 724613
+
+// maxImportLine - bytes a single line of a JSON Lines import may consist of
+const maxImportLine = 1 << 20
+
+// ErrUnknownImportFormat - returned when the format of an import is neither CSV nor JSON Lines
+var ErrUnknownImportFormat = errors.New("Unknown import format, expected csv or jsonl")
+
+// importRow - A movie read from the import
+type importRow struct {
+	Row         int
+	Name        string
+	ReleaseYear int64
+	Description string
+}
+
+// key - Movies are considered duplicates when their name, compared case-insensitively, and
+// release year match
+func (row importRow) key() string {
+	return strings.ToLower(row.Name) + "\x00" + strconv.FormatInt(row.ReleaseYear, 10)
+}
+
+// importReader - Reads the rows of an import one at a time, returns io.EOF after the last row.
+// Errors of a single row are returned along with its number, any other error ends the import
+type importReader interface {
+	Next() (importRow, error)
+}
+
+// importRowError - returned by importReader when a single row cannot be read
+type importRowError struct {
+	Row     int
+	Message string
+}
+
+// Error - Message of the row
+func (e importRowError) Error() string {
+	return e.Message
+}
+
+// csvImportReader - Reads CSV with a header row, columns are matched by name and unknown columns
+// are ignored
+type csvImportReader struct {
+	reader  *csv.Reader
+	columns map[string]int
+	row     int
+}
+
+// newCSVImportReader - Read the header row of the CSV
+func newCSVImportReader(data io.Reader) (*csvImportReader, error) {
+	reader := csv.NewReader(data)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, headerErr := reader.Read()
+	if headerErr == io.EOF {
+		return nil, errors.New("CSV import is missing the header row")
+	}
+	if headerErr != nil {
+		return nil, headerErr
+	}
+
+	columns := map[string]int{}
+	for i, column := range header {
+		// Excel prefixes UTF-8 files with a byte order mark
+		column = strings.TrimPrefix(column, "\ufeff")
+		columns[strings.ToLower(strings.TrimSpace(column))] = i
+	}
+	for _, required := range []string{"name", "release_year"} {
+		if _, ok := columns[required]; !ok {
+			return nil, errors.New("CSV import is missing the " + required + " column")
+		}
+	}
+
+	return &csvImportReader{reader: reader, columns: columns}, nil
+}
+
+// field - Value of the column in the record, empty when the record is too short
+func (r *csvImportReader) field(record []string, column string) string {
+	i, ok := r.columns[column]
+	if !ok || i >= len(record) {
+		return ""
+	}
+	return record[i]
+}
+
+// Next - Read the next record
+func (r *csvImportReader) Next() (importRow, error) {
+	record, readErr := r.reader.Read()
+	if readErr == io.EOF {
+		return importRow{}, io.EOF
+	}
+	r.row++
+	if parseErr, ok := readErr.(*csv.ParseError); ok {
+		return importRow{}, importRowError{Row: r.row, Message: parseErr.Err.Error()}
+	}
+	if readErr != nil {
+		return importRow{}, readErr
+	}
+
+	releaseYear, yearErr := strconv.ParseInt(strings.TrimSpace(r.field(record, "release_year")), 10, 64)
+	if yearErr != nil {
+		return importRow{}, importRowError{Row: r.row, Message: "release_year must be a whole number"}
+	}
+
+	return importRow{
+		Row:         r.row,
+		Name:        r.field(record, "name"),
+		ReleaseYear: releaseYear,
+		Description: r.field(record, "description"),
+	}, nil
+}
+
+// jsonlImportReader - Reads JSON Lines, blank lines are skipped and unknown fields ignored
+type jsonlImportReader struct {
+	scanner *bufio.Scanner
+	row     int
+}
+
+// newJSONLImportReader - Read JSON Lines from the data
+func newJSONLImportReader(data io.Reader) *jsonlImportReader {
+	scanner := bufio.NewScanner(data)
+	scanner.Buffer(make([]byte, 64*1024), maxImportLine)
+	return &jsonlImportReader{scanner: scanner}
+}
+
+// Next - Decode the next line
+func (r *jsonlImportReader) Next() (importRow, error) {
+	for r.scanner.Scan() {
+		line := bytes.TrimSpace(r.scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		r.row++
+
+		var movie struct {
+			Name        *string     `json:"name"`
+			ReleaseYear json.Number `json:"release_year"`
+			Description string      `json:"description"`
+		}
+		if decodeErr := json.Unmarshal(line, &movie); decodeErr != nil {
+			return importRow{}, importRowError{Row: r.row, Message: "Invalid JSON: " + decodeErr.Error()}
+		}
+		if movie.Name == nil {
+			return importRow{}, importRowError{Row: r.row, Message: "name is required"}
+		}
+		releaseYear, yearErr := movie.ReleaseYear.Int64()
+		if yearErr != nil {
+			return importRow{}, importRowError{Row: r.row, Message: "release_year must be a whole number"}
+		}
+
+		return importRow{
+			Row:         r.row,
+			Name:        *movie.Name,
+			ReleaseYear: releaseYear,
+			Description: movie.Description,
+		}, nil
+	}
+
+	if scanErr := r.scanner.Err(); scanErr != nil {
+		if scanErr == bufio.ErrTooLong {
+			return importRow{}, errors.New("Lines of JSON Lines imports must not exceed 1 MiB")
+		}
+		return importRow{}, scanErr
+	}
+	return importRow{}, io.EOF
+}
+
+// ImportFormat - Determine the format of an import from the extension of its file name, empty
+// when the extension is unknown
+func ImportFormat(filename string) string {
+	switch strings.ToLower(path.Ext(filename)) {
+	case ".csv":
+		return ImportCSV
+	case ".jsonl", ".ndjson", ".json":
+		return ImportJSONL
+	default:
+		return ""
+	}
+}
+
+// validImportRow - Check the fields of an imported movie
+func validImportRow(row importRow) error {
+	return ValidateMovie(row.Name, row.Description, row.ReleaseYear)
+}
+
+// existingMovies - Keys of the rows matching a movie which is not deleted
+// dialect - Query builder dialect object used
+// tx - Transaction to use
+// rows - Rows of the batch
+func existingMovies(dialect goqu.DialectWrapper, tx *sql.Tx, rows []importRow) (map[string]bool, error) {
+	matches := []exp.Expression{}
+	for _, row := range rows {
+		matches = append(matches, goqu.And(
+			goqu.Func("lower", goqu.C("name")).Eq(strings.ToLower(row.Name)),
+			goqu.C("release_year").Eq(row.ReleaseYear),
+		))
+	}
+	selectDialect := dialect.From("movies").Select("name", "release_year").Where(
+		goqu.C("deleted_at").IsNull(),
+		goqu.Or(matches...),
+	)
+	selectQuery, _, toSQLErr := selectDialect.ToSQL()
+	if toSQLErr != nil {
+		return nil, toSQLErr
+	}
+
+	existing, queryErr := tx.Query(selectQuery)
+	if queryErr != nil {
+		return nil, queryErr
+	}
+	defer existing.Close()
+
+	keys := map[string]bool{}
+	for existing.Next() {
+		var row importRow
+		if scanErr := existing.Scan(&row.Name, &row.ReleaseYear); scanErr != nil {
+			return nil, scanErr
+		}
+		keys[row.key()] = true
+	}
+	if errRows := existing.Err(); errRows != nil {
+		return nil, errRows
+	}
+
+	return keys, nil
+}
+
+// importBatch - Insert the rows of the batch which do not match an existing movie in a single
+// transaction, returns the amount of movies inserted
+// dialect - Query builder dialect object used
+// db - SQL DB connection to use
+// userID - UUID of the user the movies belong to
+// rows - Validated rows, free of duplicates among themselves
+func importBatch(dialect goqu.DialectWrapper, db *sql.DB, userID string, rows []importRow) (int, error) {
+	tx, txErr := db.Begin()
+	if txErr != nil {
+		return 0, txErr
+	}
+	defer tx.Rollback()
+
+	if _, lockErr := tx.Exec("SELECT pg_advisory_xact_lock(" + strconv.Itoa(importLock) + ")"); lockErr != nil {
+		return 0, lockErr
+	}
+
+	existing, existingErr := existingMovies(dialect, tx, rows)
+	if existingErr != nil {
+		return 0, existingErr
+	}
+
+	records := []interface{}{}
+	for _, row := range rows {
+		if existing[row.key()] {
+			continue
+		}
+		records = append(records, goqu.Record{
+			"id":           uuid.NewV4().String(),
+			"name":         row.Name,
+			"description":  row.Description,
+			"release_year": row.ReleaseYear,
+			"users_id":     userID,
+		})
+	}
+	if len(records) == 0 {
+		return 0, nil
+	}
+
+	insertDialect := dialect.Insert("movies").Rows(records...).Returning(goqu.L("to_jsonb(movies.*)"))
+	insertQuery, _, toSQLErr := insertDialect.ToSQL()
+	if toSQLErr != nil {
+		return 0, toSQLErr
+	}
+
+	inserted, insertErr := tx.Query(insertQuery)
+	if insertErr != nil {
+		return 0, insertErr
+	}
+	created := []models.Movie{}
+	for inserted.Next() {
+		var snapshot []byte
+		if scanErr := inserted.Scan(&snapshot); scanErr != nil {
+			inserted.Close()
+			return 0, scanErr
+		}
+		movie := models.Movie{}
+		if unmarshalErr := json.Unmarshal(snapshot, &movie); unmarshalErr != nil {
+			inserted.Close()
+			return 0, unmarshalErr
+		}
+		created = append(created, movie)
+	}
+	inserted.Close()
+	if errRows := inserted.Err(); errRows != nil {
+		return 0, errRows
+	}
+
+	if revisionErr := RecordCreateRevisions(dialect, tx, userID, created); revisionErr != nil {
+		return 0, revisionErr
+	}
+
+	return len(created), tx.Commit()
+}
+
+// ImportMovies - Import the movies of the CSV or JSON Lines data for the user. Invalid rows are
+// reported and left out, rows matching a movie which is not deleted or an earlier row are
+// skipped. The rows are committed in batches of the configured size, when an error ends the
+// import the batches committed so far are kept and reported in the result
+// dialect - Query builder dialect object used
+// db - SQL DB connection to use
+// userID - UUID of the user the movies belong to
+// format - ImportCSV or ImportJSONL
+// data - Contents of the file
+func ImportMovies(dialect goqu.DialectWrapper, db *sql.DB, userID string, format string, data io.Reader) (models.MovieImport, error) {
+	// Read configuration file
+	config := GetConfig(".")
+	maxRows := config.Import.MaxRows
+	batchSize := config.Import.BatchSize
+	if batchSize < 1 {
+		batchSize = 500
+	}
+
+	result := models.MovieImport{Errors: []models.MovieImportError{}}
+
+	var reader importReader
+	switch format {
+	case ImportCSV:
+		csvReader, csvErr := newCSVImportReader(data)
+		if csvErr != nil {
+			return result, csvErr
+		}
+		reader = csvReader
+	case ImportJSONL:
+		reader = newJSONLImportReader(data)
+	default:
+		return result, ErrUnknownImportFormat
+	}
+
+	seen := map[string]bool{}
+	batch := []importRow{}
+	for {
+		row, readErr := reader.Next()
+		if readErr == io.EOF {
+			break
+		}
+		rowErr, invalid := readErr.(importRowError)
+		if readErr != nil && !invalid {
+			return result, readErr
+		}
+		if maxRows > 0 && (row.Row > maxRows || rowErr.Row > maxRows) {
+			return result, errors.New("Imports must not exceed " + strconv.Itoa(maxRows) + " rows")
+		}
+		if invalid {
+			result.Errors = append(result.Errors, models.MovieImportError{Row: rowErr.Row, Message: rowErr.Message})
+			continue
+		}
+
+		row.Name = strings.TrimSpace(row.Name)
+		row.Description = strings.TrimSpace(row.Description)
+		if validErr := validImportRow(row); validErr != nil {
+			result.Errors = append(result.Errors, models.MovieImportError{Row: row.Row, Message: validErr.Error()})
+			continue
+		}
+		if seen[row.key()] {
+			result.Skipped++
+			continue
+		}
+		seen[row.key()] = true
+
+		batch = append(batch, row)
+		if len(batch) < batchSize {
+			continue
+		}
+		imported, batchErr := importBatch(dialect, db, userID, batch)
+		if batchErr != nil {
+			return result, batchErr
+		}
+		result.Imported += imported
+		result.Skipped += len(batch) - imported
+		batch = batch[:0]
+	}
+
+	if len(batch) > 0 {
+		imported, batchErr := importBatch(dialect, db, userID, batch)
+		if batchErr != nil {
+			return result, batchErr
+		}
+		result.Imported += imported
+		result.Skipped += len(batch) - imported
+	}
+
+	return result, nil
+}
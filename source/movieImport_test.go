@@ -0,0 +1,34 @@
+package source
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidImportRow(t *testing.T) {
+	valid := importRow{Name: "Amélie", ReleaseYear: 2001, Description: "Paris"}
+	assert.NoError(t, validImportRow(valid))
+
+	// Lengths count characters rather than bytes
+	long := valid
+	long.Name = strings.Repeat("é", 128)
+	assert.NoError(t, validImportRow(long))
+	long.Name += "é"
+	assert.Error(t, validImportRow(long))
+
+	for _, name := range []string{"", "Invalid \xff", "Nul \x00"} {
+		row := valid
+		row.Name = name
+		assert.Error(t, validImportRow(row), "%q", name)
+	}
+
+	description := valid
+	description.Description = "Invalid \xc3"
+	assert.EqualError(t, validImportRow(description), "text must be valid UTF-8")
+
+	year := valid
+	year.ReleaseYear = 1700
+	assert.Error(t, validImportRow(year))
+}
@@ -0,0 +1,109 @@
+package source
+
+import (
+	"encoding/json"
+
+	"github.com/doug-martin/goqu/v8"
+	uuid "github.com/satori/go.uuid"
+
+	"github.com/HencoSmith/graphql-example-go/models"
+)
+
+// Actions recorded in the revisions of a movie
+const (
+	// RevisionCreate - the movie was created, there is no previous state
+	RevisionCreate = "create"
+	// RevisionUpdate - fields of the movie were changed
+	RevisionUpdate = "update"
+	// RevisionDelete - the movie was soft-deleted
+	RevisionDelete = "delete"
+	// RevisionRestore - the soft-deleted movie was restored
+	RevisionRestore = "restore"
+	// RevisionRevert - the fields of the movie were reset to those of an earlier revision
+	RevisionRevert = "revert"
+)
+
+// movieSnapshot - Encode the movie as stored in the revision, nil when there is no movie
+func movieSnapshot(movie *models.Movie) (interface{}, error) {
+	if movie == nil {
+		return nil, nil
+	}
+
+	snapshot, marshalErr := json.Marshal(movie)
+	if marshalErr != nil {
+		return nil, marshalErr
+	}
+	return string(snapshot), nil
+}
+
+// revisionRecord - Row of the movie_revisions table for the change
+func revisionRecord(userID string, action string, before *models.Movie, after *models.Movie) (goqu.Record, error) {
+	moviesID := ""
+	if after != nil {
+		moviesID = after.ID
+	} else if before != nil {
+		moviesID = before.ID
+	}
+
+	beforeSnapshot, beforeErr := movieSnapshot(before)
+	if beforeErr != nil {
+		return nil, beforeErr
+	}
+	afterSnapshot, afterErr := movieSnapshot(after)
+	if afterErr != nil {
+		return nil, afterErr
+	}
+
+	return goqu.Record{
+		"id":        uuid.NewV4().String(),
+		"movies_id": moviesID,
+		"users_id":  userID,
+		"action":    action,
+		"before":    beforeSnapshot,
+		"after":     afterSnapshot,
+	}, nil
+}
+
+// RecordMovieRevision - Append a revision of the movie changed by the user, should be executed in
+// the transaction making the change. Revisions are never changed or removed, they outlive
+// purged movies
+func RecordMovieRevision(dialect goqu.DialectWrapper, db execer, userID string, action string, before *models.Movie, after *models.Movie) error {
+	record, recordErr := revisionRecord(userID, action, before, after)
+	if recordErr != nil {
+		return recordErr
+	}
+
+	insertDialect := dialect.Insert("movie_revisions").Rows(record)
+	insertQuery, _, toSQLErr := insertDialect.ToSQL()
+	if toSQLErr != nil {
+		return toSQLErr
+	}
+
+	_, insertErr := db.Exec(insertQuery)
+	return insertErr
+}
+
+// RecordCreateRevisions - Append the create revisions of many movies using a single insert
+func RecordCreateRevisions(dialect goqu.DialectWrapper, db execer, userID string, created []models.Movie) error {
+	if len(created) == 0 {
+		return nil
+	}
+
+	records := []interface{}{}
+	for i := range created {
+		record, recordErr := revisionRecord(userID, RevisionCreate, nil, &created[i])
+		if recordErr != nil {
+			return recordErr
+		}
+		records = append(records, record)
+	}
+
+	insertDialect := dialect.Insert("movie_revisions").Rows(records...)
+	insertQuery, _, toSQLErr := insertDialect.ToSQL()
+	if toSQLErr != nil {
+		return toSQLErr
+	}
+
+	_, insertErr := db.Exec(insertQuery)
+	return insertErr
+}
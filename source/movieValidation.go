@@ -0,0 +1,36 @@
+package source
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// firstReleaseYear - release year of the earliest movies
+const firstReleaseYear = 1888
+
+// ValidateMovie - Check the fields of a created, changed or imported movie, the name and
+// description must be valid UTF-8 without NUL characters and the release year must lie between
+// the earliest movies and 10 years from now
+func ValidateMovie(name string, description string, releaseYear int64) error {
+	for _, value := range []string{name, description} {
+		if !utf8.ValidString(value) {
+			return errors.New("text must be valid UTF-8")
+		}
+		if strings.ContainsRune(value, 0) {
+			return errors.New("text must not contain NUL characters")
+		}
+	}
+	if length := utf8.RuneCountInString(name); length == 0 || length > 128 {
+		return errors.New("name must be between 1 and 128 characters")
+	}
+	if releaseYear < firstReleaseYear || releaseYear > int64(time.Now().Year()+10) {
+		return errors.New("release_year must be between " + strconv.Itoa(firstReleaseYear) + " and 10 years from now")
+	}
+	if utf8.RuneCountInString(description) > 10000 {
+		return errors.New("description must not exceed 10000 characters")
+	}
+	return nil
+}
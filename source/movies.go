@@ -0,0 +1,22 @@
+package source
+
+import "strconv"
+
+// VersionConflictError - returned when a movie was changed after the version the client expected,
+// the client should reload the movie and apply its change again
+type VersionConflictError struct {
+	CurrentVersion int64
+}
+
+// Error - Message of the conflict
+func (e VersionConflictError) Error() string {
+	return "Movie was changed by someone else, the current version is " + strconv.FormatInt(e.CurrentVersion, 10)
+}
+
+// Extensions - Machine readable details of the conflict added to the GraphQL error
+func (e VersionConflictError) Extensions() map[string]interface{} {
+	return map[string]interface{}{
+		"code":           "VERSION_CONFLICT",
+		"currentVersion": e.CurrentVersion,
+	}
+}
@@ -0,0 +1,539 @@
+package source
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/doug-martin/goqu/v8"
+	uuid "github.com/satori/go.uuid"
+
+	config "github.com/HencoSmith/graphql-example-go/config/struct"
+	"github.com/HencoSmith/graphql-example-go/models"
+)
+
+// ErrInvalidOIDCLogin - returned when the state of a callback is unknown, expired or already used
+var ErrInvalidOIDCLogin = errors.New("Invalid or expired OpenID Connect login")
+
+// ErrInvalidIDToken - returned when the ID token of the provider fails verification
+var ErrInvalidIDToken = errors.New("Invalid OpenID Connect ID token")
+
+// OIDCIdentity - The external user identified by a verified ID token
+type OIDCIdentity struct {
+	Subject       string
+	Email         string
+	EmailVerified bool
+}
+
+// oidcDiscovery - Provider metadata of /.well-known/openid-configuration
+type oidcDiscovery struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// jsonWebKey - Public key published in the JWKS of the provider
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// OIDCProvider - Client of the external identity provider, the metadata and keys are fetched on
+// first use and cached
+type OIDCProvider struct {
+	config    config.OIDCConfiguration
+	client    *http.Client
+	mutex     sync.Mutex
+	discovery *oidcDiscovery
+	keys      map[string]crypto.PublicKey
+}
+
+// NewOIDCProvider - Setup the client of the configured provider, the environment overrides the
+// client secret
+func NewOIDCProvider(oidcConfig config.OIDCConfiguration) *OIDCProvider {
+	envSecret := os.Getenv("OIDC_CLIENT_SECRET")
+	if len(envSecret) != 0 {
+		oidcConfig.ClientSecret = envSecret
+	}
+
+	return &OIDCProvider{
+		config: oidcConfig,
+		client: &http.Client{Timeout: 10 * time.Second},
+		keys:   map[string]crypto.PublicKey{},
+	}
+}
+
+// getJSON - Fetch and decode a JSON document of the provider
+func (provider *OIDCProvider) getJSON(location string, target interface{}) error {
+	res, err := provider.client.Get(location)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("OpenID Connect request to %v failed: %v", location, res.Status)
+	}
+	return json.NewDecoder(res.Body).Decode(target)
+}
+
+// endpoints - Lookup the provider metadata, the caller must hold the mutex
+func (provider *OIDCProvider) endpoints() (oidcDiscovery, error) {
+	if provider.discovery != nil {
+		return *provider.discovery, nil
+	}
+
+	var discovery oidcDiscovery
+	location := strings.TrimSuffix(provider.config.Issuer, "/") + "/.well-known/openid-configuration"
+	if err := provider.getJSON(location, &discovery); err != nil {
+		return oidcDiscovery{}, err
+	}
+	if discovery.Issuer != provider.config.Issuer {
+		return oidcDiscovery{}, fmt.Errorf("OpenID Connect issuer mismatch: %v", discovery.Issuer)
+	}
+
+	provider.discovery = &discovery
+	return discovery, nil
+}
+
+// decodeBigInt - Decode an unpadded base64url number of a JWK
+func decodeBigInt(encoded string) (*big.Int, error) {
+	buff, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, err
+	}
+	return new(big.Int).SetBytes(buff), nil
+}
+
+// parseJSONWebKey - Convert a JWK to a public key, only RSA and P-256 signing keys are supported
+func parseJSONWebKey(key jsonWebKey) (crypto.PublicKey, error) {
+	switch key.Kty {
+	case "RSA":
+		n, nErr := decodeBigInt(key.N)
+		if nErr != nil {
+			return nil, nErr
+		}
+		e, eErr := decodeBigInt(key.E)
+		if eErr != nil {
+			return nil, eErr
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		if key.Crv != "P-256" {
+			return nil, fmt.Errorf("Unsupported curve: %v", key.Crv)
+		}
+		x, xErr := decodeBigInt(key.X)
+		if xErr != nil {
+			return nil, xErr
+		}
+		y, yErr := decodeBigInt(key.Y)
+		if yErr != nil {
+			return nil, yErr
+		}
+		if !elliptic.P256().IsOnCurve(x, y) {
+			return nil, errors.New("Invalid EC key")
+		}
+		return &ecdsa.PublicKey{Curve: elliptic.P256(), X: x, Y: y}, nil
+	}
+	return nil, fmt.Errorf("Unsupported key type: %v", key.Kty)
+}
+
+// publicKey - Lookup the signing key of the provider, the JWKS is fetched again for unknown
+// keys as the provider may have rotated
+func (provider *OIDCProvider) publicKey(id string) (crypto.PublicKey, error) {
+	provider.mutex.Lock()
+	defer provider.mutex.Unlock()
+
+	if key, ok := provider.keys[id]; ok {
+		return key, nil
+	}
+
+	discovery, discoveryErr := provider.endpoints()
+	if discoveryErr != nil {
+		return nil, discoveryErr
+	}
+
+	var set struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	if err := provider.getJSON(discovery.JWKSURI, &set); err != nil {
+		return nil, err
+	}
+
+	keys := map[string]crypto.PublicKey{}
+	for _, jwk := range set.Keys {
+		if len(jwk.Use) > 0 && jwk.Use != "sig" {
+			continue
+		}
+		// Keys which cannot be parsed are skipped, tokens signed with them are rejected
+		if key, parseErr := parseJSONWebKey(jwk); parseErr == nil {
+			keys[jwk.Kid] = key
+		}
+	}
+	provider.keys = keys
+
+	key, ok := keys[id]
+	if !ok {
+		return nil, fmt.Errorf("Unknown ID token signing key: %v", id)
+	}
+	return key, nil
+}
+
+// pkceChallenge - S256 code challenge of the PKCE verifier
+func pkceChallenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// AuthURL - Build the authorization request the browser is redirected to
+func (provider *OIDCProvider) AuthURL(state string, nonce string, verifier string) (string, error) {
+	provider.mutex.Lock()
+	discovery, discoveryErr := provider.endpoints()
+	provider.mutex.Unlock()
+	if discoveryErr != nil {
+		return "", discoveryErr
+	}
+
+	scopes := provider.config.Scopes
+	hasOpenID := false
+	for _, scope := range scopes {
+		hasOpenID = hasOpenID || scope == "openid"
+	}
+	if !hasOpenID {
+		scopes = append([]string{"openid"}, scopes...)
+	}
+
+	v := url.Values{}
+	v.Set("response_type", "code")
+	v.Set("client_id", provider.config.ClientID)
+	v.Set("redirect_uri", provider.config.RedirectURL)
+	v.Set("scope", strings.Join(scopes, " "))
+	v.Set("state", state)
+	v.Set("nonce", nonce)
+	v.Set("code_challenge", pkceChallenge(verifier))
+	v.Set("code_challenge_method", "S256")
+
+	separator := "?"
+	if strings.Contains(discovery.AuthorizationEndpoint, "?") {
+		separator = "&"
+	}
+	return discovery.AuthorizationEndpoint + separator + v.Encode(), nil
+}
+
+// Exchange - Redeem the authorization code along with the PKCE verifier, returns the ID token
+func (provider *OIDCProvider) Exchange(code string, verifier string) (string, error) {
+	provider.mutex.Lock()
+	discovery, discoveryErr := provider.endpoints()
+	provider.mutex.Unlock()
+	if discoveryErr != nil {
+		return "", discoveryErr
+	}
+
+	v := url.Values{}
+	v.Set("grant_type", "authorization_code")
+	v.Set("code", code)
+	v.Set("redirect_uri", provider.config.RedirectURL)
+	v.Set("client_id", provider.config.ClientID)
+	v.Set("code_verifier", verifier)
+
+	req, reqErr := http.NewRequest("POST", discovery.TokenEndpoint, strings.NewReader(v.Encode()))
+	if reqErr != nil {
+		return "", reqErr
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	// Public clients authenticate using PKCE alone
+	if len(provider.config.ClientSecret) > 0 {
+		req.SetBasicAuth(url.QueryEscape(provider.config.ClientID), url.QueryEscape(provider.config.ClientSecret))
+	}
+
+	res, resErr := provider.client.Do(req)
+	if resErr != nil {
+		return "", resErr
+	}
+	defer res.Body.Close()
+
+	var body struct {
+		IDToken          string `json:"id_token"`
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+	if decodeErr := json.NewDecoder(res.Body).Decode(&body); decodeErr != nil {
+		return "", decodeErr
+	}
+	if res.StatusCode != http.StatusOK || len(body.Error) > 0 {
+		return "", fmt.Errorf("OpenID Connect token request failed: %v %v", body.Error, body.ErrorDescription)
+	}
+	if len(body.IDToken) == 0 {
+		return "", ErrInvalidIDToken
+	}
+
+	return body.IDToken, nil
+}
+
+// VerifyIDToken - Verify the signature, issuer, audience, expiry and nonce of the ID token and
+// return the identity it contains
+func (provider *OIDCProvider) VerifyIDToken(idToken string, nonce string) (OIDCIdentity, error) {
+	claims := jwt.MapClaims{}
+	token, err := jwt.ParseWithClaims(idToken, claims, func(token *jwt.Token) (interface{}, error) {
+		switch token.Method.(type) {
+		case *jwt.SigningMethodRSA, *jwt.SigningMethodECDSA:
+		default:
+			return nil, fmt.Errorf("Unexpected token signing method: %v", token.Header["alg"])
+		}
+		id, _ := token.Header["kid"].(string)
+		return provider.publicKey(id)
+	})
+	if err != nil || !token.Valid {
+		return OIDCIdentity{}, ErrInvalidIDToken
+	}
+
+	// MapClaims only checks the expiry if present
+	if _, ok := claims["exp"]; !ok {
+		return OIDCIdentity{}, ErrInvalidIDToken
+	}
+	if issuer, _ := claims["iss"].(string); issuer != provider.config.Issuer {
+		return OIDCIdentity{}, ErrInvalidIDToken
+	}
+
+	// The audience is either a string or an array of strings
+	audiences := []string{}
+	switch aud := claims["aud"].(type) {
+	case string:
+		audiences = append(audiences, aud)
+	case []interface{}:
+		for _, value := range aud {
+			if audience, ok := value.(string); ok {
+				audiences = append(audiences, audience)
+			}
+		}
+	}
+	hasAudience := false
+	for _, audience := range audiences {
+		hasAudience = hasAudience || audience == provider.config.ClientID
+	}
+	if !hasAudience {
+		return OIDCIdentity{}, ErrInvalidIDToken
+	}
+	if azp, ok := claims["azp"].(string); len(audiences) > 1 && (!ok || azp != provider.config.ClientID) {
+		return OIDCIdentity{}, ErrInvalidIDToken
+	}
+
+	if tokenNonce, _ := claims["nonce"].(string); tokenNonce != nonce {
+		return OIDCIdentity{}, ErrInvalidIDToken
+	}
+
+	identity := OIDCIdentity{}
+	identity.Subject, _ = claims["sub"].(string)
+	identity.Email, _ = claims["email"].(string)
+	identity.EmailVerified, _ = claims["email_verified"].(bool)
+	if len(identity.Subject) == 0 {
+		return OIDCIdentity{}, ErrInvalidIDToken
+	}
+
+	return identity, nil
+}
+
+// StartOIDCLogin - Store a pending login, returns the state binding the callback to it and the
+// authorization URL to redirect the browser to
+func StartOIDCLogin(dialect goqu.DialectWrapper, db *sql.DB, provider *OIDCProvider) (string, string, error) {
+	state, stateErr := RandomToken()
+	if stateErr != nil {
+		return "", "", stateErr
+	}
+	nonce, nonceErr := RandomToken()
+	if nonceErr != nil {
+		return "", "", nonceErr
+	}
+	verifier, verifierErr := RandomToken()
+	if verifierErr != nil {
+		return "", "", verifierErr
+	}
+
+	authURL, authErr := provider.AuthURL(state, nonce, verifier)
+	if authErr != nil {
+		return "", "", authErr
+	}
+
+	insertDialect := dialect.Insert("users_oidc_logins").Rows(
+		goqu.Record{
+			"id":            uuid.NewV4(),
+			"state_hash":    HashToken(state),
+			"nonce":         nonce,
+			"code_verifier": verifier,
+			"expires_at":    time.Now().Add(provider.config.Expiration * time.Minute).Format(time.RFC3339),
+		},
+	)
+	insertQuery, _, toSQLErr := insertDialect.ToSQL()
+	if toSQLErr != nil {
+		return "", "", toSQLErr
+	}
+
+	if _, insertErr := db.Exec(insertQuery); insertErr != nil {
+		return "", "", insertErr
+	}
+
+	return state, authURL, nil
+}
+
+// FinishOIDCLogin - Consume the pending login of the state, redeem the authorization code and
+// return the user linked to the external identity
+func FinishOIDCLogin(dialect goqu.DialectWrapper, db *sql.DB, provider *OIDCProvider, state string, code string) (models.User, error) {
+	now := time.Now().Format(time.RFC3339)
+
+	// Mark the login as used, only succeeds once for unexpired logins
+	consumeDialect := dialect.Update("users_oidc_logins").Set(
+		goqu.Record{
+			"used_at": now,
+		},
+	).Where(
+		goqu.Ex{
+			"state_hash": HashToken(state),
+			"used_at":    nil,
+		},
+		goqu.C("expires_at").Gt(now),
+	).Returning("nonce", "code_verifier")
+	consumeQuery, _, toSQLErr := consumeDialect.ToSQL()
+	if toSQLErr != nil {
+		return models.User{}, toSQLErr
+	}
+
+	var nonce, verifier string
+	scanErr := db.QueryRow(consumeQuery).Scan(&nonce, &verifier)
+	if scanErr == sql.ErrNoRows {
+		return models.User{}, ErrInvalidOIDCLogin
+	}
+	if scanErr != nil {
+		return models.User{}, scanErr
+	}
+
+	idToken, exchangeErr := provider.Exchange(code, verifier)
+	if exchangeErr != nil {
+		return models.User{}, exchangeErr
+	}
+
+	identity, verifyErr := provider.VerifyIDToken(idToken, nonce)
+	if verifyErr != nil {
+		return models.User{}, verifyErr
+	}
+
+	return ProvisionOIDCUser(dialect, db, provider.config, identity)
+}
+
+// ProvisionOIDCUser - Lookup the user linked to the external identity, on first login the
+// identity is linked to a new user or, if configured, the user with the same verified email
+func ProvisionOIDCUser(dialect goqu.DialectWrapper, db *sql.DB, oidcConfig config.OIDCConfiguration, identity OIDCIdentity) (models.User, error) {
+	linkExpression := goqu.Ex{
+		"issuer":  oidcConfig.Issuer,
+		"subject": identity.Subject,
+	}
+	linkDialect := dialect.From("users_external_identities").Select("users_id").Where(linkExpression)
+	linkQuery, _, linkToSQLErr := linkDialect.ToSQL()
+	if linkToSQLErr != nil {
+		return models.User{}, linkToSQLErr
+	}
+
+	tx, txErr := db.Begin()
+	if txErr != nil {
+		return models.User{}, txErr
+	}
+	defer tx.Rollback()
+
+	var userID string
+	linkErr := tx.QueryRow(linkQuery).Scan(&userID)
+	if linkErr == nil {
+		return GetUser(dialect, tx, userID, "")
+	}
+	if linkErr != sql.ErrNoRows {
+		return models.User{}, linkErr
+	}
+
+	email := NormalizeEmail(identity.Email)
+	if !ValidEmail(email) {
+		return models.User{}, errors.New("The identity provider did not share a valid email address")
+	}
+
+	existing, lookupErr := GetUser(dialect, tx, "", email)
+	switch {
+	case lookupErr == nil:
+		// Linking by email trusts the provider to have verified the address
+		if !oidcConfig.LinkByEmail || !identity.EmailVerified {
+			return models.User{}, ErrEmailTaken
+		}
+		userID = existing.ID
+	case lookupErr == ErrUserNotFound:
+		// The password is unknown to everyone, it can be set through a password reset
+		password, tokenErr := RandomToken()
+		if tokenErr != nil {
+			return models.User{}, tokenErr
+		}
+		encryptedPassword, hashErr := Hash(password)
+		if hashErr != nil {
+			return models.User{}, hashErr
+		}
+
+		userID = uuid.NewV4().String()
+		record := goqu.Record{
+			"id":                 userID,
+			"email":              email,
+			"encrypted_password": encryptedPassword,
+		}
+		if identity.EmailVerified {
+			record["verified_at"] = time.Now().Format(time.RFC3339)
+		}
+		insertDialect := dialect.Insert("users").Rows(record)
+		insertQuery, _, insertToSQLErr := insertDialect.ToSQL()
+		if insertToSQLErr != nil {
+			return models.User{}, insertToSQLErr
+		}
+		if _, insertErr := tx.Exec(insertQuery); insertErr != nil {
+			return models.User{}, insertErr
+		}
+	default:
+		return models.User{}, lookupErr
+	}
+
+	linkRecord := goqu.Record{
+		"id":       uuid.NewV4(),
+		"users_id": userID,
+	}
+	for column, value := range linkExpression {
+		linkRecord[column] = value
+	}
+	insertLinkDialect := dialect.Insert("users_external_identities").Rows(linkRecord)
+	insertLinkQuery, _, insertLinkToSQLErr := insertLinkDialect.ToSQL()
+	if insertLinkToSQLErr != nil {
+		return models.User{}, insertLinkToSQLErr
+	}
+	if _, insertLinkErr := tx.Exec(insertLinkQuery); insertLinkErr != nil {
+		return models.User{}, insertLinkErr
+	}
+
+	if commitErr := tx.Commit(); commitErr != nil {
+		return models.User{}, commitErr
+	}
+
+	return GetUser(dialect, db, userID, "")
+}
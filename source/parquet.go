@@ -0,0 +1,377 @@
+package source
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"math"
+	"time"
+)
+
+// Parquet files are written uncompressed with a single PLAIN encoded data page per column and row
+// group, every column is optional. Only the parts of the format the export requires are
+// implemented, see https://github.com/apache/parquet-format
+
+// parquetMagic - marks the start and the end of a Parquet file
+const parquetMagic = "PAR1"
+
+// parquetRowGroupRows, parquetRowGroupBytes - a row group is written once either is reached,
+// bounding the memory used to buffer it
+const (
+	parquetRowGroupRows  = 10000
+	parquetRowGroupBytes = 8 << 20
+)
+
+// Physical types, converted types, encodings and other enums of the Parquet metadata
+const (
+	parquetInt64           = 2
+	parquetDouble          = 5
+	parquetByteArray       = 6
+	parquetUTF8            = 0
+	parquetTimestampMicros = 10
+	parquetOptional        = 1
+	parquetPlain           = 0
+	parquetRLE             = 3
+	parquetDataPage        = 0
+	parquetUncompressed    = 0
+)
+
+// Types of the Thrift compact protocol the metadata is encoded with
+const (
+	thriftI32    = 5
+	thriftI64    = 6
+	thriftBinary = 8
+	thriftList   = 9
+	thriftStruct = 12
+)
+
+// thriftWriter - Encodes structs using the Thrift compact protocol
+type thriftWriter struct {
+	buf     bytes.Buffer
+	lastID  int16
+	parents []int16
+}
+
+// uvarint - Write the unsigned integer as ULEB128
+func (t *thriftWriter) uvarint(v uint64) {
+	var encoded [binary.MaxVarintLen64]byte
+	t.buf.Write(encoded[:binary.PutUvarint(encoded[:], v)])
+}
+
+// varint - Write the signed integer zigzag encoded
+func (t *thriftWriter) varint(v int64) {
+	t.uvarint(uint64((v << 1) ^ (v >> 63)))
+}
+
+// field - Write the header of a field, ids are delta encoded when possible
+func (t *thriftWriter) field(id int16, fieldType byte) {
+	if delta := id - t.lastID; delta > 0 && delta <= 15 {
+		t.buf.WriteByte(byte(delta)<<4 | fieldType)
+	} else {
+		t.buf.WriteByte(fieldType)
+		t.varint(int64(id))
+	}
+	t.lastID = id
+}
+
+// i32 - Write an i32 field
+func (t *thriftWriter) i32(id int16, v int32) {
+	t.field(id, thriftI32)
+	t.varint(int64(v))
+}
+
+// i64 - Write an i64 field
+func (t *thriftWriter) i64(id int16, v int64) {
+	t.field(id, thriftI64)
+	t.varint(v)
+}
+
+// binary - Write a string field
+func (t *thriftWriter) binary(id int16, v string) {
+	t.field(id, thriftBinary)
+	t.uvarint(uint64(len(v)))
+	t.buf.WriteString(v)
+}
+
+// list - Write the header of a list field, followed by its elements
+func (t *thriftWriter) list(id int16, elementType byte, size int) {
+	t.field(id, thriftList)
+	if size < 15 {
+		t.buf.WriteByte(byte(size)<<4 | elementType)
+	} else {
+		t.buf.WriteByte(0xf0 | elementType)
+		t.uvarint(uint64(size))
+	}
+}
+
+// listI32 - Write an i32 element of a list
+func (t *thriftWriter) listI32(v int32) {
+	t.varint(int64(v))
+}
+
+// listBinary - Write a string element of a list
+func (t *thriftWriter) listBinary(v string) {
+	t.uvarint(uint64(len(v)))
+	t.buf.WriteString(v)
+}
+
+// begin - Start a nested struct, written as a field when an id is given or as a list element
+func (t *thriftWriter) begin(id int16) {
+	if id > 0 {
+		t.field(id, thriftStruct)
+	}
+	t.parents = append(t.parents, t.lastID)
+	t.lastID = 0
+}
+
+// end - Finish the nested struct
+func (t *thriftWriter) end() {
+	t.buf.WriteByte(0)
+	t.lastID = t.parents[len(t.parents)-1]
+	t.parents = t.parents[:len(t.parents)-1]
+}
+
+// parquetColumn - A column of the file and the values of the row group being buffered
+type parquetColumn struct {
+	ExportColumn
+	levels []byte
+	values bytes.Buffer
+}
+
+// parquetChunk - Location of a column chunk written to the file
+type parquetChunk struct {
+	offset    int64
+	size      int64
+	numValues int64
+}
+
+// parquetRowGroup - Location of a row group written to the file
+type parquetRowGroup struct {
+	chunks []parquetChunk
+	size   int64
+	rows   int64
+}
+
+// parquetWriter - Writes rows as a Parquet file, buffering a row group at a time
+type parquetWriter struct {
+	out       io.Writer
+	offset    int64
+	columns   []*parquetColumn
+	rows      int
+	rowGroups []parquetRowGroup
+	closed    bool
+}
+
+// newParquetWriter - Start a Parquet file with the columns
+func newParquetWriter(out io.Writer, columns []ExportColumn) (*parquetWriter, error) {
+	writer := &parquetWriter{out: out}
+	for _, column := range columns {
+		writer.columns = append(writer.columns, &parquetColumn{ExportColumn: column})
+	}
+	if writeErr := writer.write([]byte(parquetMagic)); writeErr != nil {
+		return nil, writeErr
+	}
+	return writer, nil
+}
+
+// write - Write to the file keeping track of the offset
+func (w *parquetWriter) write(data []byte) error {
+	written, writeErr := w.out.Write(data)
+	w.offset += int64(written)
+	return writeErr
+}
+
+// physicalType - Parquet type the values of the column are stored as
+func (column *parquetColumn) physicalType() int32 {
+	switch column.Kind {
+	case ExportInt, ExportTime:
+		return parquetInt64
+	case ExportFloat:
+		return parquetDouble
+	default:
+		return parquetByteArray
+	}
+}
+
+// Write - Buffer a row, values are nil or of the Go type of the kind of their column
+func (w *parquetWriter) Write(values []interface{}) error {
+	if len(values) != len(w.columns) {
+		return errors.New("Row does not match the columns of the export")
+	}
+
+	buffered := 0
+	for i, column := range w.columns {
+		if values[i] == nil {
+			column.levels = append(column.levels, 0)
+			continue
+		}
+		column.levels = append(column.levels, 1)
+
+		var encoded [8]byte
+		switch value := values[i].(type) {
+		case string:
+			binary.LittleEndian.PutUint32(encoded[:4], uint32(len(value)))
+			column.values.Write(encoded[:4])
+			column.values.WriteString(value)
+		case int64:
+			binary.LittleEndian.PutUint64(encoded[:], uint64(value))
+			column.values.Write(encoded[:])
+		case float64:
+			binary.LittleEndian.PutUint64(encoded[:], math.Float64bits(value))
+			column.values.Write(encoded[:])
+		case time.Time:
+			binary.LittleEndian.PutUint64(encoded[:], uint64(value.UnixNano()/int64(time.Microsecond)))
+			column.values.Write(encoded[:])
+		default:
+			return errors.New("Unsupported value in column " + column.Name)
+		}
+		buffered += column.values.Len()
+	}
+
+	w.rows++
+	if w.rows >= parquetRowGroupRows || buffered >= parquetRowGroupBytes {
+		return w.flush()
+	}
+	return nil
+}
+
+// definitionLevels - Encode the levels using runs of the RLE/bit-packing hybrid with a bit width
+// of 1, prefixed by their length
+func definitionLevels(levels []byte) []byte {
+	var runs thriftWriter
+	for start := 0; start < len(levels); {
+		end := start + 1
+		for end < len(levels) && levels[end] == levels[start] {
+			end++
+		}
+		runs.uvarint(uint64(end-start) << 1)
+		runs.buf.WriteByte(levels[start])
+		start = end
+	}
+
+	encoded := make([]byte, 4, 4+runs.buf.Len())
+	binary.LittleEndian.PutUint32(encoded, uint32(runs.buf.Len()))
+	return append(encoded, runs.buf.Bytes()...)
+}
+
+// flush - Write the buffered rows as a row group
+func (w *parquetWriter) flush() error {
+	if w.rows == 0 {
+		return nil
+	}
+
+	rowGroup := parquetRowGroup{rows: int64(w.rows)}
+	for _, column := range w.columns {
+		page := append(definitionLevels(column.levels), column.values.Bytes()...)
+
+		var header thriftWriter
+		header.i32(1, parquetDataPage)
+		header.i32(2, int32(len(page)))
+		header.i32(3, int32(len(page)))
+		header.begin(5)
+		header.i32(1, int32(w.rows))
+		header.i32(2, parquetPlain)
+		header.i32(3, parquetRLE)
+		header.i32(4, parquetRLE)
+		header.end()
+		header.buf.WriteByte(0)
+
+		chunk := parquetChunk{
+			offset:    w.offset,
+			size:      int64(header.buf.Len() + len(page)),
+			numValues: int64(w.rows),
+		}
+		if writeErr := w.write(header.buf.Bytes()); writeErr != nil {
+			return writeErr
+		}
+		if writeErr := w.write(page); writeErr != nil {
+			return writeErr
+		}
+		rowGroup.chunks = append(rowGroup.chunks, chunk)
+		rowGroup.size += chunk.size
+
+		column.levels = column.levels[:0]
+		column.values.Reset()
+	}
+
+	w.rowGroups = append(w.rowGroups, rowGroup)
+	w.rows = 0
+	return nil
+}
+
+// Close - Write the remaining rows and the footer describing the file
+func (w *parquetWriter) Close() error {
+	if w.closed {
+		return nil
+	}
+	w.closed = true
+	if flushErr := w.flush(); flushErr != nil {
+		return flushErr
+	}
+
+	var totalRows int64
+	for _, rowGroup := range w.rowGroups {
+		totalRows += rowGroup.rows
+	}
+
+	var footer thriftWriter
+	footer.i32(1, 1)
+	footer.list(2, thriftStruct, len(w.columns)+1)
+	footer.begin(0)
+	footer.binary(4, "schema")
+	footer.i32(5, int32(len(w.columns)))
+	footer.end()
+	for _, column := range w.columns {
+		footer.begin(0)
+		footer.i32(1, column.physicalType())
+		footer.i32(3, parquetOptional)
+		footer.binary(4, column.Name)
+		switch column.Kind {
+		case ExportString:
+			footer.i32(6, parquetUTF8)
+		case ExportTime:
+			footer.i32(6, parquetTimestampMicros)
+		}
+		footer.end()
+	}
+	footer.i64(3, totalRows)
+	footer.list(4, thriftStruct, len(w.rowGroups))
+	for _, rowGroup := range w.rowGroups {
+		footer.begin(0)
+		footer.list(1, thriftStruct, len(rowGroup.chunks))
+		for i, chunk := range rowGroup.chunks {
+			footer.begin(0)
+			footer.i64(2, chunk.offset)
+			footer.begin(3)
+			footer.i32(1, w.columns[i].physicalType())
+			footer.list(2, thriftI32, 2)
+			footer.listI32(parquetPlain)
+			footer.listI32(parquetRLE)
+			footer.list(3, thriftBinary, 1)
+			footer.listBinary(w.columns[i].Name)
+			footer.i32(4, parquetUncompressed)
+			footer.i64(5, chunk.numValues)
+			footer.i64(6, chunk.size)
+			footer.i64(7, chunk.size)
+			footer.i64(9, chunk.offset)
+			footer.end()
+			footer.end()
+		}
+		footer.i64(2, rowGroup.size)
+		footer.i64(3, rowGroup.rows)
+		footer.end()
+	}
+	footer.binary(6, "graphql-example-go")
+	footer.buf.WriteByte(0)
+
+	var length [4]byte
+	binary.LittleEndian.PutUint32(length[:], uint32(footer.buf.Len()))
+	if writeErr := w.write(footer.buf.Bytes()); writeErr != nil {
+		return writeErr
+	}
+	if writeErr := w.write(length[:]); writeErr != nil {
+		return writeErr
+	}
+	return w.write([]byte(parquetMagic))
+}
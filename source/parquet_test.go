@@ -0,0 +1,245 @@
+package source
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// thriftReader - Decodes structs written using the Thrift compact protocol into maps of field ids
+// to values, independently of thriftWriter
+type thriftReader struct {
+	data []byte
+	pos  int
+}
+
+// uvarint - Read an ULEB128 encoded integer
+func (r *thriftReader) uvarint() uint64 {
+	v, n := binary.Uvarint(r.data[r.pos:])
+	if n <= 0 {
+		panic("invalid varint")
+	}
+	r.pos += n
+	return v
+}
+
+// varint - Read a zigzag encoded integer
+func (r *thriftReader) varint() int64 {
+	v := r.uvarint()
+	return int64(v>>1) ^ -int64(v&1)
+}
+
+// value - Read a value of the compact protocol type, integers are returned as int64, lists as
+// []interface{} and structs as map[int16]interface{}
+func (r *thriftReader) value(valueType byte) interface{} {
+	switch valueType {
+	case 1:
+		return true
+	case 2:
+		return false
+	case 3:
+		r.pos++
+		return int64(int8(r.data[r.pos-1]))
+	case 4, thriftI32, thriftI64:
+		return r.varint()
+	case 7:
+		r.pos += 8
+		return math.Float64frombits(binary.LittleEndian.Uint64(r.data[r.pos-8 : r.pos]))
+	case thriftBinary:
+		size := int(r.uvarint())
+		r.pos += size
+		return string(r.data[r.pos-size : r.pos])
+	case thriftList:
+		header := r.data[r.pos]
+		r.pos++
+		size := int(header >> 4)
+		if size == 15 {
+			size = int(r.uvarint())
+		}
+		elements := []interface{}{}
+		for i := 0; i < size; i++ {
+			elements = append(elements, r.value(header&0x0f))
+		}
+		return elements
+	case thriftStruct:
+		return r.structure()
+	}
+	panic("unsupported thrift type")
+}
+
+// structure - Read the fields of a struct up to its stop byte
+func (r *thriftReader) structure() map[int16]interface{} {
+	fields := map[int16]interface{}{}
+	var lastID int16
+	for {
+		header := r.data[r.pos]
+		r.pos++
+		if header == 0 {
+			return fields
+		}
+		id := lastID + int16(header>>4)
+		if header>>4 == 0 {
+			id = int16(r.varint())
+		}
+		fields[id] = r.value(header & 0x0f)
+		lastID = id
+	}
+}
+
+// readParquet - Decode the footer of the file and read the values of every column back, rows
+// holding nil for null values
+func readParquet(t *testing.T, file []byte) (map[int16]interface{}, [][]interface{}) {
+	if !assert.True(t, bytes.HasPrefix(file, []byte(parquetMagic)) && bytes.HasSuffix(file, []byte(parquetMagic))) {
+		t.FailNow()
+	}
+	footerLength := int(binary.LittleEndian.Uint32(file[len(file)-8:]))
+	footerStart := len(file) - 8 - footerLength
+	footer := &thriftReader{data: file[footerStart : len(file)-8]}
+	metadata := footer.structure()
+	assert.Equal(t, footerLength, footer.pos, "Footer should be consumed entirely")
+
+	schema := metadata[2].([]interface{})
+	rows := [][]interface{}{}
+	for _, rowGroup := range metadata[4].([]interface{}) {
+		chunks := rowGroup.(map[int16]interface{})[1].([]interface{})
+		groupRows := int(rowGroup.(map[int16]interface{})[3].(int64))
+		values := make([][]interface{}, groupRows)
+		for i, chunk := range chunks {
+			chunkMetadata := chunk.(map[int16]interface{})[3].(map[int16]interface{})
+			physicalType := chunkMetadata[1].(int64)
+			assert.Equal(t, schema[i+1].(map[int16]interface{})[1], physicalType)
+
+			offset := int(chunkMetadata[9].(int64))
+			pageReader := &thriftReader{data: file[offset:footerStart]}
+			header := pageReader.structure()
+			assert.Equal(t, int64(parquetDataPage), header[1])
+			assert.Equal(t, int64(pageReader.pos)+header[3].(int64), chunkMetadata[6].(int64), "Chunk size should cover the page header and data")
+			page := pageReader.data[pageReader.pos : pageReader.pos+int(header[3].(int64))]
+
+			// Definition levels are runs of the RLE/bit-packing hybrid with a bit width of 1
+			levelsLength := int(binary.LittleEndian.Uint32(page))
+			levelReader := &thriftReader{data: page[4 : 4+levelsLength]}
+			levels := []byte{}
+			for levelReader.pos < levelsLength {
+				run := levelReader.uvarint()
+				if !assert.Equal(t, uint64(0), run&1, "Only RLE runs are written") {
+					t.FailNow()
+				}
+				level := levelReader.data[levelReader.pos]
+				levelReader.pos++
+				for n := uint64(0); n < run>>1; n++ {
+					levels = append(levels, level)
+				}
+			}
+			if !assert.Len(t, levels, groupRows) {
+				t.FailNow()
+			}
+
+			data := page[4+levelsLength:]
+			for row, level := range levels {
+				if level == 0 {
+					values[row] = append(values[row], nil)
+					continue
+				}
+				switch physicalType {
+				case parquetInt64:
+					values[row] = append(values[row], int64(binary.LittleEndian.Uint64(data)))
+					data = data[8:]
+				case parquetDouble:
+					values[row] = append(values[row], math.Float64frombits(binary.LittleEndian.Uint64(data)))
+					data = data[8:]
+				case parquetByteArray:
+					size := int(binary.LittleEndian.Uint32(data))
+					values[row] = append(values[row], string(data[4:4+size]))
+					data = data[4+size:]
+				}
+			}
+			assert.Empty(t, data, "Every value should be read")
+		}
+		rows = append(rows, values...)
+	}
+	return metadata, rows
+}
+
+func TestParquetWriter(t *testing.T) {
+	columns := []ExportColumn{
+		{Name: "name", Kind: ExportString},
+		{Name: "release_year", Kind: ExportInt},
+		{Name: "rating", Kind: ExportFloat},
+		{Name: "created_at", Kind: ExportTime},
+	}
+	createdAt := time.Date(2019, 8, 9, 10, 11, 12, 13000, time.UTC)
+	input := [][]interface{}{
+		{"Scary Stories to Tell in the Dark", int64(2019), 4.5, createdAt},
+		{"Amélie", nil, nil, createdAt},
+		{nil, int64(2001), 3.25, nil},
+	}
+
+	var file bytes.Buffer
+	writer, err := newParquetWriter(&file, columns)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, row := range input {
+		if err := writer.Write(row); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	metadata, rows := readParquet(t, file.Bytes())
+	assert.Equal(t, int64(1), metadata[1], "Version")
+	assert.Equal(t, int64(len(input)), metadata[3], "Rows")
+
+	schema := metadata[2].([]interface{})
+	if assert.Len(t, schema, len(columns)+1) {
+		assert.Equal(t, int64(len(columns)), schema[0].(map[int16]interface{})[5], "Root should hold every column")
+		for i, column := range columns {
+			element := schema[i+1].(map[int16]interface{})
+			assert.Equal(t, column.Name, element[4])
+			assert.Equal(t, int64(parquetOptional), element[3])
+		}
+		assert.Equal(t, int64(parquetUTF8), schema[1].(map[int16]interface{})[6])
+		assert.Equal(t, int64(parquetTimestampMicros), schema[4].(map[int16]interface{})[6])
+	}
+
+	micros := createdAt.UnixNano() / int64(time.Microsecond)
+	assert.Equal(t, [][]interface{}{
+		{"Scary Stories to Tell in the Dark", int64(2019), 4.5, micros},
+		{"Amélie", nil, nil, micros},
+		{nil, int64(2001), 3.25, nil},
+	}, rows)
+
+	assert.Error(t, writer.Write([]interface{}{"Too few values"}))
+}
+
+func TestParquetWriterRowGroups(t *testing.T) {
+	var file bytes.Buffer
+	writer, err := newParquetWriter(&file, []ExportColumn{{Name: "id", Kind: ExportInt}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	total := parquetRowGroupRows + 5
+	for i := 0; i < total; i++ {
+		if err := writer.Write([]interface{}{int64(i)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	metadata, rows := readParquet(t, file.Bytes())
+	assert.Len(t, metadata[4], 2, "Rows beyond the limit should start a new row group")
+	assert.Equal(t, int64(total), metadata[3])
+	if assert.Len(t, rows, total) {
+		assert.Equal(t, []interface{}{int64(0)}, rows[0])
+		assert.Equal(t, []interface{}{int64(total - 1)}, rows[total-1])
+	}
+}
@@ -0,0 +1,204 @@
+package source
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/doug-martin/goqu/v8"
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+
+	config "github.com/HencoSmith/graphql-example-go/config/struct"
+	"github.com/HencoSmith/graphql-example-go/models"
+)
+
+// Password hashing algorithms
+const (
+	// AlgorithmBcrypt - bcrypt with a configurable cost
+	AlgorithmBcrypt = "bcrypt"
+	// AlgorithmArgon2id - argon2id with configurable time, memory and parallelism
+	AlgorithmArgon2id = "argon2id"
+)
+
+// argon2Prefix - prefix of argon2id hashes encoded in the PHC string format
+const argon2Prefix = "$argon2id$"
+
+// argon2SaltLength - bytes of random salt per argon2id hash
+const argon2SaltLength = 16
+
+// argon2Params - Parameters encoded in an argon2id hash
+type argon2Params struct {
+	Time      uint32
+	Memory    uint32
+	Threads   uint8
+	KeyLength uint32
+}
+
+// hashConfig - Lookup the configured password hashing, unset values fall back to sensible defaults
+func hashConfig() config.PasswordHashConfiguration {
+	// Read configuration file
+	hashConfig := GetConfig(".").PasswordHash
+
+	if hashConfig.Algorithm != AlgorithmArgon2id {
+		hashConfig.Algorithm = AlgorithmBcrypt
+	}
+	if hashConfig.Cost < bcrypt.MinCost || hashConfig.Cost > bcrypt.MaxCost {
+		hashConfig.Cost = bcrypt.DefaultCost
+	}
+	if hashConfig.Argon2.Time == 0 {
+		hashConfig.Argon2.Time = 1
+	}
+	if hashConfig.Argon2.Memory == 0 {
+		hashConfig.Argon2.Memory = 64 * 1024
+	}
+	if hashConfig.Argon2.Threads == 0 {
+		hashConfig.Argon2.Threads = 4
+	}
+	if hashConfig.Argon2.KeyLength == 0 {
+		hashConfig.Argon2.KeyLength = 32
+	}
+
+	return hashConfig
+}
+
+// Hash - Hash the text using the configured algorithm, return the hash or an error
+func Hash(text string) (string, error) {
+	hashConfig := hashConfig()
+
+	if hashConfig.Algorithm == AlgorithmArgon2id {
+		salt := make([]byte, argon2SaltLength)
+		if _, err := rand.Read(salt); err != nil {
+			return "", err
+		}
+
+		params := argon2Params(hashConfig.Argon2)
+		key := argon2.IDKey([]byte(text), salt, params.Time, params.Memory, params.Threads, params.KeyLength)
+		return fmt.Sprintf("%sv=%d$m=%d,t=%d,p=%d$%s$%s",
+			argon2Prefix,
+			argon2.Version,
+			params.Memory,
+			params.Time,
+			params.Threads,
+			base64.RawStdEncoding.EncodeToString(salt),
+			base64.RawStdEncoding.EncodeToString(key),
+		), nil
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(text), hashConfig.Cost)
+	if err != nil {
+		return "", err
+	}
+
+	return string(hash), nil
+}
+
+// parseArgon2Hash - Decode the parameters, salt and key of an argon2id hash
+func parseArgon2Hash(hashedText string) (argon2Params, []byte, []byte, error) {
+	invalid := errors.New("Invalid argon2id hash")
+
+	// "", "argon2id", "v=19", "m=65536,t=1,p=4", salt, key
+	parts := strings.Split(hashedText, "$")
+	if len(parts) != 6 {
+		return argon2Params{}, nil, nil, invalid
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return argon2Params{}, nil, nil, invalid
+	}
+
+	var params argon2Params
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.Memory, &params.Time, &params.Threads); err != nil {
+		return argon2Params{}, nil, nil, invalid
+	}
+
+	salt, saltErr := base64.RawStdEncoding.DecodeString(parts[4])
+	if saltErr != nil {
+		return argon2Params{}, nil, nil, invalid
+	}
+	key, keyErr := base64.RawStdEncoding.DecodeString(parts[5])
+	if keyErr != nil || len(key) == 0 {
+		return argon2Params{}, nil, nil, invalid
+	}
+	params.KeyLength = uint32(len(key))
+
+	return params, salt, key, nil
+}
+
+// ValidHash - Compare the hash and text using the algorithm of the hash, returns true if valid
+// or false along with an error if invalid
+func ValidHash(text string, hashedText string) (bool, error) {
+	if strings.HasPrefix(hashedText, argon2Prefix) {
+		params, salt, key, parseErr := parseArgon2Hash(hashedText)
+		if parseErr != nil {
+			return false, parseErr
+		}
+
+		compared := argon2.IDKey([]byte(text), salt, params.Time, params.Memory, params.Threads, params.KeyLength)
+		if subtle.ConstantTimeCompare(key, compared) != 1 {
+			return false, errors.New("Hash does not match the text")
+		}
+		return true, nil
+	}
+
+	err := bcrypt.CompareHashAndPassword([]byte(hashedText), []byte(text))
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// NeedsRehash - Determine whether the hash was created with another algorithm or weaker
+// parameters than currently configured
+func NeedsRehash(hashedText string) bool {
+	hashConfig := hashConfig()
+
+	if strings.HasPrefix(hashedText, argon2Prefix) {
+		if hashConfig.Algorithm != AlgorithmArgon2id {
+			return true
+		}
+		params, _, _, parseErr := parseArgon2Hash(hashedText)
+		return parseErr != nil || params != argon2Params(hashConfig.Argon2)
+	}
+
+	if hashConfig.Algorithm != AlgorithmBcrypt {
+		return true
+	}
+	cost, costErr := bcrypt.Cost([]byte(hashedText))
+	return costErr != nil || cost != hashConfig.Cost
+}
+
+// RehashPassword - Upgrade the stored hash of the user if it uses outdated parameters, must only
+// be called with the verified password
+func RehashPassword(dialect goqu.DialectWrapper, db execer, user models.User, password string) error {
+	if !NeedsRehash(user.EncryptedPassword) {
+		return nil
+	}
+
+	encryptedPassword, hashErr := Hash(password)
+	if hashErr != nil {
+		return hashErr
+	}
+
+	// Only replace the hash which was verified, a concurrent password change wins
+	updateDialect := dialect.Update("users").Set(
+		goqu.Record{
+			"encrypted_password": encryptedPassword,
+		},
+	).Where(goqu.Ex{
+		"id":                 user.ID,
+		"encrypted_password": user.EncryptedPassword,
+		"deleted_at":         nil,
+	})
+	updateQuery, _, toSQLErr := updateDialect.ToSQL()
+	if toSQLErr != nil {
+		return toSQLErr
+	}
+
+	_, updateErr := db.Exec(updateQuery)
+	return updateErr
+}
@@ -0,0 +1,158 @@
+package source
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"strings"
+	"testing"
+
+	"github.com/doug-martin/goqu/v8"
+	_ "github.com/doug-martin/goqu/v8/dialect/postgres"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/HencoSmith/graphql-example-go/models"
+)
+
+// lightArgon2 - argon2id parameters cheap enough for tests
+func lightArgon2() func() {
+	restoreTime := withConfig("passwordHash.argon2.time", 1)
+	restoreMemory := withConfig("passwordHash.argon2.memory", 1024)
+	return func() {
+		restoreMemory()
+		restoreTime()
+	}
+}
+
+func TestHashBcrypt(t *testing.T) {
+	defer withConfig("passwordHash.algorithm", AlgorithmBcrypt)()
+	defer withConfig("passwordHash.cost", 4)()
+
+	hash, err := Hash("secret")
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(hash, "$2a$04$"), hash)
+
+	valid, err := ValidHash("secret", hash)
+	assert.True(t, valid)
+	assert.NoError(t, err)
+
+	valid, err = ValidHash("wrong", hash)
+	assert.False(t, valid)
+	assert.Error(t, err)
+
+	assert.False(t, NeedsRehash(hash))
+}
+
+func TestHashArgon2id(t *testing.T) {
+	defer withConfig("passwordHash.algorithm", AlgorithmArgon2id)()
+	defer lightArgon2()()
+
+	hash, err := Hash("secret")
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(hash, "$argon2id$v=19$m=1024,t=1,"), hash)
+
+	// Salted, the same password hashes differently
+	other, err := Hash("secret")
+	assert.NoError(t, err)
+	assert.NotEqual(t, hash, other)
+
+	valid, err := ValidHash("secret", hash)
+	assert.True(t, valid)
+	assert.NoError(t, err)
+
+	valid, err = ValidHash("wrong", hash)
+	assert.False(t, valid)
+	assert.Error(t, err)
+
+	assert.False(t, NeedsRehash(hash))
+}
+
+func TestValidHashMalformed(t *testing.T) {
+	for _, hash := range []string{
+		"",
+		"plain",
+		"$argon2id$v=19$m=1024,t=1,p=4$salt",
+		"$argon2id$v=18$m=1024,t=1,p=4$c2FsdA$a2V5",
+		"$argon2id$v=19$m=1024,t=1,p=4$c2FsdA$",
+	} {
+		valid, err := ValidHash("secret", hash)
+		assert.False(t, valid, hash)
+		assert.Error(t, err, hash)
+	}
+}
+
+func TestNeedsRehash(t *testing.T) {
+	defer withConfig("passwordHash.algorithm", AlgorithmBcrypt)()
+	defer withConfig("passwordHash.cost", 4)()
+	defer lightArgon2()()
+
+	bcryptHash, err := Hash("secret")
+	assert.NoError(t, err)
+
+	// A higher cost upgrades existing hashes
+	restoreCost := withConfig("passwordHash.cost", 5)
+	assert.True(t, NeedsRehash(bcryptHash), "Changed bcrypt cost")
+	restoreCost()
+	assert.False(t, NeedsRehash(bcryptHash))
+
+	// Switching the algorithm upgrades hashes of the previous one
+	restoreAlgorithm := withConfig("passwordHash.algorithm", AlgorithmArgon2id)
+	assert.True(t, NeedsRehash(bcryptHash), "Changed algorithm to argon2id")
+
+	argon2Hash, err := Hash("secret")
+	assert.NoError(t, err)
+	assert.False(t, NeedsRehash(argon2Hash))
+
+	restoreMemory := withConfig("passwordHash.argon2.memory", 2048)
+	assert.True(t, NeedsRehash(argon2Hash), "Changed argon2id memory")
+	restoreMemory()
+
+	restoreThreads := withConfig("passwordHash.argon2.threads", 2)
+	assert.True(t, NeedsRehash(argon2Hash), "Changed argon2id threads")
+	restoreThreads()
+
+	restoreKeyLength := withConfig("passwordHash.argon2.keyLength", 64)
+	assert.True(t, NeedsRehash(argon2Hash), "Changed argon2id key length")
+	restoreKeyLength()
+
+	assert.False(t, NeedsRehash(argon2Hash))
+	restoreAlgorithm()
+
+	assert.True(t, NeedsRehash(argon2Hash), "Changed algorithm to bcrypt")
+	assert.True(t, NeedsRehash("garbage"))
+}
+
+// recordingExecer - execer remembering the statements instead of running them
+type recordingExecer struct {
+	queries []string
+}
+
+// Exec - Remember the statement
+func (execer *recordingExecer) Exec(query string, args ...interface{}) (sql.Result, error) {
+	execer.queries = append(execer.queries, query)
+	return driver.RowsAffected(1), nil
+}
+
+func TestRehashPassword(t *testing.T) {
+	dialect := goqu.Dialect("postgres")
+	defer withConfig("passwordHash.algorithm", AlgorithmBcrypt)()
+	defer withConfig("passwordHash.cost", 4)()
+	defer lightArgon2()()
+
+	bcryptHash, err := Hash("secret")
+	assert.NoError(t, err)
+	user := models.User{ID: "d56d4bff-4e7e-4cf9-a3d2-38973c9dd57d", EncryptedPassword: bcryptHash}
+
+	// Current hashes are left alone
+	db := &recordingExecer{}
+	assert.NoError(t, RehashPassword(dialect, db, user, "secret"))
+	assert.Empty(t, db.queries)
+
+	// Outdated hashes are replaced by a hash of the configured algorithm, only if unchanged since
+	restoreAlgorithm := withConfig("passwordHash.algorithm", AlgorithmArgon2id)
+	defer restoreAlgorithm()
+	assert.NoError(t, RehashPassword(dialect, db, user, "secret"))
+	if assert.Len(t, db.queries, 1) {
+		assert.Contains(t, db.queries[0], `"encrypted_password"='$argon2id$`)
+		assert.Contains(t, db.queries[0], `("encrypted_password" = '`+bcryptHash+`')`)
+	}
+}
@@ -0,0 +1,142 @@
+package source
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/doug-martin/goqu/v8"
+	uuid "github.com/satori/go.uuid"
+
+	"github.com/HencoSmith/graphql-example-go/models"
+)
+
+// ErrInvalidResetToken - returned when a reset token does not exist, expired or was already used
+var ErrInvalidResetToken = errors.New("Invalid or expired password reset token")
+
+// RequestPasswordReset - Store a single-use reset token for the user and email a link containing it
+func RequestPasswordReset(dialect goqu.DialectWrapper, db *sql.DB, user models.User) error {
+	// Read configuration file
+	config := GetConfig(".")
+
+	token, tokenErr := RandomToken()
+	if tokenErr != nil {
+		return tokenErr
+	}
+
+	insertDialect := dialect.Insert("users_password_resets").Rows(
+		goqu.Record{
+			"id":         uuid.NewV4(),
+			"users_id":   user.ID,
+			"token_hash": HashToken(token),
+			"expires_at": time.Now().Add(config.PasswordReset.Expiration * time.Minute).Format(time.RFC3339),
+		},
+	)
+	insertQuery, _, toSQLErr := insertDialect.ToSQL()
+	if toSQLErr != nil {
+		return toSQLErr
+	}
+
+	if _, insertErr := db.Exec(insertQuery); insertErr != nil {
+		return insertErr
+	}
+
+	link, linkErr := FormatLink(config.PasswordReset.URL, token)
+	if linkErr != nil {
+		return linkErr
+	}
+
+	return NewMailer(config.Mail).Send(models.Mail{
+		To:      user.Email,
+		Subject: "Reset your password",
+		Body: "A password reset was requested for your account.\n\n" +
+			"Follow the link below to choose a new password, it expires in " +
+			(config.PasswordReset.Expiration * time.Minute).String() + ":\n" +
+			link + "\n\n" +
+			"If you did not request a reset you can ignore this email.",
+	})
+}
+
+// ResetPassword - Consume the reset token and replace the password of the associated user,
+// any other outstanding tokens of the user are invalidated as well
+func ResetPassword(dialect goqu.DialectWrapper, db *sql.DB, token string, password string) error {
+	if len(password) == 0 {
+		return errors.New("Password must not be empty")
+	}
+
+	encryptedPassword, hashErr := Hash(password)
+	if hashErr != nil {
+		return hashErr
+	}
+
+	tx, txErr := db.Begin()
+	if txErr != nil {
+		return txErr
+	}
+	defer tx.Rollback()
+
+	now := time.Now().Format(time.RFC3339)
+
+	// Mark the token as used, only succeeds once for unexpired tokens
+	consumeDialect := dialect.Update("users_password_resets").Set(
+		goqu.Record{
+			"used_at": now,
+		},
+	).Where(
+		goqu.Ex{
+			"token_hash": HashToken(token),
+			"used_at":    nil,
+		},
+		goqu.C("expires_at").Gt(now),
+	).Returning("users_id")
+	consumeQuery, _, toSQLErr := consumeDialect.ToSQL()
+	if toSQLErr != nil {
+		return toSQLErr
+	}
+
+	var usersID string
+	scanErr := tx.QueryRow(consumeQuery).Scan(&usersID)
+	if scanErr == sql.ErrNoRows {
+		return ErrInvalidResetToken
+	}
+	if scanErr != nil {
+		return scanErr
+	}
+
+	// Invalidate other outstanding tokens
+	invalidateDialect := dialect.Update("users_password_resets").Set(
+		goqu.Record{
+			"used_at": now,
+		},
+	).Where(goqu.Ex{
+		"users_id": usersID,
+		"used_at":  nil,
+	})
+	invalidateQuery, _, invalidateToSQLErr := invalidateDialect.ToSQL()
+	if invalidateToSQLErr != nil {
+		return invalidateToSQLErr
+	}
+	if _, invalidateErr := tx.Exec(invalidateQuery); invalidateErr != nil {
+		return invalidateErr
+	}
+
+	// Store the new password
+	updateDialect := dialect.Update("users").Set(
+		goqu.Record{
+			"encrypted_password": encryptedPassword,
+			"updated_at":         now,
+		},
+	).Where(goqu.Ex{
+		"id":         usersID,
+		"deleted_at": nil,
+	})
+	updateQuery, _, updateToSQLErr := updateDialect.ToSQL()
+	if updateToSQLErr != nil {
+		return updateToSQLErr
+	}
+	if _, updateErr := tx.Exec(updateQuery); updateErr != nil {
+		return updateErr
+	}
+
+	return tx.Commit()
+}
@@ -0,0 +1,255 @@
+package source
+
+import (
+	"database/sql"
+	"errors"
+	"log"
+	"net/url"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/doug-martin/goqu/v8"
+	uuid "github.com/satori/go.uuid"
+
+	"github.com/HencoSmith/graphql-example-go/models"
+)
+
+// ErrEmailTaken - returned when another account already uses the email address
+var ErrEmailTaken = errors.New("Email address is already registered")
+
+// NormalizeEmail - Trim and lowercase the email address, addresses are compared
+// case-insensitively
+func NormalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// ValidEmail - Perform a basic sanity check of the email address
+func ValidEmail(email string) bool {
+	return len(email) >= 3 && len(email) <= 64 && strings.Contains(email, "@")
+}
+
+// UpdateProfile - Store the profile fields of the user, nil fields are left unchanged
+func UpdateProfile(dialect goqu.DialectWrapper, db *sql.DB, user models.User, displayName *string, avatarURL *string) (models.User, error) {
+	fields := goqu.Record{
+		"updated_at": time.Now().Format(time.RFC3339),
+	}
+
+	if displayName != nil {
+		name := strings.TrimSpace(*displayName)
+		if utf8.RuneCountInString(name) > 64 {
+			return models.User{}, errors.New("Display name must not exceed 64 characters")
+		}
+		fields["display_name"] = name
+	}
+
+	if avatarURL != nil {
+		avatar := strings.TrimSpace(*avatarURL)
+		// Clients render the avatar, only allow web links
+		if len(avatar) > 0 {
+			parsed, parseErr := url.Parse(avatar)
+			if parseErr != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || len(parsed.Host) == 0 || len(avatar) > 512 {
+				return models.User{}, errors.New("Avatar URL must be an http(s) link of at most 512 characters")
+			}
+		}
+		fields["avatar_url"] = avatar
+	}
+
+	if updateErr := updateUser(dialect, db, user.ID, fields); updateErr != nil {
+		return models.User{}, updateErr
+	}
+
+	return GetUser(dialect, db, user.ID, "")
+}
+
+// ChangePassword - Replace the password of the user after verifying the current one, outstanding
+// password reset tokens are invalidated
+func ChangePassword(dialect goqu.DialectWrapper, db *sql.DB, user models.User, oldPassword string, newPassword string, ip string) error {
+	if verifyErr := VerifyPassword(dialect, db, user, oldPassword, ip); verifyErr != nil {
+		return verifyErr
+	}
+	if len(newPassword) == 0 {
+		return errors.New("Password must not be empty")
+	}
+
+	encryptedPassword, hashErr := Hash(newPassword)
+	if hashErr != nil {
+		return hashErr
+	}
+
+	tx, txErr := db.Begin()
+	if txErr != nil {
+		return txErr
+	}
+	defer tx.Rollback()
+
+	now := time.Now().Format(time.RFC3339)
+
+	invalidateDialect := dialect.Update("users_password_resets").Set(
+		goqu.Record{
+			"used_at": now,
+		},
+	).Where(goqu.Ex{
+		"users_id": user.ID,
+		"used_at":  nil,
+	})
+	invalidateQuery, _, invalidateToSQLErr := invalidateDialect.ToSQL()
+	if invalidateToSQLErr != nil {
+		return invalidateToSQLErr
+	}
+	if _, invalidateErr := tx.Exec(invalidateQuery); invalidateErr != nil {
+		return invalidateErr
+	}
+
+	if updateErr := updateUser(dialect, tx, user.ID, goqu.Record{
+		"encrypted_password": encryptedPassword,
+		"updated_at":         now,
+	}); updateErr != nil {
+		return updateErr
+	}
+
+	return tx.Commit()
+}
+
+// Signup - Create an account for the email and mail a verification link to it. If the email is
+// already registered its owner is notified instead, the result is the same either way so signup
+// does not reveal which emails have accounts
+func Signup(dialect goqu.DialectWrapper, db *sql.DB, email string, password string) error {
+	email = NormalizeEmail(email)
+	if !ValidEmail(email) {
+		return errors.New("Invalid email address")
+	}
+	if len(password) == 0 {
+		return errors.New("Password must not be empty")
+	}
+
+	encryptedPassword, hashErr := Hash(password)
+	if hashErr != nil {
+		return hashErr
+	}
+
+	// Existing accounts are detected by the unique email index, both cases run the same query
+	id := uuid.NewV4().String()
+	insertDialect := dialect.Insert("users").Rows(
+		goqu.Record{
+			"id":                 id,
+			"email":              email,
+			"encrypted_password": encryptedPassword,
+		},
+	).OnConflict(goqu.DoNothing())
+	insertQuery, _, toSQLErr := insertDialect.ToSQL()
+	if toSQLErr != nil {
+		return toSQLErr
+	}
+
+	insertRes, insertErr := db.Exec(insertQuery)
+	if insertErr != nil {
+		return insertErr
+	}
+	inserted, _ := insertRes.RowsAffected()
+
+	// Mails are sent in the background so the response takes as long in both cases, the account
+	// exists even if delivery fails and the link can be sent again
+	go func() {
+		if inserted < 1 {
+			if notifyErr := notifyEmailTaken(email); notifyErr != nil {
+				log.Println("signup notification failed:", notifyErr)
+			}
+			return
+		}
+
+		user, findErr := GetUser(dialect, db, id, "")
+		if findErr != nil {
+			log.Println("email verification failed:", findErr)
+			return
+		}
+		if verifyErr := RequestEmailVerification(dialect, db, user); verifyErr != nil {
+			log.Println("email verification failed:", verifyErr)
+		}
+	}()
+
+	return nil
+}
+
+// ChangeEmail - Request a change of the email of the user after verifying the password, the email
+// is only replaced once the link mailed to the new address is followed. The result is the same
+// whether or not the new address is taken so it does not reveal which emails have accounts
+func ChangeEmail(dialect goqu.DialectWrapper, db *sql.DB, user models.User, password string, email string, ip string) (models.User, error) {
+	if verifyErr := VerifyPassword(dialect, db, user, password, ip); verifyErr != nil {
+		return models.User{}, verifyErr
+	}
+
+	email = NormalizeEmail(email)
+	if !ValidEmail(email) {
+		return models.User{}, errors.New("Invalid email address")
+	}
+	if strings.EqualFold(email, user.Email) {
+		return models.User{}, errors.New("Email address is unchanged")
+	}
+
+	go func() {
+		if changeErr := requestEmailChange(dialect, db, user, email); changeErr != nil {
+			log.Println("email change failed:", changeErr)
+		}
+	}()
+
+	return user, nil
+}
+
+// requestEmailChange - Mail a verification link to the new address of the user and notify the
+// current address, the owner is notified instead if the new address is taken
+func requestEmailChange(dialect goqu.DialectWrapper, db *sql.DB, user models.User, email string) error {
+	_, lookupErr := GetUser(dialect, db, "", email)
+	if lookupErr == nil {
+		return notifyEmailTaken(email)
+	}
+	if lookupErr != ErrUserNotFound {
+		return lookupErr
+	}
+
+	pending := user
+	pending.Email = email
+	if verifyErr := RequestEmailVerification(dialect, db, pending); verifyErr != nil {
+		return verifyErr
+	}
+
+	// Read configuration file
+	config := GetConfig(".")
+
+	return NewMailer(config.Mail).Send(models.Mail{
+		To:      user.Email,
+		Subject: "Your email address is being changed",
+		Body: "A change of the email address of your account to " + email + " was requested, " +
+			"it takes effect once the link mailed to the new address is followed.\n\n" +
+			"If you did not make this change reset your password and contact support.",
+	})
+}
+
+// notifyEmailTaken - Tell the owner of the email that it was used to sign up or change the email
+// of another account, no changes are made
+func notifyEmailTaken(email string) error {
+	// Read configuration file
+	config := GetConfig(".")
+
+	return NewMailer(config.Mail).Send(models.Mail{
+		To:      email,
+		Subject: "Your email address is already registered",
+		Body: "Someone tried to use this email address for another account, no changes were made.\n\n" +
+			"If this was you, log in to your existing account or reset its password.",
+	})
+}
+
+// updateUser - Store the fields of the user
+func updateUser(dialect goqu.DialectWrapper, db execer, userID string, fields goqu.Record) error {
+	updateDialect := dialect.Update("users").Set(fields).Where(goqu.Ex{
+		"id":         userID,
+		"deleted_at": nil,
+	})
+	updateQuery, _, toSQLErr := updateDialect.ToSQL()
+	if toSQLErr != nil {
+		return toSQLErr
+	}
+
+	_, updateErr := db.Exec(updateQuery)
+	return updateErr
+}
@@ -0,0 +1,101 @@
+package source
+
+import (
+	"database/sql"
+	"log"
+	"time"
+
+	"github.com/doug-martin/goqu/v8"
+)
+
+// PurgeDeletedMovies - Permanently remove movies soft-deleted before the cutoff along with
+// their reviews and posters, their revisions are kept, returns the amount of movies removed
+func PurgeDeletedMovies(dialect goqu.DialectWrapper, db *sql.DB, cutoff time.Time) (int64, error) {
+	tx, txErr := db.Begin()
+	if txErr != nil {
+		return 0, txErr
+	}
+	defer tx.Rollback()
+
+	expired := dialect.From("movies").Select("id").Where(
+		goqu.C("deleted_at").Lt(cutoff.Format(time.RFC3339)),
+	)
+
+	// Reviews reference the movies
+	reviewsDialect := dialect.Delete("movies_reviews").Where(goqu.C("movies_id").In(expired))
+	reviewsQuery, _, reviewsToSQLErr := reviewsDialect.ToSQL()
+	if reviewsToSQLErr != nil {
+		return 0, reviewsToSQLErr
+	}
+	if _, reviewsErr := tx.Exec(reviewsQuery); reviewsErr != nil {
+		return 0, reviewsErr
+	}
+
+	moviesDialect := dialect.Delete("movies").Where(goqu.C("id").In(expired)).Returning("poster_key", "thumbnail_key")
+	moviesQuery, _, moviesToSQLErr := moviesDialect.ToSQL()
+	if moviesToSQLErr != nil {
+		return 0, moviesToSQLErr
+	}
+	rows, moviesErr := tx.Query(moviesQuery)
+	if moviesErr != nil {
+		return 0, moviesErr
+	}
+	var purged int64
+	keys := []string{}
+	for rows.Next() {
+		var posterKey, thumbnailKey string
+		if scanErr := rows.Scan(&posterKey, &thumbnailKey); scanErr != nil {
+			rows.Close()
+			return 0, scanErr
+		}
+		keys = append(keys, posterKey, thumbnailKey)
+		purged++
+	}
+	rows.Close()
+	if errRows := rows.Err(); errRows != nil {
+		return 0, errRows
+	}
+
+	if commitErr := tx.Commit(); commitErr != nil {
+		return 0, commitErr
+	}
+
+	// Uploaded posters are only removed once nothing refers to them
+	DeleteBlobs(keys...)
+	return purged, nil
+}
+
+// StartPurge - Periodically purge movies soft-deleted longer than the configured retention in
+// the background, a retention of 0 keeps them forever. Failed logins are purged once they left
+// the login window as they no longer count towards a lockout
+func StartPurge(dialect goqu.DialectWrapper, db *sql.DB) {
+	// Read configuration file
+	config := GetConfig(".")
+	retention := time.Duration(config.Purge.RetentionDays) * 24 * time.Hour
+	interval := time.Duration(config.Purge.IntervalMinutes) * time.Minute
+	loginWindow := config.Login.Window * time.Minute
+	if interval <= 0 {
+		interval = time.Hour
+	}
+
+	go func() {
+		for {
+			if retention > 0 {
+				purged, purgeErr := PurgeDeletedMovies(dialect, db, time.Now().Add(-retention))
+				if purgeErr != nil {
+					log.Println("purging deleted movies failed:", purgeErr)
+				} else if purged > 0 {
+					log.Println("purged deleted movies:", purged)
+				}
+			}
+
+			if loginWindow > 0 {
+				if _, purgeErr := PurgeLoginFailures(dialect, db, time.Now().Add(-loginWindow)); purgeErr != nil {
+					log.Println("purging failed logins failed:", purgeErr)
+				}
+			}
+
+			time.Sleep(interval)
+		}
+	}()
+}
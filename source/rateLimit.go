@@ -0,0 +1,188 @@
+package source
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"math"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/graphql-go/graphql/language/ast"
+	"github.com/graphql-go/graphql/language/parser"
+	"github.com/graphql-go/handler"
+
+	config "github.com/HencoSmith/graphql-example-go/config/struct"
+)
+
+// tokenBucket - remaining tokens of a single key and when they were last refilled
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// RateLimiter - Token bucket rate limiter keeping a separate bucket per key
+type RateLimiter struct {
+	rate      float64
+	burst     float64
+	mutex     sync.Mutex
+	buckets   map[string]*tokenBucket
+	lastPrune time.Time
+}
+
+// NewRateLimiter - Create a rate limiter refilling rate tokens per second up to burst tokens,
+// a rate of zero disables the limiter
+func NewRateLimiter(bucket config.BucketConfiguration) *RateLimiter {
+	return &RateLimiter{
+		rate:      bucket.Rate,
+		burst:     float64(bucket.Burst),
+		buckets:   map[string]*tokenBucket{},
+		lastPrune: time.Now(),
+	}
+}
+
+// Allow - Take a token from the bucket of the key, returns true if one was available or false
+// along with how long to wait until the next token is available
+func (limiter *RateLimiter) Allow(key string) (bool, time.Duration) {
+	return limiter.allow(key, time.Now())
+}
+
+// allow - Allow at the specified time
+func (limiter *RateLimiter) allow(key string, now time.Time) (bool, time.Duration) {
+	if limiter.rate <= 0 {
+		return true, 0
+	}
+
+	limiter.mutex.Lock()
+	defer limiter.mutex.Unlock()
+
+	limiter.prune(now)
+
+	bucket, ok := limiter.buckets[key]
+	if !ok {
+		bucket = &tokenBucket{tokens: limiter.burst, last: now}
+		limiter.buckets[key] = bucket
+	}
+
+	// Refill the tokens accumulated since the last request
+	elapsed := now.Sub(bucket.last).Seconds()
+	bucket.tokens = math.Min(limiter.burst, bucket.tokens+elapsed*limiter.rate)
+	bucket.last = now
+
+	if bucket.tokens >= 1 {
+		bucket.tokens--
+		return true, 0
+	}
+
+	wait := (1 - bucket.tokens) / limiter.rate
+	return false, time.Duration(wait * float64(time.Second))
+}
+
+// prune - Forget buckets which have refilled completely, at most once a minute
+func (limiter *RateLimiter) prune(now time.Time) {
+	if now.Sub(limiter.lastPrune) < time.Minute {
+		return
+	}
+	limiter.lastPrune = now
+
+	for key, bucket := range limiter.buckets {
+		if bucket.tokens+now.Sub(bucket.last).Seconds()*limiter.rate >= limiter.burst {
+			delete(limiter.buckets, key)
+		}
+	}
+}
+
+// ClientIP - Lookup the IP address of the client, the X-Forwarded-For header is only used
+// when the server is configured to run behind a trusted proxy
+func ClientIP(req *http.Request, trustProxy bool) string {
+	if trustProxy {
+		forwarded := req.Header.Get("X-Forwarded-For")
+		if len(forwarded) > 0 {
+			return strings.TrimSpace(strings.Split(forwarded, ",")[0])
+		}
+	}
+
+	host, _, err := net.SplitHostPort(req.RemoteAddr)
+	if err != nil {
+		return req.RemoteAddr
+	}
+	return host
+}
+
+// ErrBodyTooLarge - returned when the request body exceeds the size the endpoint accepts
+var ErrBodyTooLarge = errors.New("Request body too large")
+
+// RequestFields - Lookup the root fields selected by the GraphQL operation in the request,
+// the request body is restored so it can be read again by the GraphQL handler. The body is read
+// in full, callers limit its size using http.MaxBytesReader
+func RequestFields(req *http.Request) ([]string, error) {
+	var body []byte
+	if req.Body != nil {
+		var readErr error
+		body, readErr = ioutil.ReadAll(req.Body)
+		if readErr != nil {
+			return nil, readErr
+		}
+		req.Body = ioutil.NopCloser(bytes.NewReader(body))
+	}
+	// Files of multipart requests are skipped, only the operation is read
+	var options *handler.RequestOptions
+	if IsMultipart(req) {
+		options = multipartOperations(req, body)
+	} else {
+		options = handler.NewRequestOptions(req)
+	}
+	req.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+	document, err := parser.Parse(parser.ParseParams{Source: options.Query})
+	if err != nil {
+		return []string{}, nil
+	}
+
+	fragments := map[string]*ast.FragmentDefinition{}
+	for _, definition := range document.Definitions {
+		if fragment, ok := definition.(*ast.FragmentDefinition); ok {
+			fragments[fragment.Name.Value] = fragment
+		}
+	}
+
+	fields := []string{}
+	for _, definition := range document.Definitions {
+		operation, ok := definition.(*ast.OperationDefinition)
+		if !ok {
+			continue
+		}
+		if len(options.OperationName) > 0 && (operation.Name == nil || operation.Name.Value != options.OperationName) {
+			continue
+		}
+		fields = append(fields, selectionFields(operation.SelectionSet, fragments, map[string]bool{})...)
+	}
+	return fields, nil
+}
+
+// selectionFields - Collect the field names of a selection set, following fragments
+func selectionFields(selectionSet *ast.SelectionSet, fragments map[string]*ast.FragmentDefinition, visited map[string]bool) []string {
+	fields := []string{}
+	if selectionSet == nil {
+		return fields
+	}
+
+	for _, selection := range selectionSet.Selections {
+		switch selection := selection.(type) {
+		case *ast.Field:
+			fields = append(fields, selection.Name.Value)
+		case *ast.InlineFragment:
+			fields = append(fields, selectionFields(selection.SelectionSet, fragments, visited)...)
+		case *ast.FragmentSpread:
+			name := selection.Name.Value
+			if fragment, ok := fragments[name]; ok && !visited[name] {
+				visited[name] = true
+				fields = append(fields, selectionFields(fragment.SelectionSet, fragments, visited)...)
+			}
+		}
+	}
+	return fields
+}
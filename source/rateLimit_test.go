@@ -0,0 +1,79 @@
+package source
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	config "github.com/HencoSmith/graphql-example-go/config/struct"
+)
+
+func TestRateLimiterAllow(t *testing.T) {
+	limiter := NewRateLimiter(config.BucketConfiguration{Rate: 2, Burst: 3})
+	start := time.Now()
+
+	// The burst is available at once
+	for i := 0; i < 3; i++ {
+		allowed, wait := limiter.allow("client", start)
+		assert.True(t, allowed, "Request %d of the burst", i+1)
+		assert.Equal(t, time.Duration(0), wait)
+	}
+
+	allowed, wait := limiter.allow("client", start)
+	assert.False(t, allowed, "The bucket should be empty")
+	assert.Equal(t, 500*time.Millisecond, wait, "One token refills every 1/rate seconds")
+
+	// Other keys have their own bucket
+	allowed, _ = limiter.allow("other", start)
+	assert.True(t, allowed)
+
+	// Tokens refill over time, never beyond the burst
+	allowed, _ = limiter.allow("client", start.Add(500*time.Millisecond))
+	assert.True(t, allowed)
+	allowed, wait = limiter.allow("client", start.Add(600*time.Millisecond))
+	assert.False(t, allowed)
+	assert.Equal(t, 400*time.Millisecond, wait)
+
+	later := start.Add(time.Hour)
+	for i := 0; i < 3; i++ {
+		allowed, _ = limiter.allow("client", later)
+		assert.True(t, allowed)
+	}
+	allowed, _ = limiter.allow("client", later)
+	assert.False(t, allowed, "Refills should be capped at the burst")
+}
+
+func TestRateLimiterDisabled(t *testing.T) {
+	limiter := NewRateLimiter(config.BucketConfiguration{Rate: 0, Burst: 0})
+	for i := 0; i < 100; i++ {
+		allowed, wait := limiter.Allow("client")
+		assert.True(t, allowed)
+		assert.Equal(t, time.Duration(0), wait)
+	}
+	assert.Empty(t, limiter.buckets, "Disabled limiters should not track clients")
+}
+
+func TestRateLimiterPrune(t *testing.T) {
+	limiter := NewRateLimiter(config.BucketConfiguration{Rate: 0.1, Burst: 10})
+	start := limiter.lastPrune
+
+	limiter.allow("idle", start)
+	for i := 0; i < 10; i++ {
+		limiter.allow("busy", start.Add(50*time.Second))
+	}
+	assert.Len(t, limiter.buckets, 2)
+
+	// Pruning runs at most once a minute
+	limiter.prune(start.Add(30 * time.Second))
+	assert.Len(t, limiter.buckets, 2)
+
+	// Only buckets which have refilled completely are forgotten
+	limiter.prune(start.Add(time.Minute))
+	assert.Equal(t, start.Add(time.Minute), limiter.lastPrune)
+	assert.Contains(t, limiter.buckets, "busy", "The bucket of busy has not refilled yet")
+	assert.NotContains(t, limiter.buckets, "idle")
+
+	limiter.prune(start.Add(3 * time.Minute))
+	assert.Empty(t, limiter.buckets)
+}
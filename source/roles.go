@@ -0,0 +1,32 @@
+package source
+
+import (
+	"errors"
+
+	"github.com/HencoSmith/graphql-example-go/models"
+)
+
+// Roles of users, assigned in the role column of the users table
+const (
+	// RoleUser - default role, manages their own content
+	RoleUser = "user"
+	// RoleAdmin - manages the content of every user
+	RoleAdmin = "admin"
+)
+
+// ErrForbidden - returned when the user is neither the owner of the content nor an admin
+var ErrForbidden = errors.New("Only the owner or an admin may perform this operation")
+
+// IsAdmin - Determine whether the user holds the admin role
+func IsAdmin(user models.User) bool {
+	return user.Role == RoleAdmin
+}
+
+// RequireOwnerOrAdmin - Check the user owns the content (identified by the users ID of the
+// content) or is an admin, returns ErrForbidden otherwise
+func RequireOwnerOrAdmin(user models.User, ownerID string) error {
+	if user.ID == ownerID || IsAdmin(user) {
+		return nil
+	}
+	return ErrForbidden
+}
@@ -0,0 +1,73 @@
+package source
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"os"
+)
+
+// ErrDecryptSecret - returned when a stored secret was not encrypted using the configured key
+var ErrDecryptSecret = errors.New("Unable to decrypt the stored secret")
+
+// secretCipher - Create the AES-256-GCM cipher of the configured encryption key, the environment
+// overrides the configuration
+func secretCipher() (cipher.AEAD, error) {
+	// Read configuration file
+	config := GetConfig(".")
+
+	secret := config.Encryption.Key
+	envKey := os.Getenv("ENCRYPTION_KEY")
+	if len(envKey) != 0 {
+		secret = envKey
+	}
+	if len(secret) == 0 {
+		return nil, errors.New("No encryption key configured")
+	}
+
+	key := sha256.Sum256([]byte(secret))
+	block, blockErr := aes.NewCipher(key[:])
+	if blockErr != nil {
+		return nil, blockErr
+	}
+	return cipher.NewGCM(block)
+}
+
+// EncryptSecret - Encrypt a secret before storing it in the database, returns the random nonce
+// followed by the ciphertext encoded as base64
+func EncryptSecret(secret string) (string, error) {
+	gcm, gcmErr := secretCipher()
+	if gcmErr != nil {
+		return "", gcmErr
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+
+	sealed := gcm.Seal(nonce, nonce, []byte(secret), nil)
+	return base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// DecryptSecret - Decrypt a secret stored using EncryptSecret
+func DecryptSecret(encrypted string) (string, error) {
+	gcm, gcmErr := secretCipher()
+	if gcmErr != nil {
+		return "", gcmErr
+	}
+
+	sealed, decodeErr := base64.StdEncoding.DecodeString(encrypted)
+	if decodeErr != nil || len(sealed) < gcm.NonceSize() {
+		return "", ErrDecryptSecret
+	}
+
+	secret, openErr := gcm.Open(nil, sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():], nil)
+	if openErr != nil {
+		return "", ErrDecryptSecret
+	}
+	return string(secret), nil
+}
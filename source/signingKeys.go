@@ -0,0 +1,386 @@
+package source
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"database/sql"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/doug-martin/goqu/v8"
+	uuid "github.com/satori/go.uuid"
+)
+
+// signingKey - A private key used to sign JWTs, identified by the kid header
+type signingKey struct {
+	ID        string
+	Algorithm string
+	CreatedAt time.Time
+	Private   crypto.Signer
+}
+
+// keyStore - Signing keys shared by every server through the jwt_keys table
+type keyStore struct {
+	dialect   goqu.DialectWrapper
+	db        *sql.DB
+	algorithm string
+	rotation  time.Duration
+	retention time.Duration
+	mutex     sync.RWMutex
+	// newest first
+	keys        []signingKey
+	refreshedAt time.Time
+}
+
+// signingKeys - asymmetric signing keys, nil when tokens are signed with the shared HS256 key
+var signingKeys *keyStore
+
+// InitSigningKeys - Load the asymmetric signing keys, generating the first one if required, and
+// rotate them in the background. Does nothing if the configured algorithm is HS256
+func InitSigningKeys(dialect goqu.DialectWrapper, db *sql.DB) error {
+	// Read configuration file
+	config := GetConfig(".")
+
+	algorithm := config.JWT.Algorithm
+	if len(algorithm) == 0 || algorithm == jwt.SigningMethodHS256.Alg() {
+		return nil
+	}
+	if algorithm != jwt.SigningMethodRS256.Alg() && algorithm != jwt.SigningMethodES256.Alg() {
+		return fmt.Errorf("Unsupported JWT algorithm: %v", algorithm)
+	}
+
+	store := &keyStore{
+		dialect:   dialect,
+		db:        db,
+		algorithm: algorithm,
+		rotation:  config.JWT.Rotation * time.Hour,
+		// Tokens signed just before a rotation stay valid until they expire
+		retention: config.JWT.Expiration * time.Hour,
+	}
+	if rotateErr := store.rotate(); rotateErr != nil {
+		return rotateErr
+	}
+	signingKeys = store
+
+	go func() {
+		ticker := time.NewTicker(keyRotationInterval)
+		defer ticker.Stop()
+		for range ticker.C {
+			if rotateErr := store.rotate(); rotateErr != nil {
+				log.Println("JWT key rotation failed:", rotateErr)
+			}
+		}
+	}()
+
+	return nil
+}
+
+// generateSigningKey - Create a new private key for the algorithm
+func generateSigningKey(algorithm string) (crypto.Signer, error) {
+	if algorithm == jwt.SigningMethodES256.Alg() {
+		return ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	}
+	return rsa.GenerateKey(rand.Reader, 2048)
+}
+
+// keyRotationLock - advisory lock serializing rotations, so servers rotating at the same time
+// cannot both add a key
+const keyRotationLock = 724614
+
+// keyRefreshInterval - least amount of time between reloads caused by tokens of unknown keys
+const keyRefreshInterval = time.Minute
+
+// keyRotationInterval - how often every server rotates and reloads the keys
+const keyRotationInterval = time.Hour
+
+// JWKSMaxAge - how long other services may cache /.well-known/jwks.json
+const JWKSMaxAge = 5 * time.Minute
+
+// keyActivationDelay - how long a new key is published before it signs tokens, by then every
+// server reloaded the keys and caches of the JWKS expired so the tokens verify everywhere
+const keyActivationDelay = keyRotationInterval + JWKSMaxAge
+
+// rotate - Add a new key if the newest one is due for rotation and remove keys which can no
+// longer have signed unexpired tokens
+func (store *keyStore) rotate() error {
+	tx, txErr := store.db.Begin()
+	if txErr != nil {
+		return txErr
+	}
+	defer tx.Rollback()
+
+	// Keys added by another server are only seen once its rotation committed
+	if _, lockErr := tx.Exec("SELECT pg_advisory_xact_lock(" + strconv.Itoa(keyRotationLock) + ")"); lockErr != nil {
+		return lockErr
+	}
+
+	keys, loadErr := store.load(tx)
+	if loadErr != nil {
+		return loadErr
+	}
+
+	due := len(keys) == 0 || keys[0].Algorithm != store.algorithm ||
+		(store.rotation > 0 && time.Since(keys[0].CreatedAt) > store.rotation)
+
+	if due {
+		private, generateErr := generateSigningKey(store.algorithm)
+		if generateErr != nil {
+			return generateErr
+		}
+		encoded, marshalErr := x509.MarshalPKCS8PrivateKey(private)
+		if marshalErr != nil {
+			return marshalErr
+		}
+		encrypted, encryptErr := EncryptSecret(string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: encoded})))
+		if encryptErr != nil {
+			return encryptErr
+		}
+
+		insertDialect := store.dialect.Insert("jwt_keys").Rows(
+			goqu.Record{
+				"id":          uuid.NewV4().String(),
+				"algorithm":   store.algorithm,
+				"private_key": encrypted,
+			},
+		)
+		insertQuery, _, toSQLErr := insertDialect.ToSQL()
+		if toSQLErr != nil {
+			return toSQLErr
+		}
+		if _, insertErr := tx.Exec(insertQuery); insertErr != nil {
+			return insertErr
+		}
+	}
+
+	// Keys stop signing once their successor is active, they are no longer needed when the
+	// tokens signed until then expired
+	cutoff := time.Now().Add(-(keyActivationDelay + store.retention)).Format(time.RFC3339)
+	successor := store.dialect.From(goqu.T("jwt_keys").As("successor")).Select(goqu.L("1")).Where(
+		goqu.I("successor.created_at").Gt(goqu.I("jwt_keys.created_at")),
+		goqu.I("successor.created_at").Lt(cutoff),
+	)
+	deleteDialect := store.dialect.Delete("jwt_keys").Where(goqu.L("EXISTS ?", successor))
+	deleteQuery, _, deleteToSQLErr := deleteDialect.ToSQL()
+	if deleteToSQLErr != nil {
+		return deleteToSQLErr
+	}
+	if _, deleteErr := tx.Exec(deleteQuery); deleteErr != nil {
+		return deleteErr
+	}
+
+	if commitErr := tx.Commit(); commitErr != nil {
+		return commitErr
+	}
+
+	return store.refresh()
+}
+
+// refresh - Reload the keys from the database, picking up keys rotated by other servers
+func (store *keyStore) refresh() error {
+	keys, loadErr := store.load(store.db)
+	if loadErr != nil {
+		return loadErr
+	}
+
+	store.mutex.Lock()
+	store.keys = keys
+	store.refreshedAt = time.Now()
+	store.mutex.Unlock()
+
+	return nil
+}
+
+// load - Read and decrypt the keys stored in the database, newest first. Keys which cannot be
+// decrypted using the configured encryption key are skipped
+func (store *keyStore) load(db queryer) ([]signingKey, error) {
+	dialectString := store.dialect.From("jwt_keys").Select(
+		"id",
+		"algorithm",
+		"created_at",
+		"private_key",
+	).Order(goqu.C("created_at").Desc())
+	query, _, dialectErr := dialectString.ToSQL()
+	if dialectErr != nil {
+		return nil, dialectErr
+	}
+
+	rows, queryErr := db.Query(query)
+	if queryErr != nil {
+		return nil, queryErr
+	}
+	defer rows.Close()
+
+	keys := []signingKey{}
+	for rows.Next() {
+		var key signingKey
+		var encrypted string
+		scanErr := rows.Scan(
+			&key.ID,
+			&key.Algorithm,
+			&key.CreatedAt,
+			&encrypted,
+		)
+		if scanErr != nil {
+			return nil, scanErr
+		}
+
+		encoded, decryptErr := DecryptSecret(encrypted)
+		if decryptErr != nil {
+			log.Println("JWT key "+key.ID+" skipped:", decryptErr)
+			continue
+		}
+		block, _ := pem.Decode([]byte(encoded))
+		if block == nil {
+			return nil, errors.New("Invalid JWT key " + key.ID)
+		}
+		private, parseErr := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if parseErr != nil {
+			return nil, parseErr
+		}
+		signer, ok := private.(crypto.Signer)
+		if !ok {
+			return nil, errors.New("Invalid JWT key " + key.ID)
+		}
+		key.Private = signer
+		keys = append(keys, key)
+	}
+	if errRows := rows.Err(); errRows != nil {
+		return nil, errRows
+	}
+
+	return keys, nil
+}
+
+// current - The key used to sign new tokens
+func (store *keyStore) current() (signingKey, error) {
+	store.mutex.RLock()
+	defer store.mutex.RUnlock()
+
+	return activeKey(store.keys, store.algorithm, time.Now())
+}
+
+// activeKey - The newest key of the algorithm published for at least keyActivationDelay. Until
+// one is, the oldest key of the algorithm signs as there is no other, e.g. after the first start
+// keys - Keys newest first
+// algorithm - Configured signing algorithm
+// now - Time of signing
+func activeKey(keys []signingKey, algorithm string, now time.Time) (signingKey, error) {
+	var oldest *signingKey
+	for i, key := range keys {
+		if key.Algorithm != algorithm {
+			continue
+		}
+		if now.Sub(key.CreatedAt) >= keyActivationDelay {
+			return key, nil
+		}
+		oldest = &keys[i]
+	}
+	if oldest != nil {
+		return *oldest, nil
+	}
+	return signingKey{}, errors.New("No JWT signing key available")
+}
+
+// lookup - Find the key with the kid, reloading in case another server rotated. Unknown kids only
+// cause a reload once per keyRefreshInterval so forged tokens cannot hammer the database
+func (store *keyStore) lookup(id string) (signingKey, bool) {
+	find := func() (signingKey, bool) {
+		store.mutex.RLock()
+		defer store.mutex.RUnlock()
+		for _, key := range store.keys {
+			if key.ID == id {
+				return key, true
+			}
+		}
+		return signingKey{}, false
+	}
+
+	if key, ok := find(); ok {
+		return key, true
+	}
+
+	// Claim the reload so concurrent lookups of unknown kids do not reload as well
+	store.mutex.Lock()
+	stale := time.Since(store.refreshedAt) >= keyRefreshInterval
+	if stale {
+		store.refreshedAt = time.Now()
+	}
+	store.mutex.Unlock()
+	if !stale {
+		return signingKey{}, false
+	}
+
+	if refreshErr := store.refresh(); refreshErr != nil {
+		log.Println("JWT key refresh failed:", refreshErr)
+		return signingKey{}, false
+	}
+	return find()
+}
+
+// signingMethod - Lookup the jwt-go method of the algorithm
+func signingMethod(algorithm string) jwt.SigningMethod {
+	if algorithm == jwt.SigningMethodES256.Alg() {
+		return jwt.SigningMethodES256
+	}
+	return jwt.SigningMethodRS256
+}
+
+// encodeBigInt - Encode the number as unpadded base64url as required by JWK
+func encodeBigInt(number *big.Int, size int) string {
+	buff := number.Bytes()
+	if len(buff) < size {
+		buff = append(make([]byte, size-len(buff)), buff...)
+	}
+	return base64.RawURLEncoding.EncodeToString(buff)
+}
+
+// JWKS - The public keys of every active signing key as a JSON Web Key Set, other services use
+// it to verify tokens without being able to create them
+func JWKS() map[string]interface{} {
+	keys := []map[string]interface{}{}
+	if signingKeys == nil {
+		return map[string]interface{}{"keys": keys}
+	}
+
+	signingKeys.mutex.RLock()
+	defer signingKeys.mutex.RUnlock()
+
+	for _, key := range signingKeys.keys {
+		switch public := key.Private.Public().(type) {
+		case *rsa.PublicKey:
+			keys = append(keys, map[string]interface{}{
+				"kty": "RSA",
+				"use": "sig",
+				"alg": key.Algorithm,
+				"kid": key.ID,
+				"n":   encodeBigInt(public.N, 0),
+				"e":   encodeBigInt(big.NewInt(int64(public.E)), 0),
+			})
+		case *ecdsa.PublicKey:
+			size := (public.Curve.Params().BitSize + 7) / 8
+			keys = append(keys, map[string]interface{}{
+				"kty": "EC",
+				"use": "sig",
+				"alg": key.Algorithm,
+				"kid": key.ID,
+				"crv": public.Curve.Params().Name,
+				"x":   encodeBigInt(public.X, size),
+				"y":   encodeBigInt(public.Y, size),
+			})
+		}
+	}
+
+	return map[string]interface{}{"keys": keys}
+}
@@ -0,0 +1,37 @@
+package source
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestActiveKey(t *testing.T) {
+	now := time.Date(2019, 8, 9, 12, 0, 0, 0, time.UTC)
+	fresh := signingKey{ID: "fresh", Algorithm: "RS256", CreatedAt: now.Add(-time.Minute)}
+	published := signingKey{ID: "published", Algorithm: "RS256", CreatedAt: now.Add(-keyActivationDelay)}
+	old := signingKey{ID: "old", Algorithm: "RS256", CreatedAt: now.Add(-30 * 24 * time.Hour)}
+	other := signingKey{ID: "other", Algorithm: "ES256", CreatedAt: now.Add(-time.Hour)}
+
+	// Keys only sign once they were published long enough for every verifier to know them
+	key, err := activeKey([]signingKey{fresh, published, old}, "RS256", now)
+	assert.NoError(t, err)
+	assert.Equal(t, "published", key.ID)
+
+	key, err = activeKey([]signingKey{fresh, old}, "RS256", now)
+	assert.NoError(t, err)
+	assert.Equal(t, "old", key.ID, "The previous key keeps signing until its successor is active")
+
+	// Without an older key there is nothing else to sign with
+	key, err = activeKey([]signingKey{fresh}, "RS256", now)
+	assert.NoError(t, err)
+	assert.Equal(t, "fresh", key.ID)
+
+	key, err = activeKey([]signingKey{other, fresh}, "RS256", now)
+	assert.NoError(t, err)
+	assert.Equal(t, "fresh", key.ID, "Keys of other algorithms are never used")
+
+	_, err = activeKey([]signingKey{other}, "RS256", now)
+	assert.Error(t, err)
+}
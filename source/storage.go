@@ -0,0 +1,165 @@
+package source
+
+import (
+	"errors"
+	"io"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	configStruct "github.com/HencoSmith/graphql-example-go/config/struct"
+)
+
+// ErrInvalidBlobKey - returned when a key would address a file outside of the storage
+var ErrInvalidBlobKey = errors.New("Invalid blob key")
+
+// BlobStorage - stores uploaded files under keys such as posters/<movie>/<name>.jpg
+type BlobStorage interface {
+	Put(key string, contentType string, data io.Reader) error
+	Delete(key string) error
+	URL(key string) string
+}
+
+// LocalStorage - BlobStorage keeping files in a directory of the local filesystem, it serves
+// them over HTTP itself
+type LocalStorage struct {
+	Path    string
+	BaseURL string
+}
+
+// file - Path of the file stored under the key, refusing keys escaping the directory
+func (storage LocalStorage) file(key string) (string, error) {
+	cleaned := path.Clean("/" + key)
+	if cleaned == "/" || cleaned != "/"+key {
+		return "", ErrInvalidBlobKey
+	}
+	return filepath.Join(storage.Path, filepath.FromSlash(cleaned)), nil
+}
+
+// Put - Write the data to the file of the key, replacing an existing file
+func (storage LocalStorage) Put(key string, contentType string, data io.Reader) error {
+	name, keyErr := storage.file(key)
+	if keyErr != nil {
+		return keyErr
+	}
+	if mkdirErr := os.MkdirAll(filepath.Dir(name), 0755); mkdirErr != nil {
+		return mkdirErr
+	}
+
+	// Write to a temporary file first so readers never see a partial file
+	temp, createErr := ioutil.TempFile(filepath.Dir(name), ".upload-*")
+	if createErr != nil {
+		return createErr
+	}
+	defer os.Remove(temp.Name())
+
+	if _, copyErr := io.Copy(temp, data); copyErr != nil {
+		temp.Close()
+		return copyErr
+	}
+	if closeErr := temp.Close(); closeErr != nil {
+		return closeErr
+	}
+	if chmodErr := os.Chmod(temp.Name(), 0644); chmodErr != nil {
+		return chmodErr
+	}
+
+	return os.Rename(temp.Name(), name)
+}
+
+// Delete - Remove the file of the key, missing files are ignored
+func (storage LocalStorage) Delete(key string) error {
+	name, keyErr := storage.file(key)
+	if keyErr != nil {
+		return keyErr
+	}
+	if removeErr := os.Remove(name); removeErr != nil && !os.IsNotExist(removeErr) {
+		return removeErr
+	}
+	return nil
+}
+
+// URL - Link the file of the key is served at
+func (storage LocalStorage) URL(key string) string {
+	return strings.TrimSuffix(storage.BaseURL, "/") + "/" + key
+}
+
+// ServeHTTP - Serve the stored files, the request path being the key. Directories are not listed
+func (storage LocalStorage) ServeHTTP(res http.ResponseWriter, req *http.Request) {
+	name, keyErr := storage.file(strings.TrimPrefix(req.URL.Path, "/"))
+	if keyErr != nil || strings.HasPrefix(path.Base(req.URL.Path), ".") {
+		http.NotFound(res, req)
+		return
+	}
+	info, statErr := os.Stat(name)
+	if statErr != nil || info.IsDir() {
+		http.NotFound(res, req)
+		return
+	}
+
+	// Only validated images are stored, never let browsers guess another type
+	res.Header().Set("X-Content-Type-Options", "nosniff")
+	res.Header().Set("Cache-Control", "public, max-age=86400")
+	http.ServeFile(res, req, name)
+}
+
+// blobStorage - storage of uploaded files, nil until InitBlobStorage is called
+var blobStorage BlobStorage
+
+// InitBlobStorage - Create the configured storage used for uploaded files from now on
+func InitBlobStorage(config configStruct.StorageConfiguration) (BlobStorage, error) {
+	storage, err := NewBlobStorage(config)
+	if err != nil {
+		return nil, err
+	}
+	blobStorage = storage
+	return storage, nil
+}
+
+// GetBlobStorage - Storage of uploaded files, nil when it is not initialized
+func GetBlobStorage() BlobStorage {
+	return blobStorage
+}
+
+// BlobURL - Link a stored file is served at, nil when there is no file
+func BlobURL(key string) interface{} {
+	if len(key) == 0 || blobStorage == nil {
+		return nil
+	}
+	return blobStorage.URL(key)
+}
+
+// DeleteBlobs - Remove the files of the keys, failures are only logged as the files are no
+// longer referenced
+func DeleteBlobs(keys ...string) {
+	if blobStorage == nil {
+		return
+	}
+	for _, key := range keys {
+		if len(key) == 0 {
+			continue
+		}
+		if deleteErr := blobStorage.Delete(key); deleteErr != nil {
+			log.Println("deleting file failed:", key, deleteErr)
+		}
+	}
+}
+
+// NewBlobStorage - Create the storage selected by the configured driver, only "local" is
+// available
+func NewBlobStorage(config configStruct.StorageConfiguration) (BlobStorage, error) {
+	switch config.Driver {
+	case "local", "":
+		storagePath := config.Path
+		if len(storagePath) == 0 {
+			storagePath = "uploads"
+		}
+		return LocalStorage{Path: storagePath, BaseURL: config.URL}, nil
+	default:
+		return nil, errors.New("Unknown storage driver: " + config.Driver)
+	}
+}
@@ -0,0 +1,92 @@
+package source
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// totpPeriod - seconds each TOTP code is valid for
+const totpPeriod = 30
+
+// totpDigits - length of each TOTP code
+const totpDigits = 6
+
+// totpSkew - amount of periods before and after the current one which are accepted to allow for clock drift
+const totpSkew = 1
+
+// GenerateTOTPSecret - Generate a random 160 bit secret encoded as unpadded base32
+func GenerateTOTPSecret() (string, error) {
+	buff := make([]byte, 20)
+	if _, err := rand.Read(buff); err != nil {
+		return "", err
+	}
+	return base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(buff), nil
+}
+
+// TOTPURI - Build the otpauth URI authenticator apps use to enroll the secret, usually shown as a QR code
+func TOTPURI(issuer string, account string, secret string) string {
+	values := url.Values{}
+	values.Set("secret", secret)
+	values.Set("issuer", issuer)
+	values.Set("algorithm", "SHA1")
+	values.Set("digits", fmt.Sprint(totpDigits))
+	values.Set("period", fmt.Sprint(totpPeriod))
+
+	label := url.PathEscape(issuer) + ":" + url.PathEscape(account)
+	return "otpauth://totp/" + label + "?" + values.Encode()
+}
+
+// totpCode - Calculate the RFC 6238 code of the secret for the time step
+func totpCode(secret []byte, step int64) string {
+	message := make([]byte, 8)
+	binary.BigEndian.PutUint64(message, uint64(step))
+
+	mac := hmac.New(sha1.New, secret)
+	mac.Write(message)
+	sum := mac.Sum(nil)
+
+	// Dynamic truncation
+	offset := sum[len(sum)-1] & 0x0f
+	code := (binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff) % 1000000
+
+	return fmt.Sprintf("%06d", code)
+}
+
+// ValidateTOTP - Check the code against the secret, codes of time steps up to and including lastStep
+// are rejected to prevent replays. Returns the time step of the code or false if it is invalid
+func ValidateTOTP(secret string, code string, lastStep int64) (int64, bool) {
+	return validateTOTP(secret, code, lastStep, time.Now())
+}
+
+// validateTOTP - ValidateTOTP at the specified time
+func validateTOTP(secret string, code string, lastStep int64, now time.Time) (int64, bool) {
+	key, err := base32.StdEncoding.WithPadding(base32.NoPadding).DecodeString(strings.ToUpper(secret))
+	if err != nil || len(key) == 0 {
+		return 0, false
+	}
+
+	code = strings.Replace(strings.TrimSpace(code), " ", "", -1)
+	if len(code) != totpDigits {
+		return 0, false
+	}
+
+	current := now.Unix() / totpPeriod
+	for step := current - totpSkew; step <= current+totpSkew; step++ {
+		if step <= lastStep {
+			continue
+		}
+		if subtle.ConstantTimeCompare([]byte(totpCode(key, step)), []byte(code)) == 1 {
+			return step, true
+		}
+	}
+
+	return 0, false
+}
@@ -0,0 +1,79 @@
+package source
+
+import (
+	"encoding/base32"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// rfcSecret - SHA1 secret of the RFC 6238 test vectors
+const rfcSecret = "12345678901234567890"
+
+func TestTOTPCode(t *testing.T) {
+	// RFC 6238 appendix B, truncated to 6 digits
+	for unix, code := range map[int64]string{
+		59:          "287082",
+		1111111109:  "081804",
+		1111111111:  "050471",
+		1234567890:  "005924",
+		2000000000:  "279037",
+		20000000000: "353130",
+	} {
+		assert.Equal(t, code, totpCode([]byte(rfcSecret), unix/totpPeriod), "Time %d", unix)
+	}
+}
+
+func TestValidateTOTP(t *testing.T) {
+	secret := base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString([]byte(rfcSecret))
+	now := time.Unix(1111111111, 0)
+	step := now.Unix() / totpPeriod
+
+	validated, valid := validateTOTP(secret, "050471", 0, now)
+	assert.True(t, valid)
+	assert.Equal(t, step, validated)
+
+	// Apps format codes and secrets differently
+	_, valid = validateTOTP(secret, " 050 471 ", 0, now)
+	assert.True(t, valid)
+	_, valid = validateTOTP(strings.ToLower(secret), "050471", 0, now)
+	assert.True(t, valid)
+
+	// Codes of the neighbouring steps are accepted for clock drift, older ones are not
+	_, valid = validateTOTP(secret, totpCode([]byte(rfcSecret), step-1), 0, now)
+	assert.True(t, valid, "Previous step")
+	_, valid = validateTOTP(secret, totpCode([]byte(rfcSecret), step+1), 0, now)
+	assert.True(t, valid, "Next step")
+	_, valid = validateTOTP(secret, totpCode([]byte(rfcSecret), step-2), 0, now)
+	assert.False(t, valid, "Expired step")
+
+	for _, code := range []string{"", "12345", "1234567", "abcdef", "000000"} {
+		_, valid = validateTOTP(secret, code, 0, now)
+		assert.False(t, valid, "Code %q", code)
+	}
+
+	_, valid = validateTOTP("not base32!", "050471", 0, now)
+	assert.False(t, valid, "Invalid secret")
+	_, valid = validateTOTP("", "050471", 0, now)
+	assert.False(t, valid, "Empty secret")
+}
+
+func TestValidateTOTPStepReuse(t *testing.T) {
+	secret := base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString([]byte(rfcSecret))
+	now := time.Unix(1111111111, 0)
+
+	step, valid := validateTOTP(secret, "050471", 0, now)
+	assert.True(t, valid)
+
+	// Once a step was used its code and those of earlier steps are replays
+	_, valid = validateTOTP(secret, "050471", step, now)
+	assert.False(t, valid, "Replayed code")
+	_, valid = validateTOTP(secret, totpCode([]byte(rfcSecret), step-1), step, now)
+	assert.False(t, valid, "Code of an earlier step")
+
+	next, valid := validateTOTP(secret, totpCode([]byte(rfcSecret), step+1), step, now)
+	assert.True(t, valid, "Code of a later step")
+	assert.Equal(t, step+1, next)
+}
@@ -0,0 +1,288 @@
+package source
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"encoding/base32"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/doug-martin/goqu/v8"
+	uuid "github.com/satori/go.uuid"
+
+	"github.com/HencoSmith/graphql-example-go/models"
+)
+
+// recoveryCodeCount - amount of recovery codes issued at once
+const recoveryCodeCount = 10
+
+// ErrInvalidSecondFactor - returned when a TOTP or recovery code is wrong, expired or already used
+var ErrInvalidSecondFactor = errors.New("Invalid two-factor authentication code")
+
+// ErrTwoFactorEnabled - returned when enrolling while two-factor authentication is already enabled
+var ErrTwoFactorEnabled = errors.New("Two-factor authentication is already enabled")
+
+// ErrTwoFactorDisabled - returned when an operation requires two-factor authentication to be enabled
+var ErrTwoFactorDisabled = errors.New("Two-factor authentication is not enabled")
+
+// execer - statement execution shared by *sql.DB and *sql.Tx
+type execer interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+}
+
+// queryer - queries shared by *sql.DB and *sql.Tx
+type queryer interface {
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+}
+
+// updateUserTOTP - Store the TOTP fields of the user
+func updateUserTOTP(dialect goqu.DialectWrapper, db execer, userID string, fields goqu.Record) error {
+	fields["updated_at"] = time.Now().Format(time.RFC3339)
+	return updateUser(dialect, db, userID, fields)
+}
+
+// normalizeRecoveryCode - Strip formatting users may add when typing a recovery code
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToLower(strings.TrimSpace(code))
+	return strings.Replace(strings.Replace(code, "-", "", -1), " ", "", -1)
+}
+
+// replaceRecoveryCodes - Remove the previous recovery codes of the user and store new ones,
+// returns the new codes which are only shown once
+func replaceRecoveryCodes(dialect goqu.DialectWrapper, tx *sql.Tx, userID string) ([]string, error) {
+	deleteDialect := dialect.Delete("users_recovery_codes").Where(goqu.Ex{
+		"users_id": userID,
+	})
+	deleteQuery, _, deleteToSQLErr := deleteDialect.ToSQL()
+	if deleteToSQLErr != nil {
+		return nil, deleteToSQLErr
+	}
+	if _, deleteErr := tx.Exec(deleteQuery); deleteErr != nil {
+		return nil, deleteErr
+	}
+
+	codes := []string{}
+	records := []interface{}{}
+	for i := 0; i < recoveryCodeCount; i++ {
+		buff := make([]byte, 5)
+		if _, err := rand.Read(buff); err != nil {
+			return nil, err
+		}
+		// 8 base32 characters formatted as xxxx-xxxx
+		code := strings.ToLower(base32.StdEncoding.EncodeToString(buff))
+		codes = append(codes, code[:4]+"-"+code[4:])
+		records = append(records, goqu.Record{
+			"id":        uuid.NewV4(),
+			"users_id":  userID,
+			"code_hash": HashToken(code),
+		})
+	}
+
+	insertDialect := dialect.Insert("users_recovery_codes").Rows(records...)
+	insertQuery, _, insertToSQLErr := insertDialect.ToSQL()
+	if insertToSQLErr != nil {
+		return nil, insertToSQLErr
+	}
+	if _, insertErr := tx.Exec(insertQuery); insertErr != nil {
+		return nil, insertErr
+	}
+
+	return codes, nil
+}
+
+// EnrollTOTP - Generate a new pending TOTP secret for the user, returns the secret and the
+// otpauth URI for authenticator apps. The secret is only used once confirmed with ConfirmTOTP
+func EnrollTOTP(dialect goqu.DialectWrapper, db *sql.DB, user models.User) (string, string, error) {
+	if user.TOTPEnabledAt != nil {
+		return "", "", ErrTwoFactorEnabled
+	}
+
+	// Read configuration file
+	config := GetConfig(".")
+
+	secret, secretErr := GenerateTOTPSecret()
+	if secretErr != nil {
+		return "", "", secretErr
+	}
+
+	encryptedSecret, encryptErr := EncryptSecret(secret)
+	if encryptErr != nil {
+		return "", "", encryptErr
+	}
+
+	if updateErr := updateUserTOTP(dialect, db, user.ID, goqu.Record{
+		"totp_secret":    encryptedSecret,
+		"totp_last_step": 0,
+	}); updateErr != nil {
+		return "", "", updateErr
+	}
+
+	return secret, TOTPURI(config.TOTP.Issuer, user.Email, secret), nil
+}
+
+// ConfirmTOTP - Enable two-factor authentication once the user proves their authenticator app
+// produces valid codes for the pending secret, returns newly issued recovery codes
+func ConfirmTOTP(dialect goqu.DialectWrapper, db *sql.DB, user models.User, code string) ([]string, error) {
+	if user.TOTPEnabledAt != nil {
+		return nil, ErrTwoFactorEnabled
+	}
+	if len(user.TOTPSecret) == 0 {
+		return nil, errors.New("Two-factor authentication enrollment has not been started")
+	}
+
+	secret, decryptErr := DecryptSecret(user.TOTPSecret)
+	if decryptErr != nil {
+		return nil, decryptErr
+	}
+
+	step, valid := ValidateTOTP(secret, code, user.TOTPLastStep)
+	if !valid {
+		return nil, ErrInvalidSecondFactor
+	}
+
+	tx, txErr := db.Begin()
+	if txErr != nil {
+		return nil, txErr
+	}
+	defer tx.Rollback()
+
+	if updateErr := updateUserTOTP(dialect, tx, user.ID, goqu.Record{
+		"totp_enabled_at": time.Now().Format(time.RFC3339),
+		"totp_last_step":  step,
+	}); updateErr != nil {
+		return nil, updateErr
+	}
+
+	codes, codesErr := replaceRecoveryCodes(dialect, tx, user.ID)
+	if codesErr != nil {
+		return nil, codesErr
+	}
+
+	return codes, tx.Commit()
+}
+
+// VerifySecondFactor - Check a TOTP or recovery code of a user with two-factor authentication
+// enabled, used codes are consumed so they cannot be replayed
+func VerifySecondFactor(dialect goqu.DialectWrapper, db *sql.DB, user models.User, code string) error {
+	if user.TOTPEnabledAt == nil {
+		return ErrTwoFactorDisabled
+	}
+
+	tx, txErr := db.Begin()
+	if txErr != nil {
+		return txErr
+	}
+	defer tx.Rollback()
+
+	// Secrets which can no longer be decrypted reject every TOTP code, recovery codes still work
+	secret, _ := DecryptSecret(user.TOTPSecret)
+	step, valid := ValidateTOTP(secret, code, user.TOTPLastStep)
+	if valid {
+		// Only succeeds if no other request used a code of this step in the meantime
+		updateDialect := dialect.Update("users").Set(
+			goqu.Record{
+				"totp_last_step": step,
+			},
+		).Where(
+			goqu.Ex{
+				"id": user.ID,
+			},
+			goqu.C("totp_last_step").Lt(step),
+		)
+		updateQuery, _, toSQLErr := updateDialect.ToSQL()
+		if toSQLErr != nil {
+			return toSQLErr
+		}
+
+		updateRes, updateErr := tx.Exec(updateQuery)
+		if updateErr != nil {
+			return updateErr
+		}
+		if updated, _ := updateRes.RowsAffected(); updated < 1 {
+			return ErrInvalidSecondFactor
+		}
+
+		return tx.Commit()
+	}
+
+	// Fall back to single-use recovery codes
+	consumeDialect := dialect.Update("users_recovery_codes").Set(
+		goqu.Record{
+			"used_at": time.Now().Format(time.RFC3339),
+		},
+	).Where(goqu.Ex{
+		"users_id":  user.ID,
+		"code_hash": HashToken(normalizeRecoveryCode(code)),
+		"used_at":   nil,
+	})
+	consumeQuery, _, consumeToSQLErr := consumeDialect.ToSQL()
+	if consumeToSQLErr != nil {
+		return consumeToSQLErr
+	}
+
+	consumeRes, consumeErr := tx.Exec(consumeQuery)
+	if consumeErr != nil {
+		return consumeErr
+	}
+	if consumed, _ := consumeRes.RowsAffected(); consumed < 1 {
+		return ErrInvalidSecondFactor
+	}
+
+	return tx.Commit()
+}
+
+// RegenerateRecoveryCodes - Replace the recovery codes of the user after verifying a second factor
+func RegenerateRecoveryCodes(dialect goqu.DialectWrapper, db *sql.DB, user models.User, code string) ([]string, error) {
+	if verifyErr := VerifySecondFactor(dialect, db, user, code); verifyErr != nil {
+		return nil, verifyErr
+	}
+
+	tx, txErr := db.Begin()
+	if txErr != nil {
+		return nil, txErr
+	}
+	defer tx.Rollback()
+
+	codes, codesErr := replaceRecoveryCodes(dialect, tx, user.ID)
+	if codesErr != nil {
+		return nil, codesErr
+	}
+
+	return codes, tx.Commit()
+}
+
+// DisableTOTP - Turn off two-factor authentication after verifying a second factor, the secret
+// and recovery codes are removed
+func DisableTOTP(dialect goqu.DialectWrapper, db *sql.DB, user models.User, code string) error {
+	if verifyErr := VerifySecondFactor(dialect, db, user, code); verifyErr != nil {
+		return verifyErr
+	}
+
+	tx, txErr := db.Begin()
+	if txErr != nil {
+		return txErr
+	}
+	defer tx.Rollback()
+
+	if updateErr := updateUserTOTP(dialect, tx, user.ID, goqu.Record{
+		"totp_secret":     "",
+		"totp_enabled_at": nil,
+		"totp_last_step":  0,
+	}); updateErr != nil {
+		return updateErr
+	}
+
+	deleteDialect := dialect.Delete("users_recovery_codes").Where(goqu.Ex{
+		"users_id": user.ID,
+	})
+	deleteQuery, _, deleteToSQLErr := deleteDialect.ToSQL()
+	if deleteToSQLErr != nil {
+		return deleteToSQLErr
+	}
+	if _, deleteErr := tx.Exec(deleteQuery); deleteErr != nil {
+		return deleteErr
+	}
+
+	return tx.Commit()
+}
@@ -0,0 +1,155 @@
+package source
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/graphql-go/handler"
+
+	"github.com/HencoSmith/graphql-example-go/models"
+)
+
+// multipartMemory - bytes of a multipart request kept in memory, larger files are buffered on disk
+const multipartMemory = 1 << 20
+
+// ErrInvalidMultipartRequest - returned when a multipart request does not follow the GraphQL
+// multipart request specification
+var ErrInvalidMultipartRequest = errors.New("Invalid GraphQL multipart request")
+
+// IsMultipart - Determine whether the request is a multipart/form-data request
+func IsMultipart(req *http.Request) bool {
+	mediaType, _, err := mime.ParseMediaType(req.Header.Get("Content-Type"))
+	return err == nil && mediaType == "multipart/form-data"
+}
+
+// setVariable - Replace the value at the object path e.g. variables.files.0 of the operation
+func setVariable(options *handler.RequestOptions, objectPath string, value interface{}) error {
+	segments := strings.Split(objectPath, ".")
+	if len(segments) < 2 || segments[0] != "variables" || options.Variables == nil {
+		return ErrInvalidMultipartRequest
+	}
+
+	var parent interface{} = options.Variables
+	for i, segment := range segments[1:] {
+		last := i == len(segments)-2
+		switch container := parent.(type) {
+		case map[string]interface{}:
+			if _, ok := container[segment]; !ok {
+				return ErrInvalidMultipartRequest
+			}
+			if last {
+				container[segment] = value
+			} else {
+				parent = container[segment]
+			}
+		case []interface{}:
+			index, indexErr := strconv.Atoi(segment)
+			if indexErr != nil || index < 0 || index >= len(container) {
+				return ErrInvalidMultipartRequest
+			}
+			if last {
+				container[index] = value
+			} else {
+				parent = container[index]
+			}
+		default:
+			return ErrInvalidMultipartRequest
+		}
+	}
+
+	return nil
+}
+
+// ParseMultipartRequest - Parse a request following the GraphQL multipart request specification,
+// the files are placed in the variables of the operation as models.Upload values. The caller
+// closes the files using the returned function, which is never nil, once the operation is done
+// and removes the files buffered on disk using req.MultipartForm.RemoveAll
+func ParseMultipartRequest(req *http.Request) (*handler.RequestOptions, func(), error) {
+	uploads := []*models.Upload{}
+	closeUploads := func() {
+		for _, upload := range uploads {
+			upload.File.Close()
+		}
+	}
+
+	if parseErr := req.ParseMultipartForm(multipartMemory); parseErr != nil {
+		return nil, closeUploads, parseErr
+	}
+
+	operations := req.MultipartForm.Value["operations"]
+	if len(operations) != 1 {
+		return nil, closeUploads, ErrInvalidMultipartRequest
+	}
+	if strings.HasPrefix(strings.TrimSpace(operations[0]), "[") {
+		return nil, closeUploads, errors.New("Batched operations are not supported")
+	}
+	var options handler.RequestOptions
+	if decodeErr := json.Unmarshal([]byte(operations[0]), &options); decodeErr != nil {
+		return nil, closeUploads, ErrInvalidMultipartRequest
+	}
+
+	fileMap := map[string][]string{}
+	if mapping := req.MultipartForm.Value["map"]; len(mapping) == 1 {
+		if decodeErr := json.Unmarshal([]byte(mapping[0]), &fileMap); decodeErr != nil {
+			return nil, closeUploads, ErrInvalidMultipartRequest
+		}
+	}
+
+	for key, objectPaths := range fileMap {
+		headers := req.MultipartForm.File[key]
+		if len(headers) != 1 {
+			return nil, closeUploads, ErrInvalidMultipartRequest
+		}
+
+		file, openErr := headers[0].Open()
+		if openErr != nil {
+			return nil, closeUploads, openErr
+		}
+		upload := &models.Upload{
+			Filename:    headers[0].Filename,
+			ContentType: headers[0].Header.Get("Content-Type"),
+			Size:        headers[0].Size,
+			File:        file,
+		}
+		uploads = append(uploads, upload)
+		for _, objectPath := range objectPaths {
+			if setErr := setVariable(&options, objectPath, upload); setErr != nil {
+				return nil, closeUploads, setErr
+			}
+		}
+	}
+
+	return &options, closeUploads, nil
+}
+
+// multipartOperations - Read the operations field of a multipart request without parsing the
+// files, the field precedes the files according to the specification
+func multipartOperations(req *http.Request, body []byte) *handler.RequestOptions {
+	_, params, err := mime.ParseMediaType(req.Header.Get("Content-Type"))
+	if err != nil {
+		return &handler.RequestOptions{}
+	}
+
+	reader := multipart.NewReader(bytes.NewReader(body), params["boundary"])
+	for {
+		part, partErr := reader.NextPart()
+		if partErr != nil {
+			return &handler.RequestOptions{}
+		}
+		if part.FormName() != "operations" {
+			continue
+		}
+
+		var options handler.RequestOptions
+		if decodeErr := json.NewDecoder(part).Decode(&options); decodeErr != nil {
+			return &handler.RequestOptions{}
+		}
+		return &options
+	}
+}
@@ -2,17 +2,28 @@ package moviestest
 
 import (
 	"bytes"
+	"encoding/base64"
+	"encoding/csv"
+	"image"
+	"image/png"
 	"io/ioutil"
+	"mime/multipart"
 	"net/http"
 	"net/url"
 	"reflect"
+	"strconv"
+	"strings"
 	"text/template"
+	"time"
 
 	"testing"
 
+	"github.com/doug-martin/goqu/v8"
+	_ "github.com/doug-martin/goqu/v8/dialect/postgres"
 	"github.com/stretchr/testify/assert"
 	"github.com/tidwall/gjson"
 
+	"github.com/HencoSmith/graphql-example-go/graphql/node"
 	source "github.com/HencoSmith/graphql-example-go/source"
 )
 
@@ -35,7 +46,7 @@ func getToken() (string, error) {
 
 	client := &http.Client{}
 
-	req, err := http.NewRequest("GET", "http://localhost:"+config.Server.Port+"/graphql?query={getToken%28email:%20%22test@mail.com%22,%20password:%20%22test%22%29}", nil)
+	req, err := http.NewRequest("GET", "http://localhost:"+config.Server.Port+"/graphql?query={getToken%28email:%20%22test@mail.com%22,%20password:%20%22test%22%29%7Btoken%7D}", nil)
 	if err != nil {
 		return "", err
 	}
@@ -55,7 +66,7 @@ func getToken() (string, error) {
 
 	bodyStr := string(body)
 
-	return gjson.Get(bodyStr, "data.getToken").String(), nil
+	return gjson.Get(bodyStr, "data.getToken.token").String(), nil
 }
 
 func CreateMovie(input TestMovie) (body []byte, err error) {
@@ -168,7 +179,7 @@ func UpdateMovie(input TestMovieUpdate) (body []byte, err error) {
 		return nil, errParse
 	}
 
-	query := `mutation{update(id:"{{.ID}}",name:"{{.Name}}",description:"{{.Description}}",releaseYear:{{.ReleaseYear}}){id,name,description,release_year}}`
+	query := `mutation{update(id:"{{.ID}}",name:"{{.Name}}",description:"{{.Description}}",releaseYear:{{.ReleaseYear}}){uuid,name,description,release_year}}`
 	queryTemplate := template.Must(template.New("query").Parse(query))
 	var queryParsed bytes.Buffer
 	if errExecute := queryTemplate.Execute(&queryParsed, input); errExecute != nil {
@@ -322,7 +333,7 @@ func TestGetMovie(t *testing.T) {
 
 	client := &http.Client{}
 
-	req, err := http.NewRequest("GET", "http://localhost:"+config.Server.Port+"/graphql?query={movie(id:\"13cbd25a-4a9d-4e71-9c39-4fc515083c95\"){id,name,release_year,description,rating,review_count}}", nil)
+	req, err := http.NewRequest("GET", "http://localhost:"+config.Server.Port+"/graphql?query={movie(id:\"13cbd25a-4a9d-4e71-9c39-4fc515083c95\"){uuid,name,release_year,description,rating,review_count}}", nil)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -349,7 +360,7 @@ func TestGetMovie(t *testing.T) {
 
 	bodyStr := string(body)
 
-	id := gjson.Get(bodyStr, "data.movie.id").String()
+	id := gjson.Get(bodyStr, "data.movie.uuid").String()
 	name := gjson.Get(bodyStr, "data.movie.name").String()
 	description := gjson.Get(bodyStr, "data.movie.description").String()
 	releaseYear := gjson.Get(bodyStr, "data.movie.release_year").Int()
@@ -408,6 +419,34 @@ func TestCreateAndDeleteMovie(t *testing.T) {
 	assert.Equal(t, releaseYearDelete, releaseYearDelete, "Release Years should be equal")
 }
 
+func TestCreateInvalidMovie(t *testing.T) {
+	token, err := getToken()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Movies are validated like imported rows
+	for _, fields := range []string{
+		`name:"",releaseYear:2001`,
+		`name:"` + strings.Repeat("é", 129) + `",releaseYear:2001`,
+		`name:"Nul \u0000",releaseYear:2001`,
+		`name:"Too Early",releaseYear:1700`,
+	} {
+		createBody, err := graphqlRequest(`mutation{create(`+fields+`){id}}`, token)
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.True(t, gjson.Get(createBody, "errors").Exists(), fields)
+		assert.False(t, gjson.Get(createBody, "data.create.id").Exists(), fields)
+
+		bulkBody, err := graphqlRequest(`mutation{createMovies(movies:[{`+fields+`}]){failed results{movie{uuid}}}}`, token)
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.Equal(t, int64(1), gjson.Get(bulkBody, "data.createMovies.failed").Int(), bulkBody)
+	}
+}
+
 func TestUpdateMovie(t *testing.T) {
 	input := TestMovieUpdate{
 		ID:          "77034dd5-d3e4-4a44-a7fa-c2730dfe5370",
@@ -425,7 +464,7 @@ func TestUpdateMovie(t *testing.T) {
 
 	strUpdateBody := string(buff)
 
-	idUpdate := gjson.Get(strUpdateBody, "data.update.id").String()
+	idUpdate := gjson.Get(strUpdateBody, "data.update.uuid").String()
 	nameUpdate := gjson.Get(strUpdateBody, "data.update.name").String()
 	descriptionUpdate := gjson.Get(strUpdateBody, "data.update.description").String()
 	releaseYearUpdate := gjson.Get(strUpdateBody, "data.update.release_year").Int()
@@ -452,7 +491,7 @@ func TestUpdateMovie(t *testing.T) {
 
 	strReverseBody := string(buffReverse)
 
-	idReverse := gjson.Get(strReverseBody, "data.update.id").String()
+	idReverse := gjson.Get(strReverseBody, "data.update.uuid").String()
 	nameReverse := gjson.Get(strReverseBody, "data.update.name").String()
 	descriptionReverse := gjson.Get(strReverseBody, "data.update.description").String()
 	releaseYearReverse := gjson.Get(strReverseBody, "data.update.release_year").Int()
@@ -506,3 +545,915 @@ func TestGetToken(t *testing.T) {
 	}
 	assert.Equal(t, len(token) > 168, true, "Token is too short")
 }
+
+func TestGetMovieNode(t *testing.T) {
+	config := source.GetConfig("..")
+
+	client := &http.Client{}
+
+	token, err := getToken()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Lookup the global ID of a known movie
+	req, err := http.NewRequest("GET", "http://localhost:"+config.Server.Port+"/graphql?query={movie(id:\"13cbd25a-4a9d-4e71-9c39-4fc515083c95\"){id}}", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req.Header.Add("authorization", token)
+	res, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer res.Body.Close()
+
+	body, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	globalID := gjson.Get(string(body), "data.movie.id").String()
+	assert.NotEqual(t, "13cbd25a-4a9d-4e71-9c39-4fc515083c95", globalID, "Global IDs should be opaque")
+
+	// Resolve the movie through the Node interface
+	v := url.Values{}
+	v.Add("query", `{node(id:"`+globalID+`"){__typename,id,...on Movie{uuid,name}}}`)
+
+	nodeReq, err := http.NewRequest("GET", "http://localhost:"+config.Server.Port+"/graphql?"+v.Encode(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	nodeReq.Header.Add("authorization", token)
+	nodeRes, err := client.Do(nodeReq)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer nodeRes.Body.Close()
+
+	nodeBody, err := ioutil.ReadAll(nodeRes.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	bodyStr := string(nodeBody)
+
+	assert.Equal(t, "Movie", gjson.Get(bodyStr, "data.node.__typename").String(), "Type names should be equal")
+	assert.Equal(t, globalID, gjson.Get(bodyStr, "data.node.id").String(), "Global IDs should be equal")
+	assert.Equal(t, "13cbd25a-4a9d-4e71-9c39-4fc515083c95", gjson.Get(bodyStr, "data.node.uuid").String(), "IDs should be equal")
+
+	// Global IDs which do not hold a UUID never reach the database
+	invalidID := base64.StdEncoding.EncodeToString([]byte("Movie:abc"))
+	invalidBody, err := graphqlRequest(`query{node(id:"`+invalidID+`"){id}}`, token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.False(t, gjson.Get(invalidBody, "errors").Exists(), invalidBody)
+	assert.Equal(t, gjson.Null, gjson.Get(invalidBody, "data.node").Type)
+}
+
+func TestRestoreMovie(t *testing.T) {
+	createBody, errCreate := CreateMovie(TestMovie{
+		Name:        "Restored Movie",
+		Description: "Deleted by mistake",
+		ReleaseYear: 2019,
+	})
+	if errCreate != nil {
+		t.Fatal(errCreate)
+	}
+	id := gjson.Get(string(createBody), "data.create.id").String()
+
+	if _, errDelete := DeleteMovie(id); errDelete != nil {
+		t.Fatal(errDelete)
+	}
+
+	token, err := getToken()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	deletedBody, err := graphqlRequest(`query{deletedMovies{id}}`, token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, id, gjson.Get(deletedBody, `data.deletedMovies.#(id=="`+id+`").id`).String(), "Deleted movie should be listed")
+
+	restoreBody, err := graphqlRequest(`mutation{restoreMovie(id:"`+id+`"){id deleted_at}}`, token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, id, gjson.Get(restoreBody, "data.restoreMovie.id").String())
+	assert.Equal(t, "", gjson.Get(restoreBody, "data.restoreMovie.deleted_at").String(), "Movie should be restored")
+
+	// Only deleted movies can be restored
+	againBody, err := graphqlRequest(`mutation{restoreMovie(id:"`+id+`"){id}}`, token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.True(t, gjson.Get(againBody, "errors").Exists())
+
+	if _, errDelete := DeleteMovie(id); errDelete != nil {
+		t.Fatal(errDelete)
+	}
+}
+
+func TestMovieOwnership(t *testing.T) {
+	createBody, errCreate := CreateMovie(TestMovie{
+		Name:        "Owned Movie",
+		Description: "Only changed by its owner",
+		ReleaseYear: 2019,
+	})
+	if errCreate != nil {
+		t.Fatal(errCreate)
+	}
+	id := gjson.Get(string(createBody), "data.create.id").String()
+	defer DeleteMovie(id)
+
+	_, otherToken, err := verifiedUser("owner")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Other users are refused instead of silently changing nothing
+	updateBody, err := graphqlRequest(`mutation{update(id:"`+id+`",name:"Taken Over"){name}}`, otherToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, source.ErrForbidden.Error(), gjson.Get(updateBody, "errors.0.message").String(), updateBody)
+
+	deleteBody, err := graphqlRequest(`mutation{delete(id:"`+id+`"){id}}`, otherToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, source.ErrForbidden.Error(), gjson.Get(deleteBody, "errors.0.message").String(), deleteBody)
+
+	token, err := getToken()
+	if err != nil {
+		t.Fatal(err)
+	}
+	movieBody, err := graphqlRequest(`query{movie(id:"`+id+`"){name deleted_at}}`, token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "Owned Movie", gjson.Get(movieBody, "data.movie.name").String())
+	assert.Equal(t, "", gjson.Get(movieBody, "data.movie.deleted_at").String())
+}
+
+func TestPurgeKeepsRevisions(t *testing.T) {
+	createBody, errCreate := CreateMovie(TestMovie{
+		Name:        "Purged Movie",
+		Description: "Deleted long ago",
+		ReleaseYear: 2019,
+	})
+	if errCreate != nil {
+		t.Fatal(errCreate)
+	}
+	id := gjson.Get(string(createBody), "data.create.id").String()
+
+	if _, errDelete := DeleteMovie(id); errDelete != nil {
+		t.Fatal(errDelete)
+	}
+	_, moviesID, err := node.FromGlobalID(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	db, err := source.ConnectToDB(source.GetConfig(".."))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	dialect := goqu.Dialect("postgres")
+
+	// Backdate the deletion so only this movie is past the cutoff
+	deletedAt := time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+	if _, err := db.Exec(`UPDATE movies SET deleted_at = $1 WHERE id = $2`, deletedAt, moviesID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := source.PurgeDeletedMovies(dialect, db, deletedAt.Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+
+	var movies, revisions int
+	if err := db.QueryRow(`SELECT count(*) FROM movies WHERE id = $1`, moviesID).Scan(&movies); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.QueryRow(`SELECT count(*) FROM movie_revisions WHERE movies_id = $1`, moviesID).Scan(&revisions); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, 0, movies, "Movie should be purged")
+	assert.Equal(t, 2, revisions, "Revisions should outlive the purged movie")
+}
+
+func TestMovieHistory(t *testing.T) {
+	createBody, errCreate := CreateMovie(TestMovie{
+		Name:        "History Movie",
+		Description: "Original description",
+		ReleaseYear: 2001,
+	})
+	if errCreate != nil {
+		t.Fatal(errCreate)
+	}
+	id := gjson.Get(string(createBody), "data.create.id").String()
+
+	if _, errUpdate := UpdateMovie(TestMovieUpdate{
+		ID:          id,
+		Name:        "History Movie Renamed",
+		Description: "Changed description",
+		ReleaseYear: 2002,
+	}); errUpdate != nil {
+		t.Fatal(errUpdate)
+	}
+
+	token, err := getToken()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	historyBody, err := graphqlRequest(`query{movie(id:"`+id+`"){history{id action users_id before{name} after{name}}}}`, token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "update", gjson.Get(historyBody, "data.movie.history.0.action").String(), "Newest revision should be listed first")
+	assert.Equal(t, "History Movie", gjson.Get(historyBody, "data.movie.history.0.before.name").String())
+	assert.Equal(t, "History Movie Renamed", gjson.Get(historyBody, "data.movie.history.0.after.name").String())
+	assert.Equal(t, "create", gjson.Get(historyBody, "data.movie.history.1.action").String())
+	assert.False(t, gjson.Get(historyBody, "data.movie.history.1.before.name").Exists(), "Created movies have no previous state")
+
+	// Revert to the state after the movie was created
+	revisionID := gjson.Get(historyBody, "data.movie.history.1.id").String()
+	revertBody, err := graphqlRequest(`mutation{revertMovie(revisionId:"`+revisionID+`"){name description release_year history{action}}}`, token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "History Movie", gjson.Get(revertBody, "data.revertMovie.name").String())
+	assert.Equal(t, "Original description", gjson.Get(revertBody, "data.revertMovie.description").String())
+	assert.Equal(t, int64(2001), gjson.Get(revertBody, "data.revertMovie.release_year").Int())
+	assert.Equal(t, "revert", gjson.Get(revertBody, "data.revertMovie.history.0.action").String())
+
+	if _, errDelete := DeleteMovie(id); errDelete != nil {
+		t.Fatal(errDelete)
+	}
+}
+
+func TestMovieVersionConflict(t *testing.T) {
+	createBody, errCreate := CreateMovie(TestMovie{
+		Name:        "Versioned Movie",
+		Description: "Edited concurrently",
+		ReleaseYear: 2010,
+	})
+	if errCreate != nil {
+		t.Fatal(errCreate)
+	}
+	id := gjson.Get(string(createBody), "data.create.id").String()
+
+	token, err := getToken()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	movieBody, err := graphqlRequest(`query{movie(id:"`+id+`"){version}}`, token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	version := gjson.Get(movieBody, "data.movie.version").String()
+
+	updateBody, err := graphqlRequest(`mutation{update(id:"`+id+`",name:"Versioned Movie First",expectedVersion:`+version+`){version}}`, token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, gjson.Get(movieBody, "data.movie.version").Int()+1, gjson.Get(updateBody, "data.update.version").Int(), "Version should be incremented")
+
+	// A second editor still holding the previous version is refused
+	staleBody, err := graphqlRequest(`mutation{update(id:"`+id+`",name:"Versioned Movie Second",expectedVersion:`+version+`){name}}`, token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "VERSION_CONFLICT", gjson.Get(staleBody, "errors.0.extensions.code").String())
+	assert.Equal(t, gjson.Get(updateBody, "data.update.version").Int(), gjson.Get(staleBody, "errors.0.extensions.currentVersion").Int())
+
+	staleDeleteBody, err := graphqlRequest(`mutation{delete(id:"`+id+`",expectedVersion:`+version+`){id}}`, token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "VERSION_CONFLICT", gjson.Get(staleDeleteBody, "errors.0.extensions.code").String())
+
+	deleteBody, err := graphqlRequest(`mutation{delete(id:"`+id+`",expectedVersion:`+gjson.Get(updateBody, "data.update.version").String()+`){id}}`, token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.False(t, gjson.Get(deleteBody, "errors").Exists())
+}
+
+func TestGenresAndTags(t *testing.T) {
+	createBody, errCreate := CreateMovie(TestMovie{
+		Name:        "Tagged Movie",
+		Description: "Classified by genre and tag",
+		ReleaseYear: 1999,
+	})
+	if errCreate != nil {
+		t.Fatal(errCreate)
+	}
+	id := gjson.Get(string(createBody), "data.create.id").String()
+
+	token, err := getToken()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	genresBody, err := graphqlRequest(`mutation{setMovieGenres(id:"`+id+`",genres:["drama","Horror"]){version genres{name} history{action}}}`, token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "Drama", gjson.Get(genresBody, "data.setMovieGenres.genres.0.name").String(), "Genres should be ordered by name")
+	assert.Equal(t, "Horror", gjson.Get(genresBody, "data.setMovieGenres.genres.1.name").String())
+	assert.Equal(t, int64(2), gjson.Get(genresBody, "data.setMovieGenres.version").Int(), "Changing genres should bump the version")
+	assert.Equal(t, "update", gjson.Get(genresBody, "data.setMovieGenres.history.0.action").String(), "Changing genres should be recorded")
+
+	unknownBody, err := graphqlRequest(`mutation{setMovieGenres(id:"`+id+`",genres:["Unknown Genre"]){id}}`, token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.True(t, gjson.Get(unknownBody, "errors").Exists(), "Genres have to exist")
+
+	tagsBody, err := graphqlRequest(`mutation{setMovieTags(id:"`+id+`",tags:["Cult "," cult","night"]){tags}}`, token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, `["cult","night"]`, gjson.Get(tagsBody, "data.setMovieTags.tags").Raw, "Tags should be normalized")
+
+	// Lengths count characters rather than bytes
+	longTag := strings.Repeat("é", 32)
+	unicodeBody, err := graphqlRequest(`mutation{setMovieTags(id:"`+id+`",tags:["`+longTag+`"]){tags}}`, token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, longTag, gjson.Get(unicodeBody, "data.setMovieTags.tags.0").String(), unicodeBody)
+
+	tooLongBody, err := graphqlRequest(`mutation{setMovieTags(id:"`+id+`",tags:["`+longTag+`é"]){tags}}`, token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.True(t, gjson.Get(tooLongBody, "errors").Exists(), "Tags are limited to 32 characters")
+
+	if _, err := graphqlRequest(`mutation{setMovieTags(id:"`+id+`",tags:["cult","night"]){tags}}`, token); err != nil {
+		t.Fatal(err)
+	}
+
+	genreListBody, err := graphqlRequest(`query{list(genre:"DRAMA"){id}}`, token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, id, gjson.Get(genreListBody, `data.list.#(id=="`+id+`").id`).String(), "Movie should be listed by genre")
+
+	tagListBody, err := graphqlRequest(`query{list(genre:"Drama",tag:"night"){id}}`, token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, id, gjson.Get(tagListBody, `data.list.#(id=="`+id+`").id`).String(), "Movie should be listed by genre and tag")
+
+	otherListBody, err := graphqlRequest(`query{list(tag:"no-such-tag"){id}}`, token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, int64(0), gjson.Get(otherListBody, "data.list.#").Int())
+
+	countsBody, err := graphqlRequest(`query{genres{name movie_count}}`, token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.True(t, gjson.Get(countsBody, `data.genres.#(name=="Drama").movie_count`).Int() >= 1, "Genre should count the movie")
+
+	if _, errDelete := DeleteMovie(id); errDelete != nil {
+		t.Fatal(errDelete)
+	}
+}
+
+func TestPeopleAndCredits(t *testing.T) {
+	createBody, errCreate := CreateMovie(TestMovie{
+		Name:        "Credited Movie",
+		Description: "Has a cast and crew",
+		ReleaseYear: 2005,
+	})
+	if errCreate != nil {
+		t.Fatal(errCreate)
+	}
+	movieID := gjson.Get(string(createBody), "data.create.id").String()
+
+	token, err := getToken()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	personBody, err := graphqlRequest(`mutation{createPerson(name:"Jane Director",biography:"Directs and acts"){id name}}`, token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	personID := gjson.Get(personBody, "data.createPerson.id").String()
+	assert.Equal(t, "Jane Director", gjson.Get(personBody, "data.createPerson.name").String())
+
+	directorBody, err := graphqlRequest(`mutation{addCredit(movieId:"`+movieID+`",personId:"`+personID+`",role:"director"){id role}}`, token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "director", gjson.Get(directorBody, "data.addCredit.role").String())
+
+	actorBody, err := graphqlRequest(`mutation{addCredit(movieId:"`+movieID+`",personId:"`+personID+`",role:"actor",characterName:"Herself"){id}}`, token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	actorCreditID := gjson.Get(actorBody, "data.addCredit.id").String()
+
+	invalidBody, err := graphqlRequest(`mutation{addCredit(movieId:"`+movieID+`",personId:"`+personID+`",role:"caterer"){id}}`, token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.True(t, gjson.Get(invalidBody, "errors").Exists(), "Unknown roles should be refused")
+
+	movieBody, err := graphqlRequest(`query{movie(id:"`+movieID+`"){cast{character_name person{name}} crew{role person{name}}}}`, token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "Herself", gjson.Get(movieBody, "data.movie.cast.0.character_name").String())
+	assert.Equal(t, "Jane Director", gjson.Get(movieBody, "data.movie.cast.0.person.name").String())
+	assert.Equal(t, "director", gjson.Get(movieBody, "data.movie.crew.0.role").String())
+
+	filmographyBody, err := graphqlRequest(`query{person(id:"`+personID+`"){filmography{role movie{name}}}}`, token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, int64(2), gjson.Get(filmographyBody, "data.person.filmography.#").Int())
+	assert.Equal(t, "Credited Movie", gjson.Get(filmographyBody, "data.person.filmography.0.movie.name").String())
+
+	updateBody, err := graphqlRequest(`mutation{updateCredit(id:"`+actorCreditID+`",characterName:"The Narrator"){character_name}}`, token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "The Narrator", gjson.Get(updateBody, "data.updateCredit.character_name").String())
+
+	if _, err := graphqlRequest(`mutation{removeCredit(id:"`+actorCreditID+`"){id}}`, token); err != nil {
+		t.Fatal(err)
+	}
+	castBody, err := graphqlRequest(`query{movie(id:"`+movieID+`"){cast{id}}}`, token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, int64(0), gjson.Get(castBody, "data.movie.cast.#").Int(), "Removed credits should not be listed")
+
+	if _, err := graphqlRequest(`mutation{deletePerson(id:"`+personID+`"){id}}`, token); err != nil {
+		t.Fatal(err)
+	}
+	crewBody, err := graphqlRequest(`query{movie(id:"`+movieID+`"){crew{id}}}`, token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, int64(0), gjson.Get(crewBody, "data.movie.crew.#").Int(), "Credits of deleted people should not be listed")
+
+	if _, errDelete := DeleteMovie(movieID); errDelete != nil {
+		t.Fatal(errDelete)
+	}
+}
+
+// uploadRequest - Send the mutation to the running server as a multipart request, the file is
+// passed as the $file variable
+func uploadRequest(query string, token string, filename string, data []byte) (string, error) {
+	config := source.GetConfig("..")
+
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	writer.WriteField("operations", `{"query":`+strconv.Quote(query)+`,"variables":{"file":null}}`)
+	writer.WriteField("map", `{"0":["variables.file"]}`)
+	part, err := writer.CreateFormFile("0", filename)
+	if err != nil {
+		return "", err
+	}
+	part.Write(data)
+	if err := writer.Close(); err != nil {
+		return "", err
+	}
+
+	req, err := http.NewRequest("POST", "http://localhost:"+config.Server.Port+"/graphql", &body)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	req.Header.Add("authorization", token)
+
+	client := &http.Client{}
+	res, err := client.Do(req)
+	if err != nil {
+		return "", err
+	}
+
+	defer res.Body.Close()
+
+	resBody, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return "", err
+	}
+
+	return string(resBody), nil
+}
+
+func TestUploadPoster(t *testing.T) {
+	createBody, errCreate := CreateMovie(TestMovie{
+		Name:        "Poster Movie",
+		Description: "Has a poster",
+		ReleaseYear: 2001,
+	})
+	if errCreate != nil {
+		t.Fatal(errCreate)
+	}
+	id := gjson.Get(string(createBody), "data.create.id").String()
+
+	token, err := getToken()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var poster bytes.Buffer
+	if err := png.Encode(&poster, image.NewRGBA(image.Rect(0, 0, 400, 600))); err != nil {
+		t.Fatal(err)
+	}
+
+	mutation := `mutation($file:Upload!){uploadPoster(movieId:"` + id + `",file:$file){poster_url thumbnail_url}}`
+	uploadBody, err := uploadRequest(mutation, token, "poster.png", poster.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.False(t, gjson.Get(uploadBody, "errors").Exists(), uploadBody)
+	assert.True(t, strings.HasSuffix(gjson.Get(uploadBody, "data.uploadPoster.poster_url").String(), ".png"))
+
+	thumbnailURL := gjson.Get(uploadBody, "data.uploadPoster.thumbnail_url").String()
+	res, err := http.Get(thumbnailURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	thumbnail, _, err := image.DecodeConfig(res.Body)
+	res.Body.Close()
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	assert.Nil(t, err, "Thumbnail should be an image")
+	assert.Equal(t, 200, thumbnail.Width, "Thumbnail should be scaled to the configured width")
+	assert.Equal(t, 300, thumbnail.Height, "Thumbnail should keep the aspect ratio")
+
+	// The declared name and content type are not trusted
+	textBody, err := uploadRequest(mutation, token, "poster.png", []byte("not an image"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, source.ErrUnsupportedImage.Error(), gjson.Get(textBody, "errors.0.message").String())
+
+	if _, errDelete := DeleteMovie(id); errDelete != nil {
+		t.Fatal(errDelete)
+	}
+}
+
+func TestImportMovies(t *testing.T) {
+	token, err := getToken()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	name := "Imported Movie " + strconv.FormatInt(time.Now().UnixNano(), 36)
+	csv := "name,release_year,description\n" +
+		name + ",1999,First import\n" +
+		strings.ToUpper(name) + ",1999,Duplicate of the previous row\n" +
+		"Too Early,1700,\n" +
+		"Not UTF-8 \xff,1999,\n" +
+		"Nul \x00 Byte,1999,\n"
+
+	mutation := `mutation($file:Upload!){importMovies(file:$file){imported skipped errors{row message}}}`
+	importBody, err := uploadRequest(mutation, token, "movies.csv", []byte(csv))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, int64(1), gjson.Get(importBody, "data.importMovies.imported").Int(), importBody)
+	assert.Equal(t, int64(1), gjson.Get(importBody, "data.importMovies.skipped").Int())
+	assert.Equal(t, int64(3), gjson.Get(importBody, "data.importMovies.errors.0.row").Int(), "Invalid rows should be reported")
+	assert.Equal(t, int64(4), gjson.Get(importBody, "data.importMovies.errors.1.row").Int(), "Invalid UTF-8 should be refused")
+	assert.Equal(t, int64(5), gjson.Get(importBody, "data.importMovies.errors.2.row").Int(), "NUL characters should be refused")
+	assert.Equal(t, "", gjson.Get(importBody, "data.importMovies.error").String())
+
+	// Importing again skips the movie created before
+	jsonl := `{"name":"` + name + `","release_year":1999}` + "\n"
+	againBody, err := uploadRequest(mutation, token, "movies.jsonl", []byte(jsonl))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, int64(0), gjson.Get(againBody, "data.importMovies.imported").Int(), againBody)
+	assert.Equal(t, int64(1), gjson.Get(againBody, "data.importMovies.skipped").Int())
+
+	listBody, err := graphqlRequest(`query{list{id name}}`, token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	id := gjson.Get(listBody, `data.list.#(name=="`+name+`").id`).String()
+	assert.NotEmpty(t, id, "Imported movie should be listed")
+
+	if _, errDelete := DeleteMovie(id); errDelete != nil {
+		t.Fatal(errDelete)
+	}
+}
+
+// exportRequest - Download the export from the running server
+func exportRequest(file string, token string) (*http.Response, []byte, error) {
+	config := source.GetConfig("..")
+
+	req, err := http.NewRequest("GET", "http://localhost:"+config.Server.Port+"/export/"+file, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	req.Header.Add("authorization", token)
+
+	client := &http.Client{}
+	res, err := client.Do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	defer res.Body.Close()
+
+	body, err := ioutil.ReadAll(res.Body)
+	return res, body, err
+}
+
+func TestExportMovies(t *testing.T) {
+	createBody, errCreate := CreateMovie(TestMovie{
+		Name:        "Exported Movie",
+		Description: "Exported, with a comma",
+		ReleaseYear: 2003,
+	})
+	if errCreate != nil {
+		t.Fatal(errCreate)
+	}
+	id := gjson.Get(string(createBody), "data.create.id").String()
+
+	token, err := getToken()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tag := "export-" + strconv.FormatInt(time.Now().UnixNano(), 36)
+	if _, err := graphqlRequest(`mutation{setMovieTags(id:"`+id+`",tags:["`+tag+`"]){id}}`, token); err != nil {
+		t.Fatal(err)
+	}
+
+	res, body, err := exportRequest("movies.csv?tag="+tag, token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	records, err := csv.NewReader(bytes.NewReader(body)).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, 2, len(records), "Only the tagged movie should be exported")
+	assert.Equal(t, "name", records[0][4])
+	assert.Equal(t, "Exported Movie", records[1][4])
+	assert.Equal(t, "Exported, with a comma", records[1][6])
+
+	_, body, err = exportRequest("movies.jsonl?tag="+tag, token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, int64(2003), gjson.Get(string(body), "release_year").Int())
+
+	_, body, err = exportRequest("movies.parquet?tag="+tag, token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.True(t, bytes.HasPrefix(body, []byte("PAR1")) && bytes.HasSuffix(body, []byte("PAR1")), "Parquet file should be complete")
+
+	res, _, err = exportRequest("users.csv", token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, http.StatusForbidden, res.StatusCode, "Only admins should export users")
+
+	if _, errDelete := DeleteMovie(id); errDelete != nil {
+		t.Fatal(errDelete)
+	}
+}
+
+func TestBulkMovies(t *testing.T) {
+	token, err := getToken()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	createBody, err := graphqlRequest(`mutation{createMovies(movies:[{name:"Bulk One",releaseYear:2010},{name:"Bulk Two",releaseYear:2011}]){succeeded failed results{index movie{uuid version}}}}`, token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, int64(2), gjson.Get(createBody, "data.createMovies.succeeded").Int(), createBody)
+	first := gjson.Get(createBody, "data.createMovies.results.0.movie.uuid").String()
+	second := gjson.Get(createBody, "data.createMovies.results.1.movie.uuid").String()
+	version := gjson.Get(createBody, "data.createMovies.results.0.movie.version").String()
+
+	// The stale version of the second movie rolls back the change of the first one
+	updateBody, err := graphqlRequest(`mutation{updateMovies(allOrNothing:true,movies:[{id:"`+first+`",name:"Bulk Renamed",expectedVersion:`+version+`},{id:"`+second+`",name:"Bulk Renamed",expectedVersion:999}]){succeeded failed results{movie{name} code}}}`, token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, int64(0), gjson.Get(updateBody, "data.updateMovies.succeeded").Int(), updateBody)
+	assert.Equal(t, "ROLLED_BACK", gjson.Get(updateBody, "data.updateMovies.results.0.code").String())
+	assert.Equal(t, "VERSION_CONFLICT", gjson.Get(updateBody, "data.updateMovies.results.1.code").String())
+
+	movieBody, err := graphqlRequest(`query{movie(id:"`+first+`"){name}}`, token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "Bulk One", gjson.Get(movieBody, "data.movie.name").String(), "Rolled back change should not be applied")
+
+	// Without allOrNothing the valid change is applied
+	partialBody, err := graphqlRequest(`mutation{updateMovies(movies:[{id:"`+first+`",name:"Bulk Renamed"},{id:"`+second+`",name:"Bulk Renamed",expectedVersion:999}]){succeeded failed results{movie{name}}}}`, token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, int64(1), gjson.Get(partialBody, "data.updateMovies.succeeded").Int(), partialBody)
+	assert.Equal(t, "Bulk Renamed", gjson.Get(partialBody, "data.updateMovies.results.0.movie.name").String())
+
+	deleteBody, err := graphqlRequest(`mutation{deleteMovies(movies:[{id:"`+first+`"},{id:"`+second+`"},{id:"`+first+`"}]){succeeded failed results{error}}}`, token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, int64(2), gjson.Get(deleteBody, "data.deleteMovies.succeeded").Int(), deleteBody)
+	assert.Equal(t, "Movie not found", gjson.Get(deleteBody, "data.deleteMovies.results.2.error").String(), "Deleted movies cannot be deleted again")
+}
+
+func TestWatchlistAndFavorites(t *testing.T) {
+	token, err := getToken()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	createBody, err := graphqlRequest(`mutation{createMovies(movies:[{name:"Saved One",releaseYear:2012},{name:"Saved Two",releaseYear:2013}]){results{movie{uuid}}}}`, token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	first := gjson.Get(createBody, "data.createMovies.results.0.movie.uuid").String()
+	second := gjson.Get(createBody, "data.createMovies.results.1.movie.uuid").String()
+	defer graphqlRequest(`mutation{deleteMovies(movies:[{id:"`+first+`"},{id:"`+second+`"}]){succeeded}}`, token)
+
+	for _, id := range []string{first, second} {
+		addBody, err := graphqlRequest(`mutation{addToWatchlist(movieId:"`+id+`"){inWatchlist watchlist_count}}`, token)
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.True(t, gjson.Get(addBody, "data.addToWatchlist.inWatchlist").Bool(), addBody)
+		assert.Equal(t, int64(1), gjson.Get(addBody, "data.addToWatchlist.watchlist_count").Int())
+	}
+	favoriteBody, err := graphqlRequest(`mutation{addToFavorites(movieId:"`+first+`"){isFavorite favorite_count}}`, token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.True(t, gjson.Get(favoriteBody, "data.addToFavorites.isFavorite").Bool(), favoriteBody)
+
+	// Most recently added first, one movie per page
+	pageBody, err := graphqlRequest(`query{me{watchlist(first:1){totalCount pageInfo{hasNextPage endCursor} edges{saved_at node{uuid}}}}}`, token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, int64(2), gjson.Get(pageBody, "data.me.watchlist.totalCount").Int(), pageBody)
+	assert.True(t, gjson.Get(pageBody, "data.me.watchlist.pageInfo.hasNextPage").Bool())
+	assert.Equal(t, second, gjson.Get(pageBody, "data.me.watchlist.edges.0.node.uuid").String())
+
+	cursor := gjson.Get(pageBody, "data.me.watchlist.pageInfo.endCursor").String()
+	nextBody, err := graphqlRequest(`query{me{watchlist(first:1,after:"`+cursor+`"){pageInfo{hasNextPage} edges{node{uuid}}}}}`, token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.False(t, gjson.Get(nextBody, "data.me.watchlist.pageInfo.hasNextPage").Bool(), nextBody)
+	assert.Equal(t, first, gjson.Get(nextBody, "data.me.watchlist.edges.0.node.uuid").String())
+
+	removeBody, err := graphqlRequest(`mutation{removeFromWatchlist(movieId:"`+first+`"){inWatchlist isFavorite watchlist_count}}`, token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.False(t, gjson.Get(removeBody, "data.removeFromWatchlist.inWatchlist").Bool(), removeBody)
+	assert.True(t, gjson.Get(removeBody, "data.removeFromWatchlist.isFavorite").Bool(), "Favorites are a separate list")
+	assert.Equal(t, int64(0), gjson.Get(removeBody, "data.removeFromWatchlist.watchlist_count").Int())
+
+	favoritesBody, err := graphqlRequest(`query{me{favorites{totalCount edges{node{uuid}}}}}`, token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, int64(1), gjson.Get(favoritesBody, "data.me.favorites.totalCount").Int(), favoritesBody)
+	assert.Equal(t, first, gjson.Get(favoritesBody, "data.me.favorites.edges.0.node.uuid").String())
+}
+
+func TestCollections(t *testing.T) {
+	token, err := getToken()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	createBody, err := graphqlRequest(`mutation{createMovies(movies:[{name:"Listed One",releaseYear:2014},{name:"Listed Two",releaseYear:2015},{name:"Listed Three",releaseYear:2016}]){results{movie{uuid}}}}`, token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	movies := []string{}
+	for _, result := range gjson.Get(createBody, "data.createMovies.results.#.movie.uuid").Array() {
+		movies = append(movies, result.String())
+	}
+	defer graphqlRequest(`mutation{deleteMovies(movies:[{id:"`+movies[0]+`"},{id:"`+movies[1]+`"},{id:"`+movies[2]+`"}]){succeeded}}`, token)
+
+	collectionBody, err := graphqlRequest(`mutation{createCollection(name:"Best of the test"){id public share_url}}`, token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	collection := gjson.Get(collectionBody, "data.createCollection.id").String()
+	assert.NotEmpty(t, collection, collectionBody)
+	assert.False(t, gjson.Get(collectionBody, "data.createCollection.public").Bool(), "Collections are private by default")
+	defer graphqlRequest(`mutation{deleteCollection(id:"`+collection+`"){id}}`, token)
+
+	for _, movie := range movies {
+		if _, err := graphqlRequest(`mutation{addToCollection(collectionId:"`+collection+`",movieId:"`+movie+`"){id}}`, token); err != nil {
+			t.Fatal(err)
+		}
+	}
+	insertBody, err := graphqlRequest(`mutation{addToCollection(collectionId:"`+collection+`",movieId:"`+movies[2]+`",position:0){movies{totalCount edges{position node{uuid}}}}}`, token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, int64(3), gjson.Get(insertBody, "data.addToCollection.movies.totalCount").Int(), "Adding a movie again moves it")
+	assert.Equal(t, []string{movies[2], movies[0], movies[1]}, stringArray(gjson.Get(insertBody, "data.addToCollection.movies.edges.#.node.uuid").Array()))
+
+	reorderBody, err := graphqlRequest(`mutation{reorderCollection(collectionId:"`+collection+`",movieIds:["`+movies[1]+`"]){movies{edges{node{uuid}}}}}`, token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, []string{movies[1], movies[2], movies[0]}, stringArray(gjson.Get(reorderBody, "data.reorderCollection.movies.edges.#.node.uuid").Array()), reorderBody)
+
+	removeBody, err := graphqlRequest(`mutation{removeFromCollection(collectionId:"`+collection+`",movieId:"`+movies[2]+`"){movies(first:1){pageInfo{hasNextPage endCursor} edges{position node{uuid}}}}}`, token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.True(t, gjson.Get(removeBody, "data.removeFromCollection.movies.pageInfo.hasNextPage").Bool(), removeBody)
+	cursor := gjson.Get(removeBody, "data.removeFromCollection.movies.pageInfo.endCursor").String()
+	nextBody, err := graphqlRequest(`query{collection(id:"`+collection+`"){movies(first:1,after:"`+cursor+`"){pageInfo{hasNextPage} edges{position node{uuid}}}}}`, token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, movies[0], gjson.Get(nextBody, "data.collection.movies.edges.0.node.uuid").String(), nextBody)
+	assert.Equal(t, int64(1), gjson.Get(nextBody, "data.collection.movies.edges.0.position").Int(), "Positions close the gap of removed movies")
+
+	shareBody, err := graphqlRequest(`mutation{shareCollection(id:"`+collection+`"){share_url}}`, token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	shareURL := gjson.Get(shareBody, "data.shareCollection.share_url").String()
+	assert.Contains(t, shareURL, "share=", shareBody)
+	shareToken := shareURL[strings.Index(shareURL, "share=")+len("share="):]
+
+	sharedBody, err := graphqlRequest(`query{collection(shareToken:"`+shareToken+`"){id name}}`, token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, collection, gjson.Get(sharedBody, "data.collection.id").String(), sharedBody)
+
+	if _, err := graphqlRequest(`mutation{unshareCollection(id:"`+collection+`"){id}}`, token); err != nil {
+		t.Fatal(err)
+	}
+	revokedBody, err := graphqlRequest(`query{collection(shareToken:"`+shareToken+`"){id}}`, token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.False(t, gjson.Get(revokedBody, "data.collection.id").Exists(), "Revoked share links should not reveal the collection")
+
+	listBody, err := graphqlRequest(`query{collections{id name}}`, token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Contains(t, gjson.Get(listBody, "data.collections.#.id").String(), collection, listBody)
+
+	// Private collections are only fetched by global ID for their owner
+	nodeBody, err := graphqlRequest(`query{node(id:"`+collection+`"){id ...on Collection{name}}}`, token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "Best of the test", gjson.Get(nodeBody, "data.node.name").String(), nodeBody)
+
+	_, otherToken, err := verifiedUser("collection")
+	if err != nil {
+		t.Fatal(err)
+	}
+	hiddenBody, err := graphqlRequest(`query{node(id:"`+collection+`"){id}}`, otherToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "null", gjson.Get(hiddenBody, "data.node").Raw, hiddenBody)
+}
+
+// stringArray - Values of a gjson array as strings
+func stringArray(results []gjson.Result) []string {
+	values := []string{}
+	for _, result := range results {
+		values = append(values, result.String())
+	}
+	return values
+}
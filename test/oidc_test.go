@@ -0,0 +1,117 @@
+package moviestest
+
+import (
+	"encoding/base64"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tidwall/gjson"
+
+	source "github.com/HencoSmith/graphql-example-go/source"
+	"github.com/HencoSmith/graphql-example-go/test/oidcmock"
+)
+
+// oidcLogin - Follow the OpenID Connect login through the mock provider, returns the JWT
+func oidcLogin(port string) (string, error) {
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		return "", err
+	}
+	client := &http.Client{Jar: jar}
+
+	res, err := client.Get("http://localhost:" + port + "/oidc/login")
+	if err != nil {
+		return "", err
+	}
+	defer res.Body.Close()
+
+	body, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return "", err
+	}
+
+	return gjson.Get(string(body), "token").String(), nil
+}
+
+// tokenUserID - Lookup the user ID in the payload of the JWT
+func tokenUserID(token string) string {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return ""
+	}
+	payload, _ := base64.RawURLEncoding.DecodeString(parts[1])
+	return gjson.Get(string(payload), "userID").String()
+}
+
+func TestOIDCLogin(t *testing.T) {
+	config := source.GetConfig("..")
+	if !config.OIDC.Enabled {
+		t.Skip("OpenID Connect login is disabled")
+	}
+
+	// Serve the mock provider at the configured issuer
+	issuer, err := url.Parse(config.OIDC.Issuer)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	secret := config.OIDC.ClientSecret
+	if envSecret := os.Getenv("OIDC_CLIENT_SECRET"); len(envSecret) != 0 {
+		secret = envSecret
+	}
+	provider, err := oidcmock.New(config.OIDC.Issuer, config.OIDC.ClientID, secret)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	provider.Subject = "oidc-test-subject"
+	provider.Email = "oidc-test@mail.com"
+
+	listener, err := net.Listen("tcp", issuer.Host)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	defer listener.Close()
+	go http.Serve(listener, provider)
+
+	token, err := oidcLogin(config.Server.Port)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	assert.NotEmpty(t, token)
+
+	// The token grants access like one from getToken
+	body, err := graphqlRequest(`query { nodes(ids: []) { id } }`, token)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	assert.False(t, gjson.Get(body, "errors").Exists())
+
+	// Later logins use the linked user
+	second, err := oidcLogin(config.Server.Port)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	assert.NotEmpty(t, tokenUserID(token))
+	assert.Equal(t, tokenUserID(token), tokenUserID(second))
+
+	// A callback without the state cookie of the browser is refused
+	res, err := http.Get("http://localhost:" + config.Server.Port + "/oidc/callback?state=forged&code=forged")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	res.Body.Close()
+	assert.Equal(t, http.StatusBadRequest, res.StatusCode)
+}
@@ -0,0 +1,207 @@
+// Package oidcmock implements a minimal OpenID Connect provider for local development and tests,
+// every authorization request is approved for the configured user without a login page
+package oidcmock
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+)
+
+// keyID - kid of the signing key
+const keyID = "mock"
+
+// authorization - A pending authorization code
+type authorization struct {
+	redirectURI string
+	challenge   string
+	nonce       string
+}
+
+// Provider - Mock provider issuing ID tokens for Subject
+type Provider struct {
+	Issuer        string
+	ClientID      string
+	ClientSecret  string
+	Subject       string
+	Email         string
+	EmailVerified bool
+
+	key   *rsa.PrivateKey
+	mutex sync.Mutex
+	codes map[string]authorization
+	mux   *http.ServeMux
+}
+
+// New - Setup a provider for the issuer URL, the client must authenticate with the ID and secret
+func New(issuer string, clientID string, clientSecret string) (*Provider, error) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return nil, err
+	}
+
+	provider := &Provider{
+		Issuer:        issuer,
+		ClientID:      clientID,
+		ClientSecret:  clientSecret,
+		Subject:       "mock-subject",
+		Email:         "mock@mail.com",
+		EmailVerified: true,
+		key:           key,
+		codes:         map[string]authorization{},
+		mux:           http.NewServeMux(),
+	}
+	provider.mux.HandleFunc("/.well-known/openid-configuration", provider.discovery)
+	provider.mux.HandleFunc("/authorize", provider.authorize)
+	provider.mux.HandleFunc("/token", provider.token)
+	provider.mux.HandleFunc("/jwks", provider.jwks)
+
+	return provider, nil
+}
+
+// ServeHTTP - Serve the provider endpoints
+func (provider *Provider) ServeHTTP(res http.ResponseWriter, req *http.Request) {
+	provider.mux.ServeHTTP(res, req)
+}
+
+// writeJSON - Respond with the status and value as JSON
+func writeJSON(res http.ResponseWriter, status int, value interface{}) {
+	res.Header().Set("Content-Type", "application/json; charset=utf-8")
+	res.WriteHeader(status)
+	json.NewEncoder(res).Encode(value)
+}
+
+// discovery - Provider metadata
+func (provider *Provider) discovery(res http.ResponseWriter, req *http.Request) {
+	writeJSON(res, http.StatusOK, map[string]interface{}{
+		"issuer":                                provider.Issuer,
+		"authorization_endpoint":                provider.Issuer + "/authorize",
+		"token_endpoint":                        provider.Issuer + "/token",
+		"jwks_uri":                              provider.Issuer + "/jwks",
+		"response_types_supported":              []string{"code"},
+		"subject_types_supported":               []string{"public"},
+		"id_token_signing_alg_values_supported": []string{"RS256"},
+		"code_challenge_methods_supported":      []string{"S256"},
+	})
+}
+
+// authorize - Approve the request and redirect back with an authorization code
+func (provider *Provider) authorize(res http.ResponseWriter, req *http.Request) {
+	query := req.URL.Query()
+	redirectURI, err := url.Parse(query.Get("redirect_uri"))
+	if err != nil || len(query.Get("redirect_uri")) == 0 {
+		http.Error(res, "invalid redirect_uri", http.StatusBadRequest)
+		return
+	}
+	if query.Get("client_id") != provider.ClientID || query.Get("response_type") != "code" ||
+		query.Get("code_challenge_method") != "S256" || len(query.Get("code_challenge")) == 0 {
+		http.Error(res, "invalid authorization request", http.StatusBadRequest)
+		return
+	}
+
+	buff := make([]byte, 16)
+	if _, err := rand.Read(buff); err != nil {
+		http.Error(res, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	code := base64.RawURLEncoding.EncodeToString(buff)
+
+	provider.mutex.Lock()
+	provider.codes[code] = authorization{
+		redirectURI: redirectURI.String(),
+		challenge:   query.Get("code_challenge"),
+		nonce:       query.Get("nonce"),
+	}
+	provider.mutex.Unlock()
+
+	v := redirectURI.Query()
+	v.Set("code", code)
+	v.Set("state", query.Get("state"))
+	redirectURI.RawQuery = v.Encode()
+	http.Redirect(res, req, redirectURI.String(), http.StatusFound)
+}
+
+// token - Redeem an authorization code for an ID token, verifying the client and PKCE verifier
+func (provider *Provider) token(res http.ResponseWriter, req *http.Request) {
+	if req.Method != "POST" || req.ParseForm() != nil {
+		writeJSON(res, http.StatusBadRequest, map[string]string{"error": "invalid_request"})
+		return
+	}
+
+	clientID, clientSecret, ok := req.BasicAuth()
+	if !ok {
+		clientID, clientSecret = req.PostForm.Get("client_id"), req.PostForm.Get("client_secret")
+	}
+	clientID, _ = url.QueryUnescape(clientID)
+	clientSecret, _ = url.QueryUnescape(clientSecret)
+	if clientID != provider.ClientID || clientSecret != provider.ClientSecret {
+		writeJSON(res, http.StatusUnauthorized, map[string]string{"error": "invalid_client"})
+		return
+	}
+
+	// Codes are single-use
+	code := req.PostForm.Get("code")
+	provider.mutex.Lock()
+	pending, found := provider.codes[code]
+	delete(provider.codes, code)
+	provider.mutex.Unlock()
+
+	sum := sha256.Sum256([]byte(req.PostForm.Get("code_verifier")))
+	if !found || req.PostForm.Get("grant_type") != "authorization_code" ||
+		req.PostForm.Get("redirect_uri") != pending.redirectURI ||
+		base64.RawURLEncoding.EncodeToString(sum[:]) != pending.challenge {
+		writeJSON(res, http.StatusBadRequest, map[string]string{"error": "invalid_grant"})
+		return
+	}
+
+	now := time.Now()
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{
+		"iss":            provider.Issuer,
+		"sub":            provider.Subject,
+		"aud":            provider.ClientID,
+		"iat":            now.Unix(),
+		"exp":            now.Add(5 * time.Minute).Unix(),
+		"nonce":          pending.nonce,
+		"email":          provider.Email,
+		"email_verified": provider.EmailVerified,
+	})
+	token.Header["kid"] = keyID
+	idToken, err := token.SignedString(provider.key)
+	if err != nil {
+		writeJSON(res, http.StatusInternalServerError, map[string]string{"error": "server_error"})
+		return
+	}
+
+	writeJSON(res, http.StatusOK, map[string]interface{}{
+		"access_token": "mock",
+		"token_type":   "Bearer",
+		"expires_in":   300,
+		"id_token":     idToken,
+	})
+}
+
+// jwks - Public key verifying the ID tokens
+func (provider *Provider) jwks(res http.ResponseWriter, req *http.Request) {
+	public := provider.key.PublicKey
+	writeJSON(res, http.StatusOK, map[string]interface{}{
+		"keys": []map[string]string{
+			{
+				"kty": "RSA",
+				"use": "sig",
+				"alg": "RS256",
+				"kid": keyID,
+				"n":   base64.RawURLEncoding.EncodeToString(public.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(public.E)).Bytes()),
+			},
+		},
+	})
+}
@@ -0,0 +1,66 @@
+package moviestest
+
+import (
+	"io/ioutil"
+	"testing"
+
+	"github.com/doug-martin/goqu/v8"
+	_ "github.com/doug-martin/goqu/v8/dialect/postgres"
+	"github.com/stretchr/testify/assert"
+
+	gqlschema "github.com/HencoSmith/graphql-example-go/graphql/schema"
+)
+
+func TestSchemaSnapshot(t *testing.T) {
+	schema, err := gqlschema.New(goqu.Dialect("postgres"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	snapshot, err := ioutil.ReadFile("../graphql/schema.graphql")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, string(snapshot), gqlschema.PrintSDL(schema), "Schema snapshot is out of date, run: go run ./cmd/schema print > graphql/schema.graphql")
+
+	changes, err := gqlschema.Diff(string(snapshot), gqlschema.PrintSDL(schema))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, 0, len(changes), "Snapshot should not differ from itself")
+}
+
+func TestSchemaDiff(t *testing.T) {
+	oldSDL := `
+	enum Genre { DRAMA HORROR }
+	type Movie { id: ID! name: String rating: Float genre: Genre }
+	type Query { movie(id: ID!): Movie list(limit: Int): [Movie] }
+	`
+	newSDL := `
+	enum Genre { DRAMA HORROR COMEDY }
+	type Movie { id: ID! name: String! genre: Genre year: Int }
+	type Query { movie(id: ID): Movie list(limit: Int, offset: Int, year: Int!): [Movie] }
+	type Person { id: ID! }
+	`
+
+	changes, err := gqlschema.Diff(oldSDL, newSDL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	criticality := map[string]gqlschema.Criticality{}
+	for _, change := range changes {
+		criticality[change.Path] = change.Criticality
+	}
+
+	assert.Equal(t, gqlschema.Breaking, criticality["Movie.rating"], "Removing a field is breaking")
+	assert.Equal(t, gqlschema.Breaking, criticality["Query.list.year"], "Adding a required argument is breaking")
+	assert.Equal(t, gqlschema.Dangerous, criticality["Query.list.offset"], "Adding an optional argument is dangerous")
+	assert.Equal(t, gqlschema.Dangerous, criticality["Genre.COMEDY"], "Adding an enum value is dangerous")
+	assert.Equal(t, gqlschema.Safe, criticality["Movie.name"], "Making an output field non-null is safe")
+	assert.Equal(t, gqlschema.Safe, criticality["Query.movie.id"], "Making an argument nullable is safe")
+	assert.Equal(t, gqlschema.Safe, criticality["Movie.year"], "Adding a field is safe")
+	assert.Equal(t, gqlschema.Safe, criticality["Person"], "Adding a type is safe")
+	assert.Equal(t, true, gqlschema.HasBreaking(changes), "Changes should include breaking changes")
+}
@@ -0,0 +1,568 @@
+package moviestest
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/base32"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/doug-martin/goqu/v8"
+	"github.com/stretchr/testify/assert"
+	"github.com/tidwall/gjson"
+
+	source "github.com/HencoSmith/graphql-example-go/source"
+)
+
+// graphqlRequest - Send the query to the running server, with the token if one is given
+func graphqlRequest(query string, token string) (string, error) {
+	config := source.GetConfig("..")
+
+	v := url.Values{}
+	v.Add("query", query)
+
+	req, err := http.NewRequest("POST", "http://localhost:"+config.Server.Port+"/graphql?"+v.Encode(), bytes.NewBuffer([]byte("{}")))
+	if err != nil {
+		return "", err
+	}
+
+	if len(token) > 0 {
+		req.Header.Add("authorization", token)
+	}
+
+	client := &http.Client{}
+	res, err := client.Do(req)
+	if err != nil {
+		return "", err
+	}
+
+	defer res.Body.Close()
+
+	body, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return "", err
+	}
+
+	return string(body), nil
+}
+
+// lastMailedToken - Lookup the token in the most recent link written by the file mailer
+func lastMailedToken() (string, error) {
+	config := source.GetConfig("..")
+
+	contents, err := ioutil.ReadFile("../" + config.Mail.Path)
+	if err != nil {
+		return "", err
+	}
+
+	matches := regexp.MustCompile(`token=([A-Za-z0-9_-]+)`).FindAllStringSubmatch(string(contents), -1)
+	if len(matches) < 1 {
+		return "", nil
+	}
+
+	return matches[len(matches)-1][1], nil
+}
+
+// mailedTokenAfter - Wait for a mail containing a token other than the previous one, for mail sent
+// in the background
+func mailedTokenAfter(previous string) (string, error) {
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		token, err := lastMailedToken()
+		if err != nil && !os.IsNotExist(err) {
+			return "", err
+		}
+		if token != previous || time.Now().After(deadline) {
+			return token, nil
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
+
+func TestPasswordReset(t *testing.T) {
+	previous, _ := lastMailedToken()
+
+	body, err := graphqlRequest(`mutation{requestPasswordReset(email:"test@mail.com")}`, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "success", gjson.Get(body, "data.requestPasswordReset").String(), "Reset request failed")
+
+	// Unknown emails are indistinguishable from known ones
+	unknownBody, err := graphqlRequest(`mutation{requestPasswordReset(email:"unknown@mail.com")}`, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "success", gjson.Get(unknownBody, "data.requestPasswordReset").String(), "Reset request should not reveal unknown emails")
+
+	token, err := mailedTokenAfter(previous)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.NotEqual(t, "", token, "Reset link should have been mailed")
+
+	// Reset to the default password so other tests keep working
+	resetBody, err := graphqlRequest(`mutation{resetPassword(token:"`+token+`",newPassword:"test")}`, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "success", gjson.Get(resetBody, "data.resetPassword").String(), "Reset failed")
+
+	// Tokens are single-use
+	reuseBody, err := graphqlRequest(`mutation{resetPassword(token:"`+token+`",newPassword:"other")}`, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "failure", gjson.Get(reuseBody, "data.resetPassword").String(), "Reset tokens should be single-use")
+
+	loginToken, err := getToken()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, len(loginToken) > 168, true, "Login with the reset password failed")
+}
+
+func TestJWKS(t *testing.T) {
+	config := source.GetConfig("..")
+
+	res, err := http.Get("http://localhost:" + config.Server.Port + "/.well-known/jwks.json")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	defer res.Body.Close()
+
+	body, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	// Tokens issued by the server name one of the published keys
+	token, err := getToken()
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	header, err := base64.RawURLEncoding.DecodeString(strings.Split(token, ".")[0])
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	kid := gjson.Get(string(header), "kid").String()
+	assert.NotEmpty(t, kid)
+	assert.Equal(t, kid, gjson.Get(string(body), `keys.#(kid=="`+kid+`").kid`).String())
+}
+
+func TestAPIKeys(t *testing.T) {
+	token, err := getToken()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	createBody, err := graphqlRequest(`mutation{createApiKey(name:"importer",scopes:["read"]){key apiKey{id prefix scopes}}}`, token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key := gjson.Get(createBody, "data.createApiKey.key").String()
+	id := gjson.Get(createBody, "data.createApiKey.apiKey.id").String()
+	assert.True(t, strings.HasPrefix(key, gjson.Get(createBody, "data.createApiKey.apiKey.prefix").String()), "Prefix should identify the key")
+	assert.Equal(t, `["read"]`, gjson.Get(createBody, "data.createApiKey.apiKey.scopes").Raw)
+
+	// The key authenticates within its scopes
+	readBody, err := graphqlRequest(`query{list{uuid}}`, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.False(t, gjson.Get(readBody, "errors").Exists(), "Read scope should allow queries")
+
+	writeBody, err := graphqlRequest(`mutation{create(name:"API Key Movie",releaseYear:2000){uuid}}`, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.True(t, gjson.Get(writeBody, "errors").Exists(), "Read scope should not allow mutations")
+
+	// Keys cannot manage credentials
+	escalateBody, err := graphqlRequest(`mutation{createApiKey(name:"escalate",scopes:["write"]){key}}`, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.True(t, gjson.Get(escalateBody, "errors").Exists(), "API keys should not create API keys")
+
+	listBody, err := graphqlRequest(`query{listApiKeys{id last_used_at}}`, token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.NotEmpty(t, gjson.Get(listBody, `data.listApiKeys.#(id=="`+id+`").last_used_at`).String(), "Use of the key should be tracked")
+
+	revokeBody, err := graphqlRequest(`mutation{revokeApiKey(id:"`+id+`")}`, token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "success", gjson.Get(revokeBody, "data.revokeApiKey").String())
+
+	revokedBody, err := graphqlRequest(`query{list{uuid}}`, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.True(t, gjson.Get(revokedBody, "errors").Exists(), "Revoked keys should be rejected")
+}
+
+func TestProfile(t *testing.T) {
+	token, err := getToken()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	meBody, err := graphqlRequest(`query{me{email}}`, token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "test@mail.com", gjson.Get(meBody, "data.me.email").String())
+
+	profileBody, err := graphqlRequest(`mutation{updateProfile(displayName:"Tester",avatarURL:"https://example.com/a.png"){display_name avatar_url}}`, token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "Tester", gjson.Get(profileBody, "data.updateProfile.display_name").String())
+	assert.Equal(t, "https://example.com/a.png", gjson.Get(profileBody, "data.updateProfile.avatar_url").String())
+
+	invalidBody, err := graphqlRequest(`mutation{updateProfile(avatarURL:"javascript:alert(1)"){avatar_url}}`, token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.True(t, gjson.Get(invalidBody, "errors").Exists(), "Only web links should be accepted as avatars")
+
+	wrongBody, err := graphqlRequest(`mutation{changePassword(oldPassword:"wrong",newPassword:"other")}`, token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "failure", gjson.Get(wrongBody, "data.changePassword").String(), "The old password should be required")
+
+	// Change to the same password so other tests keep working
+	changeBody, err := graphqlRequest(`mutation{changePassword(oldPassword:"test",newPassword:"test")}`, token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "success", gjson.Get(changeBody, "data.changePassword").String())
+}
+
+func TestChangeEmail(t *testing.T) {
+	email, token, err := verifiedUser("change")
+	if err != nil {
+		t.Fatal(err)
+	}
+	previous, _ := lastMailedToken()
+
+	// The email is kept until the new address is verified
+	changed := "new-" + email
+	changeBody, err := graphqlRequest(`mutation{changeEmail(email:"`+changed+`",password:"secret"){email}}`, token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, email, gjson.Get(changeBody, "data.changeEmail.email").String(), "The email should change once verified")
+
+	verifyToken, err := mailedTokenAfter(previous)
+	if err != nil {
+		t.Fatal(err)
+	}
+	verifyBody, err := graphqlRequest(`mutation{verifyEmail(token:"`+verifyToken+`")}`, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "success", gjson.Get(verifyBody, "data.verifyEmail").String())
+
+	meBody, err := graphqlRequest(`query{me{email}}`, token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, changed, gjson.Get(meBody, "data.me.email").String())
+
+	// Taken addresses give the same response without changing anything
+	takenBody, err := graphqlRequest(`mutation{changeEmail(email:"test@mail.com",password:"secret"){email}}`, token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, changed, gjson.Get(takenBody, "data.changeEmail.email").String())
+	assert.False(t, gjson.Get(takenBody, "errors").Exists(), "Taken addresses should not be revealed")
+}
+
+func TestSignupExistingEmail(t *testing.T) {
+	email := "signup-" + strconv.FormatInt(time.Now().UnixNano(), 36) + "@mail.com"
+	newBody, err := graphqlRequest(`mutation{signup(email:"`+email+`",password:"secret"){token}}`, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "success", gjson.Get(newBody, "data.signup").String())
+
+	// Registered emails are indistinguishable from new ones
+	existingBody, err := graphqlRequest(`mutation{signup(email:"test@mail.com",password:"secret"){token}}`, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "success", gjson.Get(existingBody, "data.signup").String())
+	assert.False(t, gjson.Get(existingBody, "errors").Exists(), "Registered emails should not be revealed")
+
+	// The existing account keeps its password
+	token, err := getToken()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.NotEmpty(t, token)
+}
+
+func TestEmailCase(t *testing.T) {
+	db, err := source.ConnectToDB(source.GetConfig(".."))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	email := "case-" + strconv.FormatInt(time.Now().UnixNano(), 36) + "@mail.com"
+	for _, variant := range []string{" " + strings.ToUpper(email) + " ", email} {
+		signupBody, err := graphqlRequest(`mutation{signup(email:"`+variant+`",password:"secret")}`, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.Equal(t, "success", gjson.Get(signupBody, "data.signup").String(), signupBody)
+	}
+
+	var stored string
+	var accounts int
+	if err := db.QueryRow(`SELECT min(email), count(*) FROM users WHERE lower(email) = $1`, email).Scan(&stored, &accounts); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, 1, accounts, "Emails differing in case should belong to the same account")
+	assert.Equal(t, email, stored, "Emails should be stored in lowercase")
+
+	// Logins match the email regardless of case
+	loginBody, err := graphqlRequest(`query{getToken(email:"TEST@Mail.com",password:"test"){token}}`, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.NotEmpty(t, gjson.Get(loginBody, "data.getToken.token").String(), loginBody)
+}
+
+func TestDeleteAccount(t *testing.T) {
+	email, token, err := verifiedUser("delete")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := graphqlRequest(`mutation{create(name:"Exported Movie",releaseYear:1999){uuid}}`, token); err != nil {
+		t.Fatal(err)
+	}
+
+	exportBody, err := graphqlRequest(`query{exportMyData}`, token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	archive := gjson.Get(exportBody, "data.exportMyData").String()
+	assert.Equal(t, email, gjson.Get(archive, "user.email").String())
+	assert.Equal(t, "Exported Movie", gjson.Get(archive, "movies.0.name").String())
+	assert.Equal(t, "create", gjson.Get(archive, "revisions.0.action").String(), "Edits should be exported")
+	assert.NotContains(t, archive, "encrypted_password", "Secrets should not be exported")
+
+	wrongBody, err := graphqlRequest(`mutation{deleteMyAccount(password:"wrong")}`, token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "failure", gjson.Get(wrongBody, "data.deleteMyAccount").String(), "The password should be required")
+
+	deleteBody, err := graphqlRequest(`mutation{deleteMyAccount(password:"secret"){token}}`, token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "success", gjson.Get(deleteBody, "data.deleteMyAccount").String())
+
+	// The account can no longer be used
+	meBody, err := graphqlRequest(`query{me{email}}`, token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.True(t, gjson.Get(meBody, "errors").Exists(), "Tokens of deleted accounts should be rejected")
+
+	deletedLoginBody, err := graphqlRequest(`query{getToken(email:"`+email+`",password:"secret"){token}}`, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "", gjson.Get(deletedLoginBody, "data.getToken.token").String(), "Deleted accounts should not login")
+}
+
+// verifiedUser - Sign up and verify a new user, returns the email and a token of the user
+func verifiedUser(prefix string) (string, string, error) {
+	email := prefix + "-" + strconv.FormatInt(time.Now().UnixNano(), 36) + "@mail.com"
+	previous, _ := lastMailedToken()
+	if _, err := graphqlRequest(`mutation{signup(email:"`+email+`",password:"secret"){token}}`, ""); err != nil {
+		return "", "", err
+	}
+	verifyToken, err := mailedTokenAfter(previous)
+	if err != nil {
+		return "", "", err
+	}
+	if _, err := graphqlRequest(`mutation{verifyEmail(token:"`+verifyToken+`")}`, ""); err != nil {
+		return "", "", err
+	}
+	loginBody, err := graphqlRequest(`query{getToken(email:"`+email+`",password:"secret"){token}}`, "")
+	if err != nil {
+		return "", "", err
+	}
+	return email, gjson.Get(loginBody, "data.getToken.token").String(), nil
+}
+
+func TestUserNodePrivacy(t *testing.T) {
+	_, token, err := verifiedUser("node")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The test user owns the seeded movies
+	globalID := base64.StdEncoding.EncodeToString([]byte("User:d56d4bff-4e7e-4cf9-a3d2-38973c9dd57d"))
+	missingID := base64.StdEncoding.EncodeToString([]byte("User:00000000-0000-4000-8000-000000000000"))
+	body, err := graphqlRequest(`query{nodes(ids:["`+globalID+`","`+missingID+`","not-an-id"]){...on User{uuid email}}}`, token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.False(t, gjson.Get(body, "errors").Exists(), body)
+	assert.Equal(t, "d56d4bff-4e7e-4cf9-a3d2-38973c9dd57d", gjson.Get(body, "data.nodes.0.uuid").String())
+	assert.Equal(t, "", gjson.Get(body, "data.nodes.0.email").String(), "Emails of other users should not be revealed")
+	assert.Equal(t, gjson.Null, gjson.Get(body, "data.nodes.1").Type, "Unknown users should be null")
+	assert.Equal(t, gjson.Null, gjson.Get(body, "data.nodes.2").Type, "Malformed IDs should be null")
+
+	meBody, err := graphqlRequest(`query{me{email}}`, token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.NotEmpty(t, gjson.Get(meBody, "data.me.email").String(), "Users should see their own email")
+}
+
+// totpCode - Calculate the current code of a base32 TOTP secret like an authenticator app
+func totpCode(secret string) (string, error) {
+	key, err := base32.StdEncoding.WithPadding(base32.NoPadding).DecodeString(secret)
+	if err != nil {
+		return "", err
+	}
+
+	message := make([]byte, 8)
+	binary.BigEndian.PutUint64(message, uint64(time.Now().Unix()/30))
+	mac := hmac.New(sha1.New, key)
+	mac.Write(message)
+	sum := mac.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0x0f
+	code := (binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff) % 1000000
+	return fmt.Sprintf("%06d", code), nil
+}
+
+func TestTwoFactorLogin(t *testing.T) {
+	email, token, err := verifiedUser("totp")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	enrollBody, err := graphqlRequest(`mutation{enrollTwoFactor{secret}}`, token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	code, err := totpCode(gjson.Get(enrollBody, "data.enrollTwoFactor.secret").String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	confirmBody, err := graphqlRequest(`mutation{confirmTwoFactor(code:"`+code+`")}`, token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	recoveryCode := gjson.Get(confirmBody, "data.confirmTwoFactor.0").String()
+	assert.NotEmpty(t, recoveryCode, confirmBody)
+
+	// The password alone only returns a challenge
+	loginBody, err := graphqlRequest(`query{getToken(email:"`+email+`",password:"secret"){token challenge totpRequired}}`, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, gjson.Null, gjson.Get(loginBody, "data.getToken.token").Type, "No JWT should be issued without a second factor")
+	assert.True(t, gjson.Get(loginBody, "data.getToken.totpRequired").Bool())
+	challenge := gjson.Get(loginBody, "data.getToken.challenge").String()
+
+	verifyBody, err := graphqlRequest(`query{verifyTwoFactor(challenge:"`+challenge+`",code:"`+recoveryCode+`")}`, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.NotEmpty(t, gjson.Get(verifyBody, "data.verifyTwoFactor").String(), verifyBody)
+
+	// Two-factor codes confirm the deletion of accounts without a known password
+	deleteBody, err := graphqlRequest(`mutation{deleteMyAccount(code:"`+gjson.Get(confirmBody, "data.confirmTwoFactor.1").String()+`")}`, token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "success", gjson.Get(deleteBody, "data.deleteMyAccount").String(), deleteBody)
+}
+
+func TestPurgeLoginFailures(t *testing.T) {
+	db, err := source.ConnectToDB(source.GetConfig(".."))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	dialect := goqu.Dialect("postgres")
+
+	email := "sprayed-" + strconv.FormatInt(time.Now().UnixNano(), 36) + "@mail.com"
+	if err := source.RecordLoginFailure(dialect, db, email, "192.0.2.1"); err != nil {
+		t.Fatal(err)
+	}
+
+	// Backdate the failure so only this one is past the cutoff
+	recordedAt := time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+	if _, err := db.Exec(`UPDATE users_login_failures SET created_at = $1 WHERE email = $2`, recordedAt, email); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := source.PurgeLoginFailures(dialect, db, recordedAt.Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+
+	var failures int
+	if err := db.QueryRow(`SELECT count(*) FROM users_login_failures WHERE email = $1`, email).Scan(&failures); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, 0, failures, "Failures outside the login window should be removed")
+}
+
+func TestChangePasswordLockout(t *testing.T) {
+	email, token, err := verifiedUser("lockout")
+	if err != nil {
+		t.Fatal(err)
+	}
+	config := source.GetConfig("..")
+
+	// Guessing the password of a signed in account counts towards its lockout
+	for i := 0; i < config.Login.MaxAttempts; i++ {
+		if _, err := graphqlRequest(`mutation{changePassword(oldPassword:"wrong",newPassword:"guess")}`, token); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	changeBody, err := graphqlRequest(`mutation{changePassword(oldPassword:"secret",newPassword:"changed")}`, token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "failure", gjson.Get(changeBody, "data.changePassword").String(), "The account should be locked")
+	assert.Equal(t, source.ErrLoginLocked.Error(), gjson.Get(changeBody, "errors.0.message").String())
+
+	emailBody, err := graphqlRequest(`mutation{changeEmail(email:"locked-`+email+`",password:"secret"){email}}`, token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, source.ErrLoginLocked.Error(), gjson.Get(emailBody, "errors.0.message").String())
+}